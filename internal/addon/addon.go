@@ -2,8 +2,20 @@ package addon
 
 import (
 	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/source"
+	"github.com/geomark27/loom-go/internal/state"
+	"github.com/geomark27/loom-go/internal/version"
 )
 
 // Addon represents a component that can be added to the project
@@ -20,18 +32,36 @@ type Addon interface {
 	// CanInstall checks if the addon can be installed (dependencies, etc.)
 	CanInstall() (bool, string, error)
 
-	// Install installs the addon
+	// Install stages the files of the addon in the change set of its
+	// manager; they are written by AddonManager.Commit
 	Install(force bool) error
 
+	// Uninstall stages the removal of what Install added: its files, go.mod
+	// requirements, config fields, .env.example sections, Makefile targets
+	// and docker-compose services. AddonManager keeps the files edited
+	// since they were generated.
+	Uninstall() error
+
 	// GetConflicts returns addons that may conflict
 	GetConflicts() []string
 }
 
-// AddonManager manages available addons
+// AddonManager manages available addons. The addons it installs share a
+// change set, so several addons are written (or rolled back) together.
 type AddonManager struct {
 	projectRoot  string
 	architecture string // "layered" or "modular"
 	addons       map[string]Addon
+	changes      *changeset.Set
+
+	// The generation state is loaded on the first install: "loom new"
+	// creates the manager before the project exists
+	state       *state.State
+	stateLoaded bool
+	tracked     map[string]bool // generated files already recorded
+	manifest    *state.Manifest // project manifest, updated by every install
+
+	prune []string // directories to remove when a commit leaves them empty
 }
 
 // NewAddonManager creates a new addon manager
@@ -40,6 +70,8 @@ func NewAddonManager(projectRoot, architecture string) *AddonManager {
 		projectRoot:  projectRoot,
 		architecture: architecture,
 		addons:       make(map[string]Addon),
+		changes:      changeset.New(),
+		tracked:      make(map[string]bool),
 	}
 
 	// Register available addons
@@ -51,26 +83,167 @@ func NewAddonManager(projectRoot, architecture string) *AddonManager {
 // registerAddons registers all available addons
 func (am *AddonManager) registerAddons() {
 	// Routers
-	am.addons["gin"] = NewRouterAddon(am.projectRoot, am.architecture, "gin")
-	am.addons["chi"] = NewRouterAddon(am.projectRoot, am.architecture, "chi")
-	am.addons["echo"] = NewRouterAddon(am.projectRoot, am.architecture, "echo")
+	am.addons["gin"] = NewRouterAddon(am.projectRoot, am.architecture, "gin", am.changes)
+	am.addons["chi"] = NewRouterAddon(am.projectRoot, am.architecture, "chi", am.changes)
+	am.addons["echo"] = NewRouterAddon(am.projectRoot, am.architecture, "echo", am.changes)
 
 	// ORMs
-	am.addons["gorm"] = NewORMAddon(am.projectRoot, am.architecture, "gorm")
-	am.addons["sqlc"] = NewORMAddon(am.projectRoot, am.architecture, "sqlc")
+	am.addons["gorm"] = NewORMAddon(am.projectRoot, am.architecture, "gorm", am.changes)
+	am.addons["sqlc"] = NewORMAddon(am.projectRoot, am.architecture, "sqlc", am.changes)
 
 	// Databases
-	am.addons["postgres"] = NewDatabaseAddon(am.projectRoot, am.architecture, "postgres")
-	am.addons["mysql"] = NewDatabaseAddon(am.projectRoot, am.architecture, "mysql")
-	am.addons["mongodb"] = NewDatabaseAddon(am.projectRoot, am.architecture, "mongodb")
-	am.addons["redis"] = NewDatabaseAddon(am.projectRoot, am.architecture, "redis")
+	am.addons["postgres"] = NewDatabaseAddon(am.projectRoot, am.architecture, "postgres", am.changes)
+	am.addons["mysql"] = NewDatabaseAddon(am.projectRoot, am.architecture, "mysql", am.changes)
+	am.addons["sqlite"] = NewDatabaseAddon(am.projectRoot, am.architecture, "sqlite", am.changes)
+	am.addons["sqlserver"] = NewDatabaseAddon(am.projectRoot, am.architecture, "sqlserver", am.changes)
+	am.addons["mongodb"] = NewDatabaseAddon(am.projectRoot, am.architecture, "mongodb", am.changes)
+	am.addons["redis"] = NewDatabaseAddon(am.projectRoot, am.architecture, "redis", am.changes)
 
 	// Auth
-	am.addons["jwt"] = NewAuthAddon(am.projectRoot, am.architecture, "jwt")
-	am.addons["oauth2"] = NewAuthAddon(am.projectRoot, am.architecture, "oauth2")
+	am.addons["jwt"] = NewAuthAddon(am.projectRoot, am.architecture, "jwt", am.changes)
+	am.addons["oauth2"] = NewAuthAddon(am.projectRoot, am.architecture, "oauth2", am.changes)
 
 	// Infrastructure
-	am.addons["docker"] = NewDockerAddon(am.projectRoot, am.architecture)
+	am.addons["docker"] = NewDockerAddon(am.projectRoot, am.architecture, am.changes)
+}
+
+// Changes returns the writes staged by the installed addons
+func (am *AddonManager) Changes() *changeset.Set {
+	return am.changes
+}
+
+// Commit writes the files staged by the installed addons and the
+// generation state. When any write fails, the project is left as it was.
+func (am *AddonManager) Commit() error {
+	if am.state != nil {
+		if err := am.state.SaveTo(am.changes); err != nil {
+			return fmt.Errorf("error saving generation state: %w", err)
+		}
+	}
+	if am.manifest != nil {
+		if err := am.manifest.SaveTo(am.projectRoot, am.changes); err != nil {
+			return fmt.Errorf("error saving project manifest: %w", err)
+		}
+	}
+	if err := am.changes.Commit(); err != nil {
+		return err
+	}
+	// Directories emptied by an uninstall go too (os.Remove fails on the
+	// others); deepest first so parents can be emptied
+	sort.Sort(sort.Reverse(sort.StringSlice(am.prune)))
+	for _, dir := range am.prune {
+		_ = os.Remove(dir)
+	}
+	return nil
+}
+
+// Conflicts returns the staged files merged with conflicts
+func (am *AddonManager) Conflicts() []string {
+	return am.changes.Conflicts()
+}
+
+// loadState loads the generation state of the project, which also gives
+// the change set the content addons generated last time
+func (am *AddonManager) loadState() {
+	if am.stateLoaded {
+		return
+	}
+	am.stateLoaded = true
+
+	am.loadManifest()
+
+	st, err := state.Load(am.projectRoot)
+	if err != nil {
+		fmt.Printf("⚠️  %v (addon files will not be tracked)\n", err)
+		return
+	}
+	am.state = st
+	am.changes.UseBases(st)
+}
+
+// loadManifest loads the project manifest. Projects created by older
+// versions of Loom have none: it is detected before the first addon
+// changes the project.
+func (am *AddonManager) loadManifest() {
+	m, err := state.LoadManifest(am.projectRoot)
+	if err != nil {
+		fmt.Printf("⚠️  %v (installed addons will not be recorded)\n", err)
+		return
+	}
+	if m == nil {
+		m = am.DetectManifest()
+	}
+	am.manifest = m
+}
+
+// DetectManifest builds a manifest for a project that has none from what
+// is detected in it: its stack and the addons installed (by an unknown
+// version of Loom)
+func (am *AddonManager) DetectManifest() *state.Manifest {
+	m := &state.Manifest{Architecture: am.architecture}
+	am.detectStack(m)
+
+	for name, addon := range am.addons {
+		if installed, _ := addon.IsInstalled(); installed {
+			m.AddAddon(name, "")
+		}
+	}
+	return m
+}
+
+// detectStack records in the manifest the stack detected in the project,
+// as staged
+func (am *AddonManager) detectStack(m *state.Manifest) {
+	detector := newProjectDetector(am.projectRoot, am.changes)
+	m.Router = detector.routerFromGoMod()
+	m.ORM = detector.ormFromGoMod()
+	m.Databases = detector.databasesFromGoMod()
+	m.Auth = detector.authFromFiles()
+}
+
+// record updates the manifest after an addon is installed or removed, and
+// stages it so the detectors of the next addons see the change
+func (am *AddonManager) record(name string, installed bool) error {
+	if am.manifest == nil {
+		return nil
+	}
+	databases := am.manifest.Databases
+	am.detectStack(am.manifest)
+	// A database stays recorded until its own addon is removed: GORM
+	// connects to one, and removing GORM leaves it installed
+	if installed {
+		for _, db := range databases {
+			if !slices.Contains(am.manifest.Databases, db) {
+				am.manifest.Databases = append(am.manifest.Databases, db)
+			}
+		}
+		am.manifest.AddAddon(name, version.Current.String())
+	} else {
+		am.manifest.Databases = slices.DeleteFunc(databases, func(db string) bool { return db == name })
+		am.manifest.RemoveAddon(name)
+	}
+
+	data, err := am.manifest.Marshal()
+	if err != nil {
+		return err
+	}
+	return am.changes.WriteFile(state.ManifestPath(am.projectRoot), data)
+}
+
+// track records the files generated by an addon in the generation state
+func (am *AddonManager) track(name string) {
+	if am.state == nil {
+		return
+	}
+	for path, content := range am.changes.Generated() {
+		if am.tracked[path] {
+			continue
+		}
+		if rel, err := filepath.Rel(am.projectRoot, path); err == nil {
+			am.state.Record(rel, content, "addon:"+name, "")
+			am.tracked[path] = true
+		}
+	}
 }
 
 // GetAddon returns an addon by name
@@ -87,13 +260,19 @@ func (am *AddonManager) ListAddons() map[string][]string {
 	return map[string][]string{
 		"routers":        {"gin", "chi", "echo"},
 		"orms":           {"gorm", "sqlc"},
-		"databases":      {"postgres", "mysql", "mongodb", "redis"},
+		"databases":      {"postgres", "mysql", "sqlite", "sqlserver", "mongodb", "redis"},
 		"authentication": {"jwt", "oauth2"},
 		"infrastructure": {"docker"},
 	}
 }
 
-// InstallAddon installs an addon
+// SetORMDriver sets the SQL driver GORM is installed with, instead of the
+// database installed in the project
+func (am *AddonManager) SetORMDriver(driver string) error {
+	return am.addons["gorm"].(*ORMAddon).SetDriver(driver)
+}
+
+// InstallAddon installs an addon (staged until Commit)
 func (am *AddonManager) InstallAddon(name string, force bool) error {
 	addon, err := am.GetAddon(name)
 	if err != nil {
@@ -110,17 +289,8 @@ func (am *AddonManager) InstallAddon(name string, force bool) error {
 		return fmt.Errorf("%s is already installed. Use --force to reinstall", addon.Name())
 	}
 
-	// Check if it can be installed
-	canInstall, reason, err := addon.CanInstall()
-	if err != nil {
-		return fmt.Errorf("error checking compatibility: %w", err)
-	}
-
-	if !canInstall {
-		return fmt.Errorf("cannot install %s: %s", addon.Name(), reason)
-	}
-
-	// Check conflicts
+	// Check conflicts. A replaced addon is uninstalled first, so the
+	// checks below see the project without it.
 	conflicts := addon.GetConflicts()
 	for _, conflictName := range conflicts {
 		conflictAddon, _ := am.GetAddon(conflictName)
@@ -130,147 +300,332 @@ func (am *AddonManager) InstallAddon(name string, force bool) error {
 					return fmt.Errorf("conflict detected: %s is installed. Use --force to replace", conflictName)
 				}
 				fmt.Printf("⚠️  Replacing %s with %s...\n", conflictName, name)
+				am.loadState()
+				if err := am.uninstall(conflictName, conflictAddon, false); err != nil {
+					return fmt.Errorf("error removing %s: %w", conflictAddon.Name(), err)
+				}
 			}
 		}
 	}
 
+	// Check if it can be installed
+	canInstall, reason, err := addon.CanInstall()
+	if err != nil {
+		return fmt.Errorf("error checking compatibility: %w", err)
+	}
+
+	if !canInstall {
+		return fmt.Errorf("cannot install %s: %s", addon.Name(), reason)
+	}
+
 	// Install
+	am.loadState()
 	fmt.Printf("📦 Installing %s...\n", addon.Name())
 	if err := addon.Install(force); err != nil {
 		return fmt.Errorf("error installing %s: %w", addon.Name(), err)
 	}
+	am.track(name)
+	if err := am.record(name, true); err != nil {
+		return fmt.Errorf("error recording %s in the project manifest: %w", addon.Name(), err)
+	}
 
 	fmt.Printf("✅ %s installed successfully!\n", addon.Name())
 	return nil
 }
 
+// UninstallAddon removes an installed addon (staged until Commit). Files
+// edited since the addon generated them, or not generated by Loom, are
+// kept unless force is set.
+func (am *AddonManager) UninstallAddon(name string, force bool) error {
+	addon, err := am.GetAddon(name)
+	if err != nil {
+		return err
+	}
+
+	installed, err := addon.IsInstalled()
+	if err != nil {
+		return fmt.Errorf("error checking installation: %w", err)
+	}
+	if !installed {
+		return fmt.Errorf("%s is not installed", addon.Name())
+	}
+
+	am.loadState()
+	fmt.Printf("🗑️  Removing %s...\n", addon.Name())
+	if err := am.uninstall(name, addon, force); err != nil {
+		return fmt.Errorf("error removing %s: %w", addon.Name(), err)
+	}
+
+	fmt.Printf("✅ %s removed successfully!\n", addon.Name())
+	return nil
+}
+
+// uninstall stages the removal of an addon, then keeps the files it
+// deleted that the user edited (unless force is set) and forgets the
+// others in the generation state
+func (am *AddonManager) uninstall(name string, addon Addon, force bool) error {
+	before := am.deletions()
+	if err := addon.Uninstall(); err != nil {
+		return err
+	}
+	if err := am.record(name, false); err != nil {
+		return err
+	}
+
+	var deleted []string
+	for path := range am.deletions() {
+		if !before[path] {
+			deleted = append(deleted, path)
+		}
+	}
+	sort.Strings(deleted)
+
+	for _, path := range deleted {
+		rel, err := filepath.Rel(am.projectRoot, path)
+		if err != nil {
+			return err
+		}
+		if reason := am.keepReason(rel); reason != "" && !force {
+			am.changes.Discard(path)
+			fmt.Printf("   ⏭️  Kept %s (%s, use --force to delete)\n", path, reason)
+			continue
+		}
+
+		if am.state != nil {
+			am.state.Forget(rel)
+		}
+		for dir := filepath.Dir(path); dir != am.projectRoot && dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			am.prune = append(am.prune, dir)
+		}
+	}
+	return nil
+}
+
+// deletions returns the paths whose removal is staged
+func (am *AddonManager) deletions() map[string]bool {
+	paths := make(map[string]bool)
+	for _, change := range am.changes.Changes() {
+		if change.Kind == changeset.Delete {
+			paths[change.Path] = true
+		}
+	}
+	return paths
+}
+
+// keepReason tells why a file must not be deleted without --force, or
+// returns "" when it is exactly what an addon generated
+func (am *AddonManager) keepReason(rel string) string {
+	if am.state == nil || !am.state.Tracked(rel) {
+		return "not generated by Loom"
+	}
+	if pristine, err := am.state.IsPristine(rel); err != nil || !pristine {
+		return "modified since it was installed"
+	}
+	return ""
+}
+
+// addonOrder is the installation order of the addon categories: the
+// database settings must exist before Docker writes docker-compose.yml
+var addonOrder = []string{"routers", "orms", "databases", "authentication", "infrastructure"}
+
+// InstallAddons installs several addons in dependency order and commits
+// them together: if one of them fails, none is written. Addons that are
+// already installed are skipped (GORM also sets up the PostgreSQL driver,
+// for instance).
+func (am *AddonManager) InstallAddons(names []string) error {
+	requested := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, err := am.GetAddon(name); err != nil {
+			return err
+		}
+		requested[name] = true
+	}
+
+	// GORM is installed before the databases: it uses the driver of the
+	// SQL database requested with it
+	if requested["gorm"] {
+		for _, name := range SQLDriverNames() {
+			if requested[name] {
+				if err := am.SetORMDriver(name); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	categories := am.ListAddons()
+	for _, category := range addonOrder {
+		for _, name := range categories[category] {
+			if !requested[name] {
+				continue
+			}
+			if installed, _ := am.addons[name].IsInstalled(); installed {
+				fmt.Printf("✅ %s already installed\n", am.addons[name].Name())
+				continue
+			}
+			if err := am.InstallAddon(name, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return am.Commit()
+}
+
 // Helper functions
 
-// FileExists checks if a file exists
-func FileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+// FileExists checks if a file exists (on disk or staged)
+func FileExists(changes *changeset.Set, path string) bool {
+	return changes.Exists(path)
 }
 
-// ReadFile reads the content of a file
-func ReadFile(path string) (string, error) {
-	content, err := os.ReadFile(path)
+// ReadFile reads the content of a file, as staged
+func ReadFile(changes *changeset.Set, path string) (string, error) {
+	content, err := changes.ReadFile(path)
 	if err != nil {
 		return "", err
 	}
 	return string(content), nil
 }
 
-// WriteFile writes content to a file
-func WriteFile(path, content string) error {
-	return os.WriteFile(path, []byte(content), 0644)
+// WriteFile stages content for a file
+func WriteFile(changes *changeset.Set, path, content string) error {
+	return changes.WriteFile(path, []byte(content))
+}
+
+// WriteGenerated stages a file generated by an addon. Edits made to the
+// version installed before are merged into the new content.
+func WriteGenerated(changes *changeset.Set, path, content string) error {
+	_, err := changes.WriteGenerated(path, []byte(content))
+	return err
+}
+
+// RemoveFile stages the removal of a file, when it exists
+func RemoveFile(changes *changeset.Set, path string) error {
+	if !changes.Exists(path) {
+		return nil
+	}
+	return changes.Remove(path)
 }
 
 // HasImport checks if a Go file has a specific import
-func HasImport(filePath, importPath string) bool {
-	content, err := ReadFile(filePath)
+func HasImport(changes *changeset.Set, filePath, importPath string) bool {
+	file, err := source.LoadFrom(changes, filePath)
 	if err != nil {
 		return false
 	}
-	return strings.Contains(content, importPath)
+	return file.HasImport(importPath)
 }
 
-// AddImport adds an import to a Go file (simplified)
-func AddImport(filePath, importPath string) error {
-	content, err := ReadFile(filePath)
+// AddImport adds an import to a Go file
+func AddImport(changes *changeset.Set, filePath, importPath string) error {
+	file, err := source.LoadFrom(changes, filePath)
 	if err != nil {
 		return err
 	}
 
-	// If it already has the import, do nothing
-	if strings.Contains(content, importPath) {
-		return nil
+	if _, err := file.AddImport(importPath); err != nil {
+		return err
 	}
 
-	// Search for the import block
-	lines := strings.Split(content, "\n")
-	newLines := []string{}
-	importAdded := false
-
-	for i, line := range lines {
-		newLines = append(newLines, line)
-
-		// If we find import ( and haven't added the import
-		if strings.Contains(line, "import (") && !importAdded {
-			// Add the new import after this line
-			newLines = append(newLines, fmt.Sprintf("\t\"%s\"", importPath))
-			importAdded = true
-		}
+	return file.Save()
+}
 
-		// If there's no import block, create one
-		if i == 0 && strings.HasPrefix(line, "package ") && !importAdded {
-			// Search if there's a simple import
-			for j := i + 1; j < len(lines); j++ {
-				if strings.HasPrefix(strings.TrimSpace(lines[j]), "import ") {
-					importAdded = true
-					break
-				}
-				if strings.TrimSpace(lines[j]) != "" {
-					// We reached the code, insert import here
-					newLines = append(newLines, "")
-					newLines = append(newLines, "import (")
-					newLines = append(newLines, fmt.Sprintf("\t\"%s\"", importPath))
-					newLines = append(newLines, ")")
-					importAdded = true
-					break
-				}
-			}
-		}
-	}
+// UpdateGoMod updates the go.mod of the project with a new dependency
+func UpdateGoMod(changes *changeset.Set, projectRoot, module, version string) error {
+	_, err := source.AddRequire(changes, filepath.Join(projectRoot, "go.mod"), module, version)
+	return err
+}
 
-	return WriteFile(filePath, strings.Join(newLines, "\n"))
+// RemoveFromGoMod removes a dependency from the go.mod of the project
+func RemoveFromGoMod(changes *changeset.Set, projectRoot, module string) error {
+	_, err := source.RemoveRequire(changes, filepath.Join(projectRoot, "go.mod"), module)
+	return err
 }
 
-// UpdateGoMod updates go.mod with a new dependency
-func UpdateGoMod(module, version string) error {
-	// Read current go.mod
-	content, err := ReadFile("go.mod")
+// removeUnusedRequire removes a dependency from the go.mod of the project
+// unless Go files of the project still import it, as staged. Reports
+// whether it was removed.
+func removeUnusedRequire(changes *changeset.Set, projectRoot, module string) (bool, error) {
+	importers, err := importersOf(changes, projectRoot, module)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if len(importers) > 0 {
+		fmt.Printf("   ⏭️  Kept %s in go.mod (imported by %s)\n", module, strings.Join(importers, ", "))
+		return false, nil
 	}
+	return true, RemoveFromGoMod(changes, projectRoot, module)
+}
 
-	// If it already has the dependency, do nothing
-	if strings.Contains(content, module) {
+// importersOf returns the Go files of the project importing a module or
+// one of its packages, as staged, relative to the project root. The
+// directories the go tool ignores are skipped.
+func importersOf(changes *changeset.Set, projectRoot, module string) ([]string, error) {
+	paths := make(map[string]bool)
+	err := filepath.WalkDir(projectRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != projectRoot && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(p, ".go") {
+			paths[filepath.Clean(p)] = true
+		}
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	// Add to require block
-	lines := strings.Split(content, "\n")
-	newLines := []string{}
-	requireAdded := false
-
-	for _, line := range lines {
-		newLines = append(newLines, line)
-
-		if strings.Contains(line, "require (") && !requireAdded {
-			newLines = append(newLines, fmt.Sprintf("\t%s %s", module, version))
-			requireAdded = true
+	for _, change := range changes.Changes() {
+		if change.Kind == changeset.Create && strings.HasSuffix(change.Path, ".go") {
+			paths[filepath.Clean(change.Path)] = true
 		}
 	}
 
-	// If there's no require block, add at the end
-	if !requireAdded {
-		newLines = append(newLines, "")
-		newLines = append(newLines, "require (")
-		newLines = append(newLines, fmt.Sprintf("\t%s %s", module, version))
-		newLines = append(newLines, ")")
+	var importers []string
+	for path := range paths {
+		// Files whose removal is staged cannot be read
+		content, err := changes.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, content, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range file.Imports {
+			value, _ := strconv.Unquote(spec.Path.Value)
+			if value == module || strings.HasPrefix(value, module+"/") {
+				rel, err := filepath.Rel(projectRoot, path)
+				if err != nil {
+					return nil, err
+				}
+				importers = append(importers, filepath.ToSlash(rel))
+				break
+			}
+		}
 	}
-
-	return WriteFile("go.mod", strings.Join(newLines, "\n"))
+	sort.Strings(importers)
+	return importers, nil
 }
 
-// UpdateEnvExample updates .env.example with new variables
-func UpdateEnvExample(variables map[string]string, section string) error {
+// UpdateEnvExample adds a section of variables to the .env.example of the
+// project. Variables already set in the file (by another section) are
+// left out so every key appears once.
+func UpdateEnvExample(changes *changeset.Set, projectRoot string, variables map[string]string, section string) error {
+	path := filepath.Join(projectRoot, ".env.example")
 	content := ""
 
 	// Read existing if it exists
-	if FileExists(".env.example") {
-		existingContent, err := ReadFile(".env.example")
+	if FileExists(changes, path) {
+		existingContent, err := ReadFile(changes, path)
 		if err != nil {
 			return err
 		}
@@ -279,12 +634,93 @@ func UpdateEnvExample(variables map[string]string, section string) error {
 
 	// Add section if it doesn't exist
 	sectionHeader := fmt.Sprintf("\n# %s\n", section)
-	if !strings.Contains(content, sectionHeader) {
-		content += sectionHeader
-		for key, value := range variables {
-			content += fmt.Sprintf("%s=%s\n", key, value)
+	if strings.Contains(content, sectionHeader) {
+		return nil
+	}
+
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		if !hasEnvVar(content, key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += sectionHeader
+	for _, key := range keys {
+		content += fmt.Sprintf("%s=%s\n", key, variables[key])
+	}
+
+	return WriteFile(changes, path, content)
+}
+
+// hasEnvVar reports whether an env file sets key
+func hasEnvVar(content, key string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), key+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveEnvSection removes a section added by UpdateEnvExample: its
+// header and the variables following it
+func RemoveEnvSection(changes *changeset.Set, projectRoot, section string) error {
+	path := filepath.Join(projectRoot, ".env.example")
+	if !FileExists(changes, path) {
+		return nil
+	}
+	content, err := ReadFile(changes, path)
+	if err != nil {
+		return err
+	}
+
+	sectionHeader := fmt.Sprintf("\n# %s\n", section)
+	start := strings.Index(content, sectionHeader)
+	if start < 0 {
+		return nil
+	}
+
+	// The variables run up to the next blank line or comment
+	rest := content[start+len(sectionHeader):]
+	end := 0
+	for _, line := range strings.SplitAfter(rest, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || !strings.Contains(trimmed, "=") {
+			break
 		}
+		end += len(line)
+	}
+
+	return WriteFile(changes, path, content[:start]+rest[end:])
+}
+
+// removeMakefileTargets removes a block of targets appended to the
+// Makefile of the project. A block edited since is left for the user.
+func removeMakefileTargets(changes *changeset.Set, projectRoot, block string) error {
+	path := filepath.Join(projectRoot, "Makefile")
+	if !FileExists(changes, path) {
+		return nil
+	}
+	content, err := ReadFile(changes, path)
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(content, block) {
+		header, _, _ := strings.Cut(strings.TrimLeft(block, "\n"), "\n")
+		if strings.Contains(content, header) {
+			fmt.Printf("   ⚠️  The %q targets of the Makefile were edited, remove them by hand\n", strings.TrimPrefix(header, "# "))
+		}
+		return nil
 	}
 
-	return WriteFile(".env.example", content)
+	return WriteFile(changes, path, strings.Replace(content, block, "", 1))
 }
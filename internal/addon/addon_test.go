@@ -0,0 +1,334 @@
+package addon
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/geomark27/loom-go/internal/golden"
+	"github.com/geomark27/loom-go/internal/state"
+	"golang.org/x/mod/modfile"
+)
+
+// TestAddonsGolden installs the addons into a fresh project of each
+// architecture and compares the files they add or edit with golden files
+func TestAddonsGolden(t *testing.T) {
+	sets := [][]string{
+		{"gorm", "postgres", "jwt", "docker"},
+		{"postgres", "docker"},
+		{"sqlc"},
+		{"gorm", "sqlite"},
+		{"gorm", "mysql", "docker"},
+	}
+
+	for _, architecture := range []string{"layered", "modular"} {
+		for _, addons := range sets {
+			name := "addons-" + architecture + "-" + strings.Join(addons, "-")
+
+			t.Run(name, func(t *testing.T) {
+				t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+				root := filepath.Join(t.TempDir(), "shop")
+
+				config := &generator.ProjectConfig{
+					Name:         "shop",
+					Path:         root,
+					ModuleName:   "example.com/shop",
+					Description:  "shop project generated with Loom",
+					UseHelpers:   true,
+					IsModular:    architecture == "modular",
+					Architecture: architecture,
+					LoomVersion:  "1.0.0",
+					Addons:       addons,
+					Installer:    NewAddonManager(root, architecture),
+				}
+				if err := generator.New().GenerateProject(config); err != nil {
+					t.Fatalf("GenerateProject: %v", err)
+				}
+
+				files, err := golden.Changed(root)
+				if err != nil {
+					t.Fatal(err)
+				}
+				golden.ParseGo(t, root, files)
+
+				archive, err := golden.Archive(root, files)
+				if err != nil {
+					t.Fatal(err)
+				}
+				golden.Assert(t, name, archive)
+			})
+		}
+	}
+}
+
+// TestUninstallAddons installs addons into a project and removes them
+// again: the project must be back to what "loom new" generated
+// (formatting aside, which the verification phase restores)
+func TestUninstallAddons(t *testing.T) {
+	addons := []string{"gorm", "postgres", "jwt", "docker"}
+
+	for _, architecture := range []string{"layered", "modular"} {
+		t.Run(architecture, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			plain := newProject(t, architecture, nil)
+			root := newProject(t, architecture, addons)
+
+			manager := NewAddonManager(root, architecture)
+			for i := len(addons) - 1; i >= 0; i-- {
+				if err := manager.UninstallAddon(addons[i], false); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := manager.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := golden.Files(plain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := golden.Files(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Fatalf("files after removal:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+
+			for _, file := range want {
+				wantContent := readFormatted(t, filepath.Join(plain, file))
+				if file == "go.mod" {
+					// The models of the skeleton import GORM, whose
+					// requirement is kept
+					wantContent = withRequire(t, wantContent, "gorm.io/gorm", "v1.25.5")
+				}
+				if gotContent := readFormatted(t, filepath.Join(root, file)); gotContent != wantContent {
+					t.Errorf("%s not restored:\n%s", file, gotContent)
+				}
+			}
+		})
+	}
+}
+
+// newProject generates a project with the given addons
+func newProject(t *testing.T, architecture string, addons []string) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "shop")
+
+	config := &generator.ProjectConfig{
+		Name:         "shop",
+		Path:         root,
+		ModuleName:   "example.com/shop",
+		Description:  "shop project generated with Loom",
+		UseHelpers:   true,
+		IsModular:    architecture == "modular",
+		Architecture: architecture,
+		LoomVersion:  "1.0.0",
+		Addons:       addons,
+		Installer:    NewAddonManager(root, architecture),
+	}
+	if err := generator.New().GenerateProject(config); err != nil {
+		t.Fatalf("GenerateProject: %v", err)
+	}
+	return root
+}
+
+// readFormatted reads a file, gofmt'd when it is Go source or go.mod
+func readFormatted(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) == "go.mod" {
+		if file, err := modfile.Parse(path, content, nil); err == nil {
+			if formatted, err := file.Format(); err == nil {
+				return string(formatted)
+			}
+		}
+	}
+	if strings.HasSuffix(path, ".go") {
+		if formatted, err := format.Source(content); err == nil {
+			return string(formatted)
+		}
+	}
+	return string(content)
+}
+
+// withRequire adds a requirement to the content of a go.mod
+func withRequire(t *testing.T, goMod, path, version string) string {
+	t.Helper()
+	file, err := modfile.Parse("go.mod", []byte(goMod), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.AddRequire(path, version); err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := file.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(formatted)
+}
+
+// TestManifest checks that installing and removing addons keeps the
+// project manifest up to date, and that detectors read it first
+func TestManifest(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := newProject(t, "layered", []string{"gorm", "docker"})
+
+	m, err := state.LoadManifest(root)
+	if err != nil || m == nil {
+		t.Fatalf("LoadManifest: %v, %v", m, err)
+	}
+	if m.Version != "1.0.0" || m.Architecture != "layered" || m.Router != "gin" || m.ORM != "gorm" || m.Auth != "none" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+	if strings.Join(m.Databases, ",") != "postgres" {
+		t.Errorf("databases = %v, want [postgres]", m.Databases)
+	}
+	if !m.HasAddon("gorm") || !m.HasAddon("docker") {
+		t.Errorf("addons = %v, want docker and gorm", m.Addons)
+	}
+
+	manager := NewAddonManager(root, "layered")
+	if err := manager.UninstallAddon("docker", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ = state.LoadManifest(root); m.HasAddon("docker") {
+		t.Errorf("docker still recorded after removal: %v", m.Addons)
+	}
+
+	// What the manifest records wins over go.mod
+	m.Router = "echo"
+	if err := m.Save(root); err != nil {
+		t.Fatal(err)
+	}
+	if router := NewProjectDetector(root).DetectRouter(); router != "echo" {
+		t.Errorf("DetectRouter = %s, want the recorded echo", router)
+	}
+}
+
+// TestReplaceRouter checks that replacing the router rewrites the server,
+// routes and handlers of the skeleton, and keeps the previous router
+// required while code still imports it
+func TestReplaceRouter(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		architecture string
+		files        []string // rewritten without gin
+		extra        string   // a file still importing gin, if any
+	}{
+		{
+			architecture: "layered",
+			files: []string{
+				"internal/platform/server/server.go",
+				"internal/platform/server/routes.go",
+				"internal/app/handlers/health_handler.go",
+				"internal/app/handlers/user_handler.go",
+			},
+		},
+		{
+			architecture: "modular",
+			files: []string{
+				"internal/platform/server/server.go",
+				"internal/platform/server/router.go",
+				"internal/modules/users/handler.go",
+			},
+		},
+		{
+			architecture: "layered",
+			files:        []string{"internal/platform/server/server.go"},
+			extra:        "internal/app/handlers/product_handler.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.architecture+tt.extra, func(t *testing.T) {
+			root := newProject(t, tt.architecture, nil)
+			if tt.extra != "" {
+				content := "package handlers\n\nimport \"github.com/gin-gonic/gin\"\n\nfunc Products(c *gin.Context) {}\n"
+				if err := os.WriteFile(filepath.Join(root, tt.extra), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			manager := NewAddonManager(root, tt.architecture)
+			if err := manager.InstallAddon("chi", true); err != nil {
+				t.Fatal(err)
+			}
+			if err := manager.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			changes := changeset.New()
+			for _, file := range tt.files {
+				if path := filepath.Join(root, file); HasImport(changes, path, "github.com/gin-gonic/gin") {
+					t.Errorf("%s not rewritten for chi:\n%s", file, readFormatted(t, path))
+				}
+			}
+			if server := filepath.Join(root, "internal", "platform", "server", "server.go"); !HasImport(changes, server, "github.com/go-chi/chi/v5") {
+				t.Errorf("server not rewritten for chi:\n%s", readFormatted(t, server))
+			}
+
+			goMod := readFormatted(t, filepath.Join(root, "go.mod"))
+			if !strings.Contains(goMod, "github.com/go-chi/chi/v5 ") {
+				t.Errorf("go.mod does not require chi:\n%s", goMod)
+			}
+			if kept := strings.Contains(goMod, "github.com/gin-gonic/gin "); kept != (tt.extra != "") {
+				t.Errorf("gin required = %v, want it only while %q imports it:\n%s", kept, tt.extra, goMod)
+			}
+
+			if router := NewProjectDetector(root).DetectRouter(); router != "chi" {
+				t.Errorf("DetectRouter = %s, want chi", router)
+			}
+		})
+	}
+}
+
+// TestUninstallORMKeepsDatabase checks that removing GORM keeps the
+// database it connected to, and the requirements code still imports
+func TestUninstallORMKeepsDatabase(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := newProject(t, "layered", []string{"gorm", "postgres"})
+
+	manager := NewAddonManager(root, "layered")
+	if err := manager.UninstallAddon("gorm", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	goMod := readFormatted(t, filepath.Join(root, "go.mod"))
+	if !strings.Contains(goMod, "gorm.io/gorm ") {
+		t.Errorf("gorm.io/gorm removed while the User model imports it:\n%s", goMod)
+	}
+	for _, module := range []string{"gorm.io/driver/postgres", "github.com/spf13/cobra", "golang.org/x/crypto"} {
+		if strings.Contains(goMod, module) {
+			t.Errorf("go.mod still requires %s:\n%s", module, goMod)
+		}
+	}
+
+	m, err := state.LoadManifest(root)
+	if err != nil || m == nil {
+		t.Fatalf("LoadManifest: %v, %v", m, err)
+	}
+	if m.ORM != "none" || m.HasAddon("gorm") {
+		t.Errorf("GORM still recorded: orm %s, addons %v", m.ORM, m.Addons)
+	}
+	if strings.Join(m.Databases, ",") != "postgres" {
+		t.Errorf("databases = %v, want [postgres]", m.Databases)
+	}
+	if env := readFormatted(t, filepath.Join(root, ".env.example")); !strings.Contains(env, "DB_HOST=") {
+		t.Errorf("connection settings removed with GORM:\n%s", env)
+	}
+}
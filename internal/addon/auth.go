@@ -1,20 +1,27 @@
 package addon
 
-import "fmt"
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+)
 
 // AuthAddon manages authentication systems
 type AuthAddon struct {
 	projectRoot  string
 	architecture string
 	authType     string // "jwt", "oauth2"
+	changes      *changeset.Set
 }
 
 // NewAuthAddon creates a new authentication addon
-func NewAuthAddon(projectRoot, architecture, authType string) *AuthAddon {
+func NewAuthAddon(projectRoot, architecture, authType string, changes *changeset.Set) *AuthAddon {
 	return &AuthAddon{
 		projectRoot:  projectRoot,
 		architecture: architecture,
 		authType:     authType,
+		changes:      changes,
 	}
 }
 
@@ -31,14 +38,14 @@ func (a *AuthAddon) Description() string {
 }
 
 func (a *AuthAddon) IsInstalled() (bool, error) {
-	detector := NewProjectDetector(a.projectRoot)
+	detector := newProjectDetector(a.projectRoot, a.changes)
 	currentAuth := detector.DetectAuth()
 	return currentAuth == a.authType, nil
 }
 
 func (a *AuthAddon) CanInstall() (bool, string, error) {
 	// Check that there's no other auth system
-	detector := NewProjectDetector(a.projectRoot)
+	detector := newProjectDetector(a.projectRoot, a.changes)
 	currentAuth := detector.DetectAuth()
 
 	if currentAuth != "none" && currentAuth != a.authType {
@@ -74,31 +81,128 @@ func (a *AuthAddon) installJWT() error {
 	fmt.Println("   📦 Installing JWT Auth...")
 
 	// Add dependency
-	if err := UpdateGoMod("github.com/golang-jwt/jwt/v5", "v5.2.0"); err != nil {
+	if err := UpdateGoMod(a.changes, a.projectRoot, "github.com/golang-jwt/jwt/v5", "v5.2.0"); err != nil {
 		return err
 	}
 
-	// Update .env.example
-	envVars := map[string]string{
-		"JWT_SECRET":     "your-secret-key-change-this-in-production",
-		"JWT_EXPIRATION": "24h",
+	// Config fields (JWTSecret is part of the default config) and .env.example
+	fields := jwtFields
+	if err := addConfigFields(a.changes, a.projectRoot, fields, ""); err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	if err := UpdateEnvExample(a.changes, a.projectRoot, envVariables(fields), "JWT Authentication"); err != nil {
+		return err
 	}
 
-	if err := UpdateEnvExample(envVars, "JWT Authentication"); err != nil {
+	// Token helpers
+	if err := a.createJWTManager(); err != nil {
 		return err
 	}
 
 	fmt.Println("   ✅ JWT configured")
-	fmt.Println("   💡 Coming soon: Automatic generation of auth structure")
-
-	// TODO: Create complete auth structure
-	// - internal/auth/jwt.go
-	// - internal/auth/middleware.go
-	// - internal/handlers/auth_handler.go
+	fmt.Println("   💡 Issue and verify tokens with auth.NewJWTManager(cfg) in internal/auth/jwt.go")
 
 	return nil
 }
 
+// jwtFields are the config fields of JWT Auth
+var jwtFields = []configField{
+	{"JWTSecret", "JWT_SECRET", "your-secret-key-change-this-in-production"},
+	{"JWTExpiration", "JWT_EXPIRATION", "24h"},
+}
+
+// jwtPath returns the JWT manager written by installJWT
+func (a *AuthAddon) jwtPath() string {
+	return filepath.Join(a.projectRoot, "internal", "auth", "jwt.go")
+}
+
+// Uninstall removes the JWT manager, its dependency and settings
+func (a *AuthAddon) Uninstall() error {
+	if a.authType != "jwt" {
+		// OAuth2 installs nothing yet
+		return nil
+	}
+
+	fmt.Println("   🗑️  Removing JWT Auth...")
+	if err := RemoveFromGoMod(a.changes, a.projectRoot, "github.com/golang-jwt/jwt/v5"); err != nil {
+		return err
+	}
+	if err := RemoveFile(a.changes, a.jwtPath()); err != nil {
+		return err
+	}
+
+	// JWTSecret stays: it is part of the default config
+	if err := removeConfigFields(a.changes, a.projectRoot, jwtFields[1:], nil); err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	return RemoveEnvSection(a.changes, a.projectRoot, "JWT Authentication")
+}
+
+// createJWTManager writes internal/auth/jwt.go, issuing and verifying
+// tokens signed with the configured secret
+func (a *AuthAddon) createJWTManager() error {
+	moduleName, err := GetModuleName(a.projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to get module name: %w", err)
+	}
+
+	content := fmt.Sprintf(`package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"%s/internal/platform/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is returned for malformed, expired or forged tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTManager issues and verifies HS256 tokens
+type JWTManager struct {
+	secret     []byte
+	expiration time.Duration
+}
+
+// NewJWTManager creates a JWTManager from JWT_SECRET and JWT_EXPIRATION
+func NewJWTManager(cfg *config.Config) (*JWTManager, error) {
+	expiration, err := time.ParseDuration(cfg.JWTExpiration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_EXPIRATION: %%w", err)
+	}
+	return &JWTManager{secret: []byte(cfg.JWTSecret), expiration: expiration}, nil
+}
+
+// Generate returns a signed token for subject (usually the user ID)
+func (m *JWTManager) Generate(subject string) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(m.expiration)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// Verify checks a token and returns its subject
+func (m *JWTManager) Verify(token string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !parsed.Valid {
+		return "", ErrInvalidToken
+	}
+	return claims.Subject, nil
+}
+`, moduleName)
+
+	return WriteGenerated(a.changes, a.jwtPath(), content)
+}
+
 func (a *AuthAddon) installOAuth2() error {
 	fmt.Println("   📦 Installing OAuth2...")
 
@@ -0,0 +1,194 @@
+package addon
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/source"
+)
+
+// configPath returns the config.go of a project (both architectures keep
+// it in internal/platform/config)
+func configPath(projectRoot string) string {
+	return filepath.Join(projectRoot, "internal", "platform", "config", "config.go")
+}
+
+// projectName returns the name of a project: the name of its root directory
+func projectName(projectRoot string) string {
+	abs, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return filepath.Base(projectRoot)
+	}
+	return filepath.Base(abs)
+}
+
+// configField is a Config field loaded from an environment variable
+type configField struct {
+	Name    string // Go field name
+	Env     string // environment variable
+	Default string // value used when the variable is not set
+}
+
+// databaseFields returns the connection settings of a driver shared by
+// the ORM, database and Docker addons, so config.go, .env.example and
+// docker-compose.yml agree on names and defaults. A SQLite database is
+// only a file name.
+func databaseFields(projectRoot string, driver *sqlDriver) []configField {
+	if driver.Port == "" {
+		return []configField{{"DBName", "DB_NAME", projectName(projectRoot) + ".db"}}
+	}
+
+	fields := []configField{
+		{"DBHost", "DB_HOST", "localhost"},
+		{"DBPort", "DB_PORT", driver.Port},
+		{"DBName", "DB_NAME", projectName(projectRoot)},
+		{"DBUser", "DB_USER", driver.User},
+		{"DBPassword", "DB_PASSWORD", driver.Password},
+	}
+	if driver.Name == "postgres" {
+		fields = append(fields, configField{"DBSSLMode", "DB_SSLMODE", "disable"})
+	}
+	return fields
+}
+
+// allDatabaseFields returns the connection settings of every driver, the
+// fields removed with the database configuration
+func allDatabaseFields(projectRoot string) []configField {
+	var fields []configField
+	seen := make(map[string]bool)
+	for _, driver := range sqlDrivers {
+		for _, field := range databaseFields(projectRoot, driver) {
+			if !seen[field.Name] {
+				seen[field.Name] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// addConfigFields adds string fields to the Config struct and loads them
+// in Load(). Fields that already exist are left untouched.
+func addConfigFields(changes *changeset.Set, projectRoot string, fields []configField, decls string, imports ...string) error {
+	path := configPath(projectRoot)
+	if !changes.Exists(path) {
+		return fmt.Errorf("config not found at %s", path)
+	}
+
+	file, err := source.LoadFrom(changes, path)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if _, err := file.AddField("Config", field.Name+" string"); err != nil {
+			return err
+		}
+		value := fmt.Sprintf("getEnv(%q, %q)", field.Env, field.Default)
+		if _, err := file.AddLiteralField("Load", "Config", field.Name, value); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range imports {
+		if _, err := file.AddImport(path); err != nil {
+			return err
+		}
+	}
+	if decls != "" {
+		if _, err := file.AddDecl(decls); err != nil {
+			return err
+		}
+	}
+
+	return file.Save()
+}
+
+// removeConfigFields removes fields added by addConfigFields from the
+// Config struct and Load(), with the given methods of Config and the
+// imports nothing uses anymore
+func removeConfigFields(changes *changeset.Set, projectRoot string, fields []configField, methods []string, imports ...string) error {
+	path := configPath(projectRoot)
+	if !changes.Exists(path) {
+		return nil
+	}
+
+	file, err := source.LoadFrom(changes, path)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if _, err := file.RemoveField("Config", field.Name); err != nil {
+			return err
+		}
+		if _, err := file.RemoveLiteralField("Load", "Config", field.Name); err != nil {
+			return err
+		}
+	}
+	for _, method := range methods {
+		if _, err := file.RemoveDecl("Config." + method); err != nil {
+			return err
+		}
+	}
+	for _, path := range imports {
+		if _, err := file.RemoveUnusedImport(path); err != nil {
+			return err
+		}
+	}
+
+	if !file.Changed() {
+		return nil
+	}
+	return file.Save()
+}
+
+// envVariables returns the .env.example entries of config fields
+func envVariables(fields []configField) map[string]string {
+	vars := make(map[string]string, len(fields))
+	for _, field := range fields {
+		vars[field.Env] = field.Default
+	}
+	return vars
+}
+
+// addDatabaseConfig adds the settings of a database driver to config.go
+// and .env.example. The first driver installed keeps its connection
+// string method.
+func addDatabaseConfig(changes *changeset.Set, projectRoot string, driver *sqlDriver) error {
+	fields := databaseFields(projectRoot, driver)
+	var imports []string
+	if driver.usesFmt() {
+		imports = append(imports, "fmt")
+	}
+	if err := addConfigFields(changes, projectRoot, fields, driver.connectionStringMethod(), imports...); err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	return UpdateEnvExample(changes, projectRoot, envVariables(fields), "Database")
+}
+
+// removeDatabaseConfig removes what addDatabaseConfig added, and the
+// database service of docker-compose.yml
+func removeDatabaseConfig(changes *changeset.Set, projectRoot string, driver *sqlDriver) error {
+	fields := allDatabaseFields(projectRoot)
+	if err := removeConfigFields(changes, projectRoot, fields, []string{"GetDBConnectionString"}, "fmt"); err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	if err := RemoveEnvSection(changes, projectRoot, "Database"); err != nil {
+		return err
+	}
+	if driver.Service == nil {
+		return nil
+	}
+	return removeComposeService(changes, projectRoot, driver.Name, envNames(fields))
+}
+
+// envNames returns the environment variables of config fields
+func envNames(fields []configField) []string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Env
+	}
+	return names
+}
@@ -1,20 +1,27 @@
 package addon
 
-import "fmt"
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+)
 
 // DatabaseAddon manages database configuration
 type DatabaseAddon struct {
 	projectRoot  string
 	architecture string
-	dbType       string // "postgres", "mysql", "mongodb", "redis"
+	dbType       string // a SQL driver (postgres, mysql, sqlite, sqlserver), "mongodb" or "redis"
+	changes      *changeset.Set
 }
 
 // NewDatabaseAddon creates a new database addon
-func NewDatabaseAddon(projectRoot, architecture, dbType string) *DatabaseAddon {
+func NewDatabaseAddon(projectRoot, architecture, dbType string, changes *changeset.Set) *DatabaseAddon {
 	return &DatabaseAddon{
 		projectRoot:  projectRoot,
 		architecture: architecture,
 		dbType:       dbType,
+		changes:      changes,
 	}
 }
 
@@ -24,16 +31,18 @@ func (d *DatabaseAddon) Name() string {
 
 func (d *DatabaseAddon) Description() string {
 	descriptions := map[string]string{
-		"postgres": "PostgreSQL - Robust relational database",
-		"mysql":    "MySQL - Popular relational database",
-		"mongodb":  "MongoDB - Document-oriented NoSQL database",
-		"redis":    "Redis - In-memory database for caching",
+		"postgres":  "PostgreSQL - Robust relational database",
+		"mysql":     "MySQL - Popular relational database",
+		"sqlite":    "SQLite - Embedded file database (pure Go driver)",
+		"sqlserver": "SQL Server - Microsoft relational database",
+		"mongodb":   "MongoDB - Document-oriented NoSQL database",
+		"redis":     "Redis - In-memory database for caching",
 	}
 	return descriptions[d.dbType]
 }
 
 func (d *DatabaseAddon) IsInstalled() (bool, error) {
-	detector := NewProjectDetector(d.projectRoot)
+	detector := newProjectDetector(d.projectRoot, d.changes)
 	databases := detector.DetectDatabase()
 
 	for _, db := range databases {
@@ -56,54 +65,112 @@ func (d *DatabaseAddon) GetConflicts() []string {
 
 func (d *DatabaseAddon) Install(force bool) error {
 	switch d.dbType {
-	case "postgres":
-		return d.installPostgres()
-	case "mysql":
-		return d.installMySQL()
 	case "mongodb":
 		return d.installMongoDB()
 	case "redis":
 		return d.installRedis()
-	default:
+	}
+
+	driver, err := findSQLDriver(d.dbType)
+	if err != nil {
 		return fmt.Errorf("unsupported database: %s", d.dbType)
 	}
+	return d.installSQL(driver)
+}
+
+// Uninstall removes the driver and connection helper of the database
+func (d *DatabaseAddon) Uninstall() error {
+	driver, err := findSQLDriver(d.dbType)
+	if err != nil {
+		// The other databases install nothing yet
+		return nil
+	}
+
+	fmt.Printf("   🗑️  Removing %s...\n", driver.Label)
+	if err := RemoveFromGoMod(d.changes, d.projectRoot, driver.Module); err != nil {
+		return err
+	}
+	if err := RemoveFile(d.changes, d.helperPath(driver)); err != nil {
+		return err
+	}
+
+	// GORM shares the connection settings (go.mod is checked: the
+	// manifest is only updated once the removal is staged)
+	if newProjectDetector(d.projectRoot, d.changes).ormFromGoMod() == "gorm" {
+		return nil
+	}
+	return removeDatabaseConfig(d.changes, d.projectRoot, driver)
+}
+
+// helperPath returns the connection helper written by installSQL
+func (d *DatabaseAddon) helperPath(driver *sqlDriver) string {
+	return filepath.Join(d.projectRoot, "internal", "platform", "database", driver.HelperFile)
 }
 
-func (d *DatabaseAddon) installPostgres() error {
-	fmt.Println("   📦 Configuring PostgreSQL...")
+// installSQL adds the database/sql driver of a SQL database, its
+// connection settings and a connection helper
+func (d *DatabaseAddon) installSQL(driver *sqlDriver) error {
+	fmt.Printf("   📦 Configuring %s...\n", driver.Label)
 
 	// Add driver
-	if err := UpdateGoMod("github.com/lib/pq", "v1.10.9"); err != nil {
+	if err := UpdateGoMod(d.changes, d.projectRoot, driver.Module, driver.Version); err != nil {
 		return err
 	}
 
-	// Update .env.example
-	envVars := map[string]string{
-		"DB_HOST":     "localhost",
-		"DB_PORT":     "5432",
-		"DB_USER":     "postgres",
-		"DB_PASSWORD": "postgres",
-		"DB_NAME":     "app_db",
-		"DB_SSLMODE":  "disable",
+	// Connection settings in config.go and .env.example
+	if err := addDatabaseConfig(d.changes, d.projectRoot, driver); err != nil {
+		return err
 	}
 
-	if err := UpdateEnvExample(envVars, "PostgreSQL Database"); err != nil {
+	// Connection helper using the driver
+	if err := d.createConnection(driver); err != nil {
 		return err
 	}
 
-	fmt.Println("   ✅ PostgreSQL configured")
-	fmt.Println("   💡 Run 'loom add docker' to add PostgreSQL to docker-compose")
+	fmt.Printf("   ✅ %s configured\n", driver.Label)
+	if driver.Service != nil {
+		fmt.Printf("   💡 Run 'loom add docker' to add %s to docker-compose\n", driver.Label)
+	}
 
 	return nil
 }
 
-func (d *DatabaseAddon) installMySQL() error {
-	fmt.Println("   📦 Configuring MySQL...")
+// createConnection writes the connection helper of a SQL database in
+// internal/platform/database, opening a *sql.DB from the config
+func (d *DatabaseAddon) createConnection(driver *sqlDriver) error {
+	moduleName, err := GetModuleName(d.projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to get module name: %w", err)
+	}
 
-	// TODO: Implement MySQL
-	fmt.Println("   ⚠️  Full implementation coming soon")
+	content := fmt.Sprintf(`package database
 
-	return nil
+import (
+	"database/sql"
+	"fmt"
+
+	"%s/internal/platform/config"
+
+	_ "%s"
+)
+
+// %s opens a connection pool to the %s database of the config
+func %s(cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open(%q, cfg.GetDBConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %%w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %%w", err)
+	}
+
+	return db, nil
+}
+`, moduleName, driver.Module, driver.OpenFunc, driver.Label, driver.OpenFunc, driver.SQLName)
+
+	return WriteGenerated(d.changes, d.helperPath(driver), content)
 }
 
 func (d *DatabaseAddon) installMongoDB() error {
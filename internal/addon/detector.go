@@ -1,54 +1,126 @@
 package addon
 
 import (
-	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/source"
+	"github.com/geomark27/loom-go/internal/state"
 )
 
-// ProjectDetector detecta qué addons están instalados en el proyecto
+// ProjectDetector detecta qué addons están instalados en el proyecto.
+// Lo registrado en el manifiesto .loom/loom.yaml tiene prioridad; sin
+// él, se deduce de go.mod y de los archivos del proyecto.
 type ProjectDetector struct {
 	projectRoot string
+	changes     *changeset.Set
 }
 
 // NewProjectDetector crea un nuevo detector
 func NewProjectDetector(projectRoot string) *ProjectDetector {
+	return newProjectDetector(projectRoot, changeset.New())
+}
+
+// newProjectDetector crea un detector que también ve los cambios
+// preparados (staged) por los addons que se están instalando
+func newProjectDetector(projectRoot string, changes *changeset.Set) *ProjectDetector {
 	return &ProjectDetector{
 		projectRoot: projectRoot,
+		changes:     changes,
 	}
 }
 
+// path resuelve una ruta relativa a la raíz del proyecto
+func (pd *ProjectDetector) path(rel string) string {
+	return filepath.Join(pd.projectRoot, rel)
+}
+
+// manifest lee el manifiesto del proyecto (.loom/loom.yaml), tal como
+// está preparado; nil si el proyecto no tiene uno
+func (pd *ProjectDetector) manifest() *state.Manifest {
+	content, err := pd.changes.ReadFile(state.ManifestPath(pd.projectRoot))
+	if err != nil {
+		return nil
+	}
+	manifest, err := state.ParseManifest(content)
+	if err != nil {
+		return nil
+	}
+	return manifest
+}
+
 // DetectRouter detecta qué router está usando el proyecto
 func (pd *ProjectDetector) DetectRouter() string {
-	goModContent, err := ReadFile("go.mod")
+	if m := pd.manifest(); m != nil && m.Router != "" {
+		return m.Router
+	}
+	return pd.routerFromGoMod()
+}
+
+// routerFromGoMod deduce el router de las dependencias de go.mod
+func (pd *ProjectDetector) routerFromGoMod() string {
+	goModContent, err := ReadFile(pd.changes, pd.path("go.mod"))
 	if err != nil {
 		return "unknown"
 	}
 
-	if strings.Contains(goModContent, "github.com/gin-gonic/gin") {
-		return "gin"
-	}
-	if strings.Contains(goModContent, "github.com/go-chi/chi") {
-		return "chi"
+	var routers []string
+	for _, router := range []string{"gin", "chi", "echo", "gorilla-mux"} {
+		if strings.Contains(goModContent, routerPackages[router]) {
+			routers = append(routers, router)
+		}
 	}
-	if strings.Contains(goModContent, "github.com/labstack/echo") {
-		return "echo"
+	if len(routers) == 0 {
+		return "none"
 	}
-	if strings.Contains(goModContent, "github.com/gorilla/mux") {
-		return "gorilla-mux"
+
+	// Un router reemplazado sigue requerido mientras el código lo importe:
+	// el router es entonces el que importa el servidor
+	if len(routers) > 1 {
+		server, err := source.LoadFrom(pd.changes, pd.path(filepath.Join("internal", "platform", "server", "server.go")))
+		if err == nil {
+			for _, router := range routers {
+				if server.HasImport(routerPackages[router]) {
+					return router
+				}
+			}
+		}
 	}
+	return routers[0]
+}
 
-	return "none"
+// routerPackages son los paquetes de los routers, por nombre
+var routerPackages = map[string]string{
+	"gin":         "github.com/gin-gonic/gin",
+	"chi":         "github.com/go-chi/chi/v5",
+	"echo":        "github.com/labstack/echo/v4",
+	"gorilla-mux": "github.com/gorilla/mux",
 }
 
 // DetectORM detecta qué ORM está usando el proyecto
 func (pd *ProjectDetector) DetectORM() string {
-	goModContent, err := ReadFile("go.mod")
+	if m := pd.manifest(); m != nil && m.ORM != "" {
+		return m.ORM
+	}
+	return pd.ormFromGoMod()
+}
+
+// ormFromGoMod deduce el ORM de las dependencias de go.mod
+func (pd *ProjectDetector) ormFromGoMod() string {
+	goModContent, err := ReadFile(pd.changes, pd.path("go.mod"))
 	if err != nil {
 		return "unknown"
 	}
 
+	// Los modelos del esqueleto también importan gorm.io/gorm: GORM está
+	// instalado cuando se requiere junto con un dialector
 	if strings.Contains(goModContent, "gorm.io/gorm") {
-		return "gorm"
+		for _, driver := range sqlDrivers {
+			if strings.Contains(goModContent, driver.GormModule) {
+				return "gorm"
+			}
+		}
 	}
 	if strings.Contains(goModContent, "github.com/sqlc-dev/sqlc") {
 		return "sqlc"
@@ -62,19 +134,26 @@ func (pd *ProjectDetector) DetectORM() string {
 
 // DetectDatabase detecta qué drivers de base de datos están instalados
 func (pd *ProjectDetector) DetectDatabase() []string {
+	if m := pd.manifest(); m != nil && m.Databases != nil {
+		return m.Databases
+	}
+	return pd.databasesFromGoMod()
+}
+
+// databasesFromGoMod deduce las bases de datos de las dependencias de go.mod
+func (pd *ProjectDetector) databasesFromGoMod() []string {
 	databases := []string{}
-	goModContent, err := ReadFile("go.mod")
+	goModContent, err := ReadFile(pd.changes, pd.path("go.mod"))
 	if err != nil {
 		return databases
 	}
 
-	if strings.Contains(goModContent, "github.com/lib/pq") ||
-		strings.Contains(goModContent, "gorm.io/driver/postgres") {
-		databases = append(databases, "postgres")
-	}
-	if strings.Contains(goModContent, "github.com/go-sql-driver/mysql") ||
-		strings.Contains(goModContent, "gorm.io/driver/mysql") {
-		databases = append(databases, "mysql")
+	// Bases de datos SQL, por su driver de database/sql o su dialector de GORM
+	for _, driver := range sqlDrivers {
+		if strings.Contains(goModContent, driver.Module) ||
+			strings.Contains(goModContent, driver.GormModule) {
+			databases = append(databases, driver.Name)
+		}
 	}
 	if strings.Contains(goModContent, "go.mongodb.org/mongo-driver") {
 		databases = append(databases, "mongodb")
@@ -88,8 +167,16 @@ func (pd *ProjectDetector) DetectDatabase() []string {
 
 // DetectAuth detecta qué sistema de autenticación está instalado
 func (pd *ProjectDetector) DetectAuth() string {
+	if m := pd.manifest(); m != nil && m.Auth != "" {
+		return m.Auth
+	}
+	return pd.authFromFiles()
+}
+
+// authFromFiles deduce la autenticación de los archivos del proyecto
+func (pd *ProjectDetector) authFromFiles() string {
 	// Verificar si existe internal/auth o pkg/auth
-	if FileExists("internal/auth") || FileExists("pkg/auth") {
+	if pd.changes.IsDir(pd.path("internal/auth")) || pd.changes.IsDir(pd.path("pkg/auth")) {
 		// Buscar JWT
 		authFiles := []string{
 			"internal/auth/jwt.go",
@@ -97,8 +184,8 @@ func (pd *ProjectDetector) DetectAuth() string {
 		}
 
 		for _, file := range authFiles {
-			if FileExists(file) {
-				content, _ := ReadFile(file)
+			if FileExists(pd.changes, pd.path(file)) {
+				content, _ := ReadFile(pd.changes, pd.path(file))
 				if strings.Contains(content, "github.com/golang-jwt/jwt") {
 					return "jwt"
 				}
@@ -112,7 +199,7 @@ func (pd *ProjectDetector) DetectAuth() string {
 		}
 
 		for _, file := range authFiles {
-			if FileExists(file) {
+			if FileExists(pd.changes, pd.path(file)) {
 				return "oauth2"
 			}
 		}
@@ -125,7 +212,10 @@ func (pd *ProjectDetector) DetectAuth() string {
 
 // DetectDocker detecta si el proyecto tiene Docker configurado
 func (pd *ProjectDetector) DetectDocker() bool {
-	return FileExists("Dockerfile") || FileExists("docker-compose.yml")
+	if m := pd.manifest(); m != nil && m.HasAddon("docker") {
+		return true
+	}
+	return FileExists(pd.changes, pd.path("Dockerfile")) || FileExists(pd.changes, pd.path("docker-compose.yml"))
 }
 
 // GetProjectStatus retorna el estado completo del proyecto
@@ -147,10 +237,13 @@ func (pd *ProjectDetector) HasDatabaseConfigured() bool {
 
 // GetArchitecture detecta la arquitectura del proyecto
 func (pd *ProjectDetector) GetArchitecture() string {
-	if _, err := os.Stat("internal/modules"); err == nil {
+	if m := pd.manifest(); m != nil && m.Architecture != "" {
+		return m.Architecture
+	}
+	if pd.changes.IsDir(pd.path("internal/modules")) {
 		return "modular"
 	}
-	if _, err := os.Stat("internal/app"); err == nil {
+	if pd.changes.IsDir(pd.path("internal/app")) {
 		return "layered"
 	}
 	return "unknown"
@@ -3,19 +3,24 @@ package addon
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/changeset"
 )
 
 // DockerAddon manages Docker configuration
 type DockerAddon struct {
 	projectRoot  string
 	architecture string
+	changes      *changeset.Set
 }
 
 // NewDockerAddon creates a new Docker addon
-func NewDockerAddon(projectRoot, architecture string) *DockerAddon {
+func NewDockerAddon(projectRoot, architecture string, changes *changeset.Set) *DockerAddon {
 	return &DockerAddon{
 		projectRoot:  projectRoot,
 		architecture: architecture,
+		changes:      changes,
 	}
 }
 
@@ -28,7 +33,7 @@ func (d *DockerAddon) Description() string {
 }
 
 func (d *DockerAddon) IsInstalled() (bool, error) {
-	detector := NewProjectDetector(d.projectRoot)
+	detector := newProjectDetector(d.projectRoot, d.changes)
 	return detector.DetectDocker(), nil
 }
 
@@ -58,7 +63,7 @@ func (d *DockerAddon) Install(force bool) error {
 	}
 
 	// 4. Update Makefile if it exists
-	if FileExists("Makefile") {
+	if FileExists(d.changes, filepath.Join(d.projectRoot, "Makefile")) {
 		if err := d.updateMakefile(); err != nil {
 			return fmt.Errorf("error updating Makefile: %w", err)
 		}
@@ -72,10 +77,21 @@ func (d *DockerAddon) Install(force bool) error {
 	return nil
 }
 
+// Uninstall removes the Docker files and Makefile targets
+func (d *DockerAddon) Uninstall() error {
+	fmt.Println("   🗑️  Removing Docker files...")
+	for _, name := range []string{"Dockerfile", ".dockerignore", "docker-compose.yml"} {
+		if err := RemoveFile(d.changes, filepath.Join(d.projectRoot, name)); err != nil {
+			return err
+		}
+	}
+	return removeMakefileTargets(d.changes, d.projectRoot, dockerTargets)
+}
+
 func (d *DockerAddon) createDockerfile() error {
 	fmt.Println("   📝 Creating Dockerfile...")
 
-	content := `# Build stage
+	content := fmt.Sprintf(`# Build stage
 FROM golang:1.23-alpine AS builder
 
 # Install build dependencies
@@ -83,8 +99,8 @@ RUN apk add --no-cache git
 
 WORKDIR /app
 
-# Copy go.mod and go.sum
-COPY go.mod go.sum ./
+# Copy go.mod and go.sum (created by go mod tidy)
+COPY go.mod go.sum* ./
 
 # Download dependencies
 RUN go mod download
@@ -93,7 +109,7 @@ RUN go mod download
 COPY . .
 
 # Build the application
-RUN CGO_ENABLED=0 GOOS=linux go build -a -installsuffix cgo -o main ./cmd/app
+RUN CGO_ENABLED=0 GOOS=linux go build -a -installsuffix cgo -o main ./cmd/%s
 
 # Runtime stage
 FROM alpine:latest
@@ -111,9 +127,9 @@ EXPOSE 8080
 
 # Command to execute
 CMD ["./main"]
-`
+`, projectName(d.projectRoot))
 
-	return WriteFile(filepath.Join(d.projectRoot, "Dockerfile"), content)
+	return WriteGenerated(d.changes, filepath.Join(d.projectRoot, "Dockerfile"), content)
 }
 
 func (d *DockerAddon) createDockerignore() error {
@@ -153,14 +169,14 @@ docker-compose*.yml
 .loom-backups/
 `
 
-	return WriteFile(filepath.Join(d.projectRoot, ".dockerignore"), content)
+	return WriteGenerated(d.changes, filepath.Join(d.projectRoot, ".dockerignore"), content)
 }
 
 func (d *DockerAddon) createDockerCompose() error {
 	fmt.Println("   📝 Creating docker-compose.yml...")
 
 	// Detect if a database is configured
-	detector := NewProjectDetector(d.projectRoot)
+	detector := newProjectDetector(d.projectRoot, d.changes)
 	databases := detector.DetectDatabase()
 
 	content := `version: '3.8'
@@ -174,49 +190,59 @@ services:
       - PORT=8080
 `
 
-	// If it has PostgreSQL, add it
-	hasPostgres := false
+	// If it has a database server, add its service
+	var driver *sqlDriver
 	for _, db := range databases {
-		if db == "postgres" {
-			hasPostgres = true
+		if found, err := findSQLDriver(db); err == nil && found.Service != nil {
+			driver = found
 			break
 		}
 	}
 
-	if hasPostgres {
-		content += `      - DB_HOST=postgres
-      - DB_PORT=5432
-      - DB_USER=postgres
-      - DB_PASSWORD=postgres
-      - DB_NAME=app_db
-      - DB_SSLMODE=disable
-    depends_on:
-      - postgres
+	if driver != nil {
+		// Same settings as config.go and .env.example, with the database
+		// reachable through its service name
+		settings := make(map[string]string)
+		for _, field := range databaseFields(d.projectRoot, driver) {
+			settings[field.Env] = field.Default
+		}
+		settings["DB_HOST"] = driver.Name
+
+		for _, key := range []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE"} {
+			if value, ok := settings[key]; ok {
+				content += fmt.Sprintf("      - %s=%s\n", key, value)
+			}
+		}
+
+		var env strings.Builder
+		for _, variable := range driver.Service.Env(settings) {
+			fmt.Fprintf(&env, "      %s: %s\n", variable[0], variable[1])
+		}
+
+		content += fmt.Sprintf(`    depends_on:
+      - %[1]s
     volumes:
       - .:/app
     networks:
       - app-network
 
-  postgres:
-    image: postgres:16-alpine
+  %[1]s:
+    image: %[2]s
     environment:
-      POSTGRES_USER: postgres
-      POSTGRES_PASSWORD: postgres
-      POSTGRES_DB: app_db
-    ports:
-      - "5432:5432"
+%[3]s    ports:
+      - "%[4]s:%[4]s"
     volumes:
-      - postgres_data:/var/lib/postgresql/data
+      - %[1]s_data:%[5]s
     networks:
       - app-network
 
 volumes:
-  postgres_data:
+  %[1]s_data:
 
 networks:
   app-network:
     driver: bridge
-`
+`, driver.Name, driver.Service.Image, env.String(), driver.Port, driver.Service.Volume)
 	} else {
 		content += `    volumes:
       - .:/app
@@ -229,49 +255,130 @@ networks:
 `
 	}
 
-	return WriteFile(filepath.Join(d.projectRoot, "docker-compose.yml"), content)
+	return WriteGenerated(d.changes, filepath.Join(d.projectRoot, "docker-compose.yml"), content)
 }
 
 func (d *DockerAddon) updateMakefile() error {
 	fmt.Println("   📝 Updating Makefile...")
 
-	content, err := ReadFile("Makefile")
+	makefilePath := filepath.Join(d.projectRoot, "Makefile")
+	content, err := ReadFile(d.changes, makefilePath)
 	if err != nil {
 		return err
 	}
 
 	// Check if it already has Docker commands
+	if strings.Contains(content, "docker-build:") {
+		return nil
+	}
 	if content != "" && content[len(content)-1:] != "\n" {
 		content += "\n"
 	}
 
-	dockerCommands := `
+	content += dockerTargets
+
+	return WriteFile(d.changes, makefilePath, content)
+}
+
+// dockerTargets are the Makefile targets of Docker
+const dockerTargets = `
 # Docker commands
-.PHONY: docker-build docker-up docker-down docker-logs
+.PHONY: docker-build docker-up docker-down docker-logs docker-clean
 
-docker-build:
+docker-build: ## Build the Docker image
 	@echo "Building Docker image..."
 	docker-compose build
 
-docker-up:
+docker-up: ## Start the containers
 	@echo "Starting containers..."
 	docker-compose up -d
 
-docker-down:
+docker-down: ## Stop the containers
 	@echo "Stopping containers..."
 	docker-compose down
 
-docker-logs:
+docker-logs: ## Follow the application logs
 	@echo "Showing logs..."
 	docker-compose logs -f app
 
-docker-clean:
+docker-clean: ## Remove containers, volumes and dangling resources
 	@echo "Cleaning Docker resources..."
 	docker-compose down -v
 	docker system prune -f
 `
 
-	content += dockerCommands
+// removeComposeService removes a service from the docker-compose.yml of
+// the project: its block under services, its <service>_data volume and,
+// in the other services, the depends_on entries and the environment
+// variables (env) referring to it
+func removeComposeService(changes *changeset.Set, projectRoot, service string, env []string) error {
+	path := filepath.Join(projectRoot, "docker-compose.yml")
+	if !FileExists(changes, path) {
+		return nil
+	}
+	content, err := ReadFile(changes, path)
+	if err != nil {
+		return err
+	}
+
+	removedEnv := make(map[string]bool, len(env))
+	for _, name := range env {
+		removedEnv[name] = true
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		// The service or its volume, with their nested lines and the
+		// blank line before them
+		case composeIndent(line) == 2 && (trimmed == service+":" || trimmed == service+"_data:"):
+			for i+1 < len(lines) && composeIndent(lines[nextContent(lines, i+1)]) > 2 {
+				i = nextContent(lines, i+1)
+			}
+			if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+				out = out[:len(out)-1]
+			}
+		case trimmed == "- "+service:
+		case strings.HasPrefix(trimmed, "- ") && removedEnv[strings.SplitN(trimmed[2:], "=", 2)[0]]:
+		default:
+			out = append(out, line)
+		}
+	}
 
-	return WriteFile("Makefile", content)
+	// Drop the keys the removal left empty
+	var kept []string
+	for i, line := range out {
+		trimmed := strings.TrimSpace(line)
+		emptied := trimmed == "depends_on:" || trimmed == "environment:" || line == "volumes:\n"
+		if emptied && composeIndent(out[nextContent(out, i+1)]) <= composeIndent(line) {
+			if len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" && composeIndent(line) == 0 {
+				kept = kept[:len(kept)-1]
+			}
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	return WriteFile(changes, path, strings.Join(kept, ""))
+}
+
+// composeIndent returns the indentation of a YAML line
+func composeIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// nextContent returns the index of the first non-blank line from i, or
+// of the last line
+func nextContent(lines []string, i int) int {
+	for i < len(lines)-1 && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i >= len(lines) {
+		return len(lines) - 1
+	}
+	return i
 }
@@ -0,0 +1,180 @@
+package addon
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlDriver describes a SQL database supported by the database addons and
+// by GORM: its drivers, connection settings and docker-compose service
+type sqlDriver struct {
+	Name  string // addon name ("postgres")
+	Label string // display name ("PostgreSQL")
+
+	// database/sql driver, used by the database addon
+	Module     string // go.mod requirement
+	Version    string
+	SQLName    string // name given to sql.Open
+	OpenFunc   string // connection helper written in internal/platform/database
+	HelperFile string
+
+	// GORM dialector, used by the ORM addon
+	GormModule  string
+	GormVersion string
+	GormPackage string // package name of the dialector ("postgres")
+
+	Port     string // default port ("" for file databases)
+	User     string // default user
+	Password string // default password
+	DSN      string // body of Config.GetDBConnectionString
+	Service  *composeService
+}
+
+// composeService is the docker-compose service running a database
+type composeService struct {
+	Image  string
+	Volume string // data directory inside the container
+	Env    func(settings map[string]string) [][2]string
+}
+
+// sqlDrivers are the supported SQL databases, in the order they are
+// listed and detected
+var sqlDrivers = []*sqlDriver{
+	{
+		Name:        "postgres",
+		Label:       "PostgreSQL",
+		Module:      "github.com/lib/pq",
+		Version:     "v1.10.9",
+		SQLName:     "postgres",
+		OpenFunc:    "OpenPostgres",
+		HelperFile:  "postgres.go",
+		GormModule:  "gorm.io/driver/postgres",
+		GormVersion: "v1.5.4",
+		GormPackage: "postgres",
+		Port:        "5432",
+		User:        "postgres",
+		Password:    "postgres",
+		DSN: `return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName, c.DBSSLMode)`,
+		Service: &composeService{
+			Image:  "postgres:16-alpine",
+			Volume: "/var/lib/postgresql/data",
+			Env: func(s map[string]string) [][2]string {
+				return [][2]string{
+					{"POSTGRES_USER", s["DB_USER"]},
+					{"POSTGRES_PASSWORD", s["DB_PASSWORD"]},
+					{"POSTGRES_DB", s["DB_NAME"]},
+				}
+			},
+		},
+	},
+	{
+		Name:        "mysql",
+		Label:       "MySQL",
+		Module:      "github.com/go-sql-driver/mysql",
+		Version:     "v1.7.1",
+		SQLName:     "mysql",
+		OpenFunc:    "OpenMySQL",
+		HelperFile:  "mysql.go",
+		GormModule:  "gorm.io/driver/mysql",
+		GormVersion: "v1.5.2",
+		GormPackage: "mysql",
+		Port:        "3306",
+		User:        "root",
+		Password:    "secret",
+		DSN: `return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local&multiStatements=true",
+		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)`,
+		Service: &composeService{
+			Image:  "mysql:8.0",
+			Volume: "/var/lib/mysql",
+			Env: func(s map[string]string) [][2]string {
+				return [][2]string{
+					{"MYSQL_ROOT_PASSWORD", s["DB_PASSWORD"]},
+					{"MYSQL_DATABASE", s["DB_NAME"]},
+				}
+			},
+		},
+	},
+	{
+		Name:        "sqlite",
+		Label:       "SQLite",
+		Module:      "modernc.org/sqlite",
+		Version:     "v1.27.0",
+		SQLName:     "sqlite",
+		OpenFunc:    "OpenSQLite",
+		HelperFile:  "sqlite.go",
+		GormModule:  "github.com/glebarez/sqlite",
+		GormVersion: "v1.10.0",
+		GormPackage: "sqlite",
+		DSN:         `return c.DBName`,
+	},
+	{
+		Name:        "sqlserver",
+		Label:       "SQL Server",
+		Module:      "github.com/microsoft/go-mssqldb",
+		Version:     "v1.6.0",
+		SQLName:     "sqlserver",
+		OpenFunc:    "OpenSQLServer",
+		HelperFile:  "sqlserver.go",
+		GormModule:  "gorm.io/driver/sqlserver",
+		GormVersion: "v1.5.2",
+		GormPackage: "sqlserver",
+		Port:        "1433",
+		User:        "sa",
+		Password:    "Loom_Passw0rd",
+		DSN: `return fmt.Sprintf("sqlserver://%s:%s@%s:%s?database=%s",
+		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)`,
+		Service: &composeService{
+			Image:  "mcr.microsoft.com/mssql/server:2022-latest",
+			Volume: "/var/opt/mssql",
+			Env: func(s map[string]string) [][2]string {
+				return [][2]string{
+					{"ACCEPT_EULA", "Y"},
+					{"MSSQL_SA_PASSWORD", s["DB_PASSWORD"]},
+				}
+			},
+		},
+	},
+}
+
+// defaultSQLDriver is the driver GORM uses when none is installed or given
+const defaultSQLDriver = "postgres"
+
+// findSQLDriver returns a supported SQL driver by name
+func findSQLDriver(name string) (*sqlDriver, error) {
+	for _, driver := range sqlDrivers {
+		if driver.Name == name {
+			return driver, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported database driver %q (use %s)", name, strings.Join(SQLDriverNames(), ", "))
+}
+
+// SQLDriverNames returns the names of the supported SQL drivers
+func SQLDriverNames() []string {
+	names := make([]string, len(sqlDrivers))
+	for i, driver := range sqlDrivers {
+		names[i] = driver.Name
+	}
+	return names
+}
+
+// connectionStringMethod returns Config.GetDBConnectionString, building
+// the DSN of the driver from the database fields
+func (d *sqlDriver) connectionStringMethod() string {
+	return fmt.Sprintf(`
+// GetDBConnectionString returns the database connection string.
+// DATABASE_URL takes precedence over the DB_* variables.
+func (c *Config) GetDBConnectionString() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
+	}
+	%s
+}
+`, d.DSN)
+}
+
+// usesFmt reports whether the connection string method needs fmt
+func (d *sqlDriver) usesFmt() bool {
+	return strings.Contains(d.DSN, "fmt.")
+}
@@ -2,9 +2,11 @@ package addon
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/generator"
 )
 
 // ORMAddon manages ORM installation
@@ -12,14 +14,17 @@ type ORMAddon struct {
 	projectRoot  string
 	architecture string
 	ormType      string // "gorm", "sqlc"
+	driver       string // SQL driver of GORM, "" to pick the installed database
+	changes      *changeset.Set
 }
 
 // NewORMAddon creates a new ORM addon
-func NewORMAddon(projectRoot, architecture, ormType string) *ORMAddon {
+func NewORMAddon(projectRoot, architecture, ormType string, changes *changeset.Set) *ORMAddon {
 	return &ORMAddon{
 		projectRoot:  projectRoot,
 		architecture: architecture,
 		ormType:      ormType,
+		changes:      changes,
 	}
 }
 
@@ -36,14 +41,14 @@ func (o *ORMAddon) Description() string {
 }
 
 func (o *ORMAddon) IsInstalled() (bool, error) {
-	detector := NewProjectDetector(o.projectRoot)
+	detector := newProjectDetector(o.projectRoot, o.changes)
 	currentORM := detector.DetectORM()
 	return currentORM == o.ormType, nil
 }
 
 func (o *ORMAddon) CanInstall() (bool, string, error) {
 	// Check that there's no other ORM installed
-	detector := NewProjectDetector(o.projectRoot)
+	detector := newProjectDetector(o.projectRoot, o.changes)
 	currentORM := detector.DetectORM()
 
 	if currentORM != "none" && currentORM != o.ormType {
@@ -75,87 +80,155 @@ func (o *ORMAddon) Install(force bool) error {
 	}
 }
 
-func (o *ORMAddon) installGORM() error {
-	fmt.Println("   📦 Installing GORM...")
-
-	// 1. Add GORM dependencies
-	fmt.Println("   📦 Adding GORM dependencies...")
-	deps := map[string]string{
-		"gorm.io/gorm":            "v1.25.5",
-		"gorm.io/driver/postgres": "v1.5.4",
-		"golang.org/x/crypto":     "v0.17.0",
-		"github.com/spf13/cobra":  "v1.9.1",
+// SetDriver sets the SQL driver GORM is installed with (postgres, mysql,
+// sqlite or sqlserver). Without one, the database installed in the
+// project is used, or PostgreSQL.
+func (o *ORMAddon) SetDriver(name string) error {
+	if _, err := findSQLDriver(name); err != nil {
+		return err
 	}
+	o.driver = name
+	return nil
+}
 
-	for module, version := range deps {
-		if err := UpdateGoMod(module, version); err != nil {
-			return fmt.Errorf("failed to add %s: %w", module, err)
-		}
+// sqlDriver returns the SQL driver GORM is installed with
+func (o *ORMAddon) sqlDriver() (*sqlDriver, error) {
+	if o.driver != "" {
+		return findSQLDriver(o.driver)
 	}
-
-	// 2. Create directory structure
-	fmt.Println("   📁 Creating database structure...")
-	dirs := []string{
-		filepath.Join(o.projectRoot, "internal", "database"),
-		filepath.Join(o.projectRoot, "internal", "database", "seeders"),
-		filepath.Join(o.projectRoot, "cmd", "console"),
+	for _, db := range newProjectDetector(o.projectRoot, o.changes).DetectDatabase() {
+		if driver, err := findSQLDriver(db); err == nil {
+			return driver, nil
+		}
 	}
+	return findSQLDriver(defaultSQLDriver)
+}
 
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+// installedDriver returns the SQL driver of the installed GORM, according
+// to the dialector required in go.mod
+func (o *ORMAddon) installedDriver() *sqlDriver {
+	goMod, _ := ReadFile(o.changes, filepath.Join(o.projectRoot, "go.mod"))
+	for _, driver := range sqlDrivers {
+		if strings.Contains(goMod, driver.GormModule) {
+			return driver
 		}
 	}
+	driver, _ := findSQLDriver(defaultSQLDriver)
+	return driver
+}
+
+func (o *ORMAddon) installGORM() error {
+	fmt.Println("   📦 Installing GORM...")
 
-	// 3. Generate database files
-	if err := o.generateDatabaseFiles(); err != nil {
+	driver, err := o.sqlDriver()
+	if err != nil {
 		return err
 	}
+	fmt.Printf("   🗄️  Driver: %s\n", driver.Label)
 
-	// 4. Generate console command
-	if err := o.generateConsoleCommand(); err != nil {
-		return err
+	// 1. Add GORM dependencies
+	fmt.Println("   📦 Adding GORM dependencies...")
+	for _, dep := range o.gormDependencies(driver) {
+		if err := UpdateGoMod(o.changes, o.projectRoot, dep[0], dep[1]); err != nil {
+			return fmt.Errorf("failed to add %s: %w", dep[0], err)
+		}
 	}
 
-	// 5. Update config to include database connection string
-	if err := o.updateConfigForDatabase(); err != nil {
+	// 2. Generate database files (internal/database and its seeders)
+	if err := o.generateDatabaseFiles(driver); err != nil {
 		return err
 	}
 
-	// 6. Update .env.example
-	envVars := map[string]string{
-		"DB_HOST":     "localhost",
-		"DB_PORT":     "5432",
-		"DB_NAME":     filepath.Base(o.projectRoot),
-		"DB_USER":     "postgres",
-		"DB_PASSWORD": "postgres",
-		"DB_SSLMODE":  "disable",
+	// 3. Generate console command
+	if err := o.generateConsoleCommand(driver); err != nil {
+		return err
 	}
 
-	if err := UpdateEnvExample(envVars, "Database Configuration"); err != nil {
+	// 4. Add the connection settings to config.go and .env.example
+	fmt.Println("   ⚙️  Updating config for database...")
+	if err := addDatabaseConfig(o.changes, o.projectRoot, driver); err != nil {
 		return err
 	}
 
-	// 7. Update Makefile
+	// 5. Update Makefile
 	if err := o.updateMakefileForDatabase(); err != nil {
 		return err
 	}
 
 	fmt.Println("✅ GORM installed successfully!")
 	fmt.Println("\n💡 Next steps:")
-	fmt.Println("   1. Add database fields to your Config struct:")
-	fmt.Println("      DBHost, DBPort, DBName, DBUser, DBPassword, DBSSLMode string")
-	fmt.Println("   2. Add GetDBConnectionString() method to Config")
-	fmt.Println("   3. Load DB environment variables in config.Load()")
-	fmt.Println("   4. Update .env with database credentials")
-	fmt.Println("   5. Run: go mod tidy")
-	fmt.Println("   6. Run migrations: go run cmd/console/main.go migrate --seed")
-	fmt.Println("   7. Or use Makefile: make db-migrate")
+	fmt.Println("   1. Update .env with database credentials")
+	fmt.Println("   2. Run: go mod tidy")
+	fmt.Println("   3. Run migrations: make db-migrate (or go run cmd/console/main.go migrate --seed)")
 	fmt.Println("\n📝 See generated files in internal/database/ and cmd/console/")
+	fmt.Println("   New migrations: loom make migration <name>")
 
 	return nil
 }
 
+// gormDependencies returns the go.mod requirements of GORM with the
+// dialector of driver. A slice keeps their order in go.mod stable.
+func (o *ORMAddon) gormDependencies(driver *sqlDriver) [][2]string {
+	deps := [][2]string{
+		{"gorm.io/gorm", "v1.25.5"},
+		{driver.GormModule, driver.GormVersion},
+		{"github.com/spf13/cobra", "v1.9.1"},
+	}
+	if o.architecture == "layered" {
+		// The user seeder hashes the password of the layered User model
+		deps = append(deps, [2]string{"golang.org/x/crypto", "v0.17.0"})
+	}
+	return deps
+}
+
+// Uninstall removes the ORM files, the dependencies nothing else imports
+// and the Makefile targets. The database GORM connects to stays installed.
+func (o *ORMAddon) Uninstall() error {
+	if o.ormType != "gorm" {
+		// sqlc installs nothing yet
+		return nil
+	}
+
+	fmt.Println("   🗑️  Removing GORM...")
+	driver := o.installedDriver()
+
+	for filename := range databaseTemplates {
+		if err := RemoveFile(o.changes, o.databaseFilePath(filename)); err != nil {
+			return err
+		}
+	}
+	for filename := range databaseSources {
+		if err := RemoveFile(o.changes, o.databaseFilePath(filename)); err != nil {
+			return err
+		}
+	}
+	if err := RemoveFile(o.changes, o.consolePath()); err != nil {
+		return err
+	}
+
+	// Checked once the files are removed: the models of the skeleton
+	// import gorm.io/gorm, which stays
+	for _, dep := range o.gormDependencies(driver) {
+		if _, err := removeUnusedRequire(o.changes, o.projectRoot, dep[0]); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dep[0], err)
+		}
+	}
+
+	if err := removeMakefileTargets(o.changes, o.projectRoot, databaseTargets); err != nil {
+		return err
+	}
+
+	// The connection settings belong to the SQL database, removed with it
+	// (without a manifest the databases of go.mod are checked, where the
+	// dialector is gone)
+	for _, db := range newProjectDetector(o.projectRoot, o.changes).DetectDatabase() {
+		if _, err := findSQLDriver(db); err == nil {
+			return nil
+		}
+	}
+	return removeDatabaseConfig(o.changes, o.projectRoot, driver)
+}
+
 func (o *ORMAddon) installSQLC() error {
 	fmt.Println("   📦 Installing sqlc...")
 
@@ -166,47 +239,70 @@ func (o *ORMAddon) installSQLC() error {
 	return nil
 }
 
+// databaseTemplates are the templates of the database files, by file name
+var databaseTemplates = map[string]string{
+	"database.go":    "database/database.go.tmpl",
+	"models_all.go":  "database/models_all.go.tmpl",
+	"seeders_all.go": "database/seeders_all.go.tmpl",
+	"user_seeder.go": "database/user_seeder.go.tmpl",
+	initialMigration: "database/create_users_table.go.tmpl",
+}
+
+// databaseSources are the database files written as plain Go code, by
+// file name: they are tested in the generator package
+var databaseSources = map[string]func() string{
+	"migrator.go":        generator.MigratorSource,
+	"database_seeder.go": generator.DatabaseSeederSource,
+	autodiffFile:         func() string { return generator.AutodiffSource("migrations") },
+}
+
+const (
+	// initialMigration is the migration creating the table of the example
+	// User model
+	initialMigration = "00000000000000_create_users_table.go"
+
+	// autodiffFile compares the models with the database schema
+	autodiffFile = "autodiff.go"
+)
+
+// databaseFilePath returns where a database file is generated: the
+// seeders live in internal/database/seeders, the migrations in
+// internal/database/migrations
+func (o *ORMAddon) databaseFilePath(filename string) string {
+	switch filename {
+	case "database.go", "models_all.go":
+		return filepath.Join(o.projectRoot, "internal", "database", filename)
+	case "migrator.go", initialMigration, autodiffFile:
+		return filepath.Join(o.projectRoot, "internal", "database", "migrations", filename)
+	}
+	return filepath.Join(o.projectRoot, "internal", "database", "seeders", filename)
+}
+
+// consolePath returns the console CLI for migrations and seeders
+func (o *ORMAddon) consolePath() string {
+	return filepath.Join(o.projectRoot, "cmd", "console", "main.go")
+}
+
 // generateDatabaseFiles creates database connection and model/seeder registry files
-func (o *ORMAddon) generateDatabaseFiles() error {
+func (o *ORMAddon) generateDatabaseFiles(driver *sqlDriver) error {
 	fmt.Println("   📝 Generating database files...")
 
-	// Get module name from go.mod
-	moduleName, err := GetModuleName(o.projectRoot)
+	data, err := o.templateData(driver)
 	if err != nil {
-		return fmt.Errorf("failed to get module name: %w", err)
+		return err
 	}
 
-	// Determine config path based on architecture
-	configPath := "internal/config"
-	modelsPath := "internal/models"
-	if o.architecture == "layered" {
-		configPath = "internal/platform/config"
-		modelsPath = "internal/app/models"
-	}
-
-	templates := map[string]string{
-		"database.go":        "database/database.go.tmpl",
-		"models_all.go":      "database/models_all.go.tmpl",
-		"seeders_all.go":     "database/seeders_all.go.tmpl",
-		"database_seeder.go": "database/database_seeder.go.tmpl",
-		"user_seeder.go":     "database/user_seeder.go.tmpl",
-	}
-
-	for filename, tmplName := range templates {
-		var targetPath string
-		if filename == "user_seeder.go" {
-			targetPath = filepath.Join(o.projectRoot, "internal", "database", "seeders", filename)
-		} else if filename == "seeders_all.go" || filename == "database_seeder.go" {
-			targetPath = filepath.Join(o.projectRoot, "internal", "database", "seeders", filename)
-		} else {
-			targetPath = filepath.Join(o.projectRoot, "internal", "database", filename)
+	for filename, tmplName := range databaseTemplates {
+		targetPath := o.databaseFilePath(filename)
+		if err := GenerateFileFromTemplate(o.changes, tmplName, targetPath, data); err != nil {
+			return fmt.Errorf("failed to generate %s: %w", filename, err)
 		}
+	}
 
-		if err := GenerateFileFromTemplate(tmplName, targetPath, map[string]interface{}{
-			"ModuleName": moduleName,
-			"ConfigPath": configPath,
-			"ModelsPath": modelsPath,
-		}); err != nil {
+	// The migrator, the seeder orchestrator and the schema diff of
+	// "loom make migration --auto"
+	for filename, source := range databaseSources {
+		if _, err := o.changes.WriteGenerated(o.databaseFilePath(filename), []byte(source())); err != nil {
 			return fmt.Errorf("failed to generate %s: %w", filename, err)
 		}
 	}
@@ -215,40 +311,40 @@ func (o *ORMAddon) generateDatabaseFiles() error {
 }
 
 // generateConsoleCommand creates the console CLI for migrations and seeders
-func (o *ORMAddon) generateConsoleCommand() error {
+func (o *ORMAddon) generateConsoleCommand(driver *sqlDriver) error {
 	fmt.Println("   📝 Generating console command...")
 
-	// Get module name from go.mod
+	data, err := o.templateData(driver)
+	if err != nil {
+		return err
+	}
+
+	return GenerateFileFromTemplate(o.changes, "console/main.go.tmpl", o.consolePath(), data)
+}
+
+// templateData returns the data of the database and console templates
+func (o *ORMAddon) templateData(driver *sqlDriver) (map[string]interface{}, error) {
 	moduleName, err := GetModuleName(o.projectRoot)
 	if err != nil {
-		return fmt.Errorf("failed to get module name: %w", err)
+		return nil, fmt.Errorf("failed to get module name: %w", err)
 	}
 
-	// Determine paths based on architecture
-	configPath := "internal/config"
-	modelsPath := "internal/models"
-	if o.architecture == "layered" {
-		configPath = "internal/platform/config"
-		modelsPath = "internal/app/models"
+	// Modular projects keep the example User model in its module
+	modelsPath := "internal/app/models"
+	if o.architecture == "modular" {
+		modelsPath = "internal/modules/users"
 	}
 
-	targetPath := filepath.Join(o.projectRoot, "cmd", "console", "main.go")
-	return GenerateFileFromTemplate("console/main.go.tmpl", targetPath, map[string]interface{}{
+	return map[string]interface{}{
+		"Name":       projectName(o.projectRoot),
 		"ModuleName": moduleName,
-		"ConfigPath": configPath,
+		"ConfigPath": "internal/platform/config",
 		"ModelsPath": modelsPath,
-	})
-}
-
-// updateConfigForDatabase adds database connection string method to config.go
-func (o *ORMAddon) updateConfigForDatabase() error {
-	fmt.Println("   ⚙️  Updating config for database...")
-	fmt.Println("   ℹ️  Manual config update required (see instructions below)")
-
-	// For now, we'll skip automatic config modification to avoid fragile string replacements
-	// User will need to add DB fields manually or we can improve this in v1.2.0
-
-	return nil
+		"Modular":    o.architecture == "modular",
+		"Driver":     driver.Name,
+		"GormDriver": driver.GormModule,
+		"GormOpen":   driver.GormPackage + ".Open",
+	}, nil
 }
 
 // updateMakefileForDatabase adds database-related targets to Makefile
@@ -258,50 +354,58 @@ func (o *ORMAddon) updateMakefileForDatabase() error {
 	makefilePath := filepath.Join(o.projectRoot, "Makefile")
 
 	// Check if Makefile exists
-	if _, err := os.Stat(makefilePath); os.IsNotExist(err) {
+	if !FileExists(o.changes, makefilePath) {
 		fmt.Println("   ℹ️  No Makefile found, skipping")
 		return nil
 	}
 
 	// Read current Makefile
-	content, err := os.ReadFile(makefilePath)
+	makefileStr, err := ReadFile(o.changes, makefilePath)
 	if err != nil {
 		return fmt.Errorf("failed to read Makefile: %w", err)
 	}
 
-	makefileStr := string(content)
-
 	// Check if database targets already exist
 	if strings.Contains(makefileStr, "db-migrate") {
 		fmt.Println("   ℹ️  Database targets already exist in Makefile")
 		return nil
 	}
 
-	// Add database targets
-	dbTargets := `
+	// Append the database targets to the end of Makefile
+	if makefileStr != "" && !strings.HasSuffix(makefileStr, "\n") {
+		makefileStr += "\n"
+	}
+	makefileStr += databaseTargets
+
+	// Write updated Makefile
+	if err := WriteFile(o.changes, makefilePath, makefileStr); err != nil {
+		return fmt.Errorf("failed to write Makefile: %w", err)
+	}
+
+	return nil
+}
+
+// databaseTargets are the Makefile targets of the ORM
+const databaseTargets = `
 # Database commands
-.PHONY: db-migrate db-seed db-fresh
+.PHONY: db-migrate db-rollback db-status db-seed db-fresh
 
-db-migrate:
+db-migrate: ## Run pending database migrations
 	@echo "Running migrations..."
 	@go run cmd/console/main.go migrate
 
-db-seed:
+db-rollback: ## Roll back the last batch of migrations
+	@echo "Rolling back migrations..."
+	@go run cmd/console/main.go rollback
+
+db-status: ## Show which migrations have been applied
+	@go run cmd/console/main.go status
+
+db-seed: ## Run database seeders
 	@echo "Running seeders..."
 	@go run cmd/console/main.go seed
 
-db-fresh:
+db-fresh: ## Drop all tables, migrate and seed
 	@echo "Fresh migration with seeds..."
 	@go run cmd/console/main.go migrate --fresh --seed
 `
-
-	// Append to the end of Makefile
-	makefileStr += dbTargets
-
-	// Write updated Makefile
-	if err := os.WriteFile(makefilePath, []byte(makefileStr), 0644); err != nil {
-		return fmt.Errorf("failed to write Makefile: %w", err)
-	}
-
-	return nil
-}
@@ -2,8 +2,14 @@ package addon
 
 import (
 	"fmt"
+	"maps"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/generator"
 )
 
 // RouterAddon manages HTTP router installation
@@ -11,14 +17,16 @@ type RouterAddon struct {
 	projectRoot  string
 	architecture string
 	routerType   string // "gin", "chi", "echo"
+	changes      *changeset.Set
 }
 
 // NewRouterAddon creates a new router addon
-func NewRouterAddon(projectRoot, architecture, routerType string) *RouterAddon {
+func NewRouterAddon(projectRoot, architecture, routerType string, changes *changeset.Set) *RouterAddon {
 	return &RouterAddon{
 		projectRoot:  projectRoot,
 		architecture: architecture,
 		routerType:   routerType,
+		changes:      changes,
 	}
 }
 
@@ -36,7 +44,7 @@ func (r *RouterAddon) Description() string {
 }
 
 func (r *RouterAddon) IsInstalled() (bool, error) {
-	detector := NewProjectDetector(r.projectRoot)
+	detector := newProjectDetector(r.projectRoot, r.changes)
 	currentRouter := detector.DetectRouter()
 	return currentRouter == r.routerType, nil
 }
@@ -67,247 +75,105 @@ func (r *RouterAddon) Install(force bool) error {
 		return fmt.Errorf("error adding dependency: %w", err)
 	}
 
-	// 2. Update server files
-	if err := r.updateServerFiles(); err != nil {
+	// 2. Rewrite the server, routes and handlers of the skeleton
+	if err := r.updateRouterFiles(); err != nil {
 		return fmt.Errorf("error updating server: %w", err)
 	}
 
-	// 3. Update handlers (if necessary)
-	fmt.Println("⚠️  Note: Existing handlers will need to be manually updated")
-	fmt.Println("   to use the new router API")
-
-	return nil
-}
-
-func (r *RouterAddon) addDependency() error {
-	modules := map[string]string{
-		"gin":  "github.com/gin-gonic/gin v1.9.1",
-		"chi":  "github.com/go-chi/chi/v5 v5.0.10",
-		"echo": "github.com/labstack/echo/v4 v4.11.3",
+	// 3. Remove the replaced router, unless code still imports it
+	if err := r.removeReplacedRouters(); err != nil {
+		return fmt.Errorf("error removing the previous router: %w", err)
 	}
 
-	parts := strings.Split(modules[r.routerType], " ")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid module format")
-	}
+	fmt.Println("⚠️  Note: Handlers of modules generated since the project was")
+	fmt.Println("   created will need to be manually updated to use the new router API")
 
-	fmt.Printf("   📦 Adding dependency: %s\n", modules[r.routerType])
-	return UpdateGoMod(parts[0], parts[1])
+	return nil
 }
 
-func (r *RouterAddon) updateServerFiles() error {
-	serverPath := r.getServerPath()
-
-	fmt.Printf("   📝 Updating %s\n", serverPath)
-
-	// Generate new content according to the router
-	newContent := r.generateServerContent()
-
-	return WriteFile(serverPath, newContent)
+// Uninstall leaves the router required: a router is only removed by the
+// one replacing it, which drops the dependency once it has rewritten the
+// files importing it
+func (r *RouterAddon) Uninstall() error {
+	return nil
 }
 
-func (r *RouterAddon) getServerPath() string {
-	if r.architecture == "modular" {
-		return filepath.Join(r.projectRoot, "internal", "platform", "server", "server.go")
-	}
-	return filepath.Join(r.projectRoot, "internal", "server", "server.go")
+// routerModules are the go.mod requirements of the routers
+var routerModules = map[string]string{
+	"gin":  "github.com/gin-gonic/gin v1.9.1",
+	"chi":  "github.com/go-chi/chi/v5 v5.0.10",
+	"echo": "github.com/labstack/echo/v4 v4.11.3",
 }
 
-func (r *RouterAddon) generateServerContent() string {
-	switch r.routerType {
-	case "gin":
-		return r.generateGinServer()
-	case "chi":
-		return r.generateChiServer()
-	case "echo":
-		return r.generateEchoServer()
-	default:
-		return ""
+func (r *RouterAddon) addDependency() error {
+	parts := strings.Split(routerModules[r.routerType], " ")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid module format")
 	}
-}
-
-func (r *RouterAddon) generateGinServer() string {
-	return `package server
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"net/http"
-	"time"
-
-	"github.com/gin-gonic/gin"
-)
-
-// Server represents the HTTP server
-type Server struct {
-	config *Config
-	router *gin.Engine
-	server *http.Server
-}
 
-// Config contains the server configuration
-type Config struct {
-	Port string
+	fmt.Printf("   📦 Adding dependency: %s\n", routerModules[r.routerType])
+	return UpdateGoMod(r.changes, r.projectRoot, parts[0], parts[1])
 }
 
-// NewServer creates a new server instance
-func NewServer(config *Config) *Server {
-	// Release mode in production
-	// gin.SetMode(gin.ReleaseMode)
-
-	router := gin.Default()
-
-	return &Server{
-		config: config,
-		router: router,
-		server: &http.Server{
-			Addr:    ":" + config.Port,
-			Handler: router,
-		},
+// updateRouterFiles rewrites the files of the skeleton that depend on the
+// router, as "loom new" generates them for this one. The edits made since
+// they were generated are merged in.
+func (r *RouterAddon) updateRouterFiles() error {
+	moduleName, err := GetModuleName(r.projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to get module name: %w", err)
 	}
-}
-
-// Start starts the server
-func (s *Server) Start() error {
-	log.Printf("🚀 Server started at http://localhost:%s", s.config.Port)
-	return s.server.ListenAndServe()
-}
-
-// Shutdown stops the server gracefully
-func (s *Server) Shutdown(ctx context.Context) error {
-	log.Println("Stopping server...")
-	return s.server.Shutdown(ctx)
-}
-
-// Router returns the router to configure routes
-func (s *Server) Router() *gin.Engine {
-	return s.router
-}
-`
-}
-
-func (r *RouterAddon) generateChiServer() string {
-	return `package server
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"net/http"
-	"time"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-)
-
-// Server represents the HTTP server
-type Server struct {
-	config *Config
-	router *chi.Mux
-	server *http.Server
-}
-
-// Config contains the server configuration
-type Config struct {
-	Port string
-}
-
-// NewServer creates a new server instance
-func NewServer(config *Config) *Server {
-	router := chi.NewRouter()
-
-	// Basic middleware
-	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.RequestID)
-
-	return &Server{
-		config: config,
-		router: router,
-		server: &http.Server{
-			Addr:    ":" + config.Port,
-			Handler: router,
-		},
+	goMod, err := ReadFile(r.changes, filepath.Join(r.projectRoot, "go.mod"))
+	if err != nil {
+		return err
 	}
-}
-
-// Start starts the server
-func (s *Server) Start() error {
-	log.Printf("🚀 Server started at http://localhost:%s", s.config.Port)
-	return s.server.ListenAndServe()
-}
-
-// Shutdown stops the server gracefully
-func (s *Server) Shutdown(ctx context.Context) error {
-	log.Println("Stopping server...")
-	return s.server.Shutdown(ctx)
-}
-
-// Router returns the router to configure routes
-func (s *Server) Router() *chi.Mux {
-	return s.router
-}
-`
-}
-
-func (r *RouterAddon) generateEchoServer() string {
-	return `package server
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"net/http"
-	"time"
-
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
-)
-
-// Server represents the HTTP server
-type Server struct {
-	config *Config
-	echo   *echo.Echo
-}
-
-// Config contains the server configuration
-type Config struct {
-	Port string
-}
-
-// NewServer creates a new server instance
-func NewServer(config *Config) *Server {
-	e := echo.New()
 
-	// Basic middleware
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-
-	// Hide banner
-	e.HideBanner = true
-
-	return &Server{
-		config: config,
-		echo:   e,
+	files, err := generator.New().RouterFiles(&generator.ProjectConfig{
+		Name:         path.Base(moduleName),
+		Path:         r.projectRoot,
+		ModuleName:   moduleName,
+		UseHelpers:   strings.Contains(goMod, "github.com/geomark27/loom-go"),
+		IsModular:    r.architecture == "modular",
+		Architecture: r.architecture,
+		Router:       r.routerType,
+	})
+	if err != nil {
+		return err
 	}
-}
 
-// Start starts the server
-func (s *Server) Start() error {
-	log.Printf("🚀 Server started at http://localhost:%s", s.config.Port)
-	return s.echo.Start(":" + s.config.Port)
+	for _, filePath := range slices.Sorted(maps.Keys(files)) {
+		// Files removed from the project (a destroyed users module) stay so
+		if !r.changes.Exists(filePath) {
+			continue
+		}
+		fmt.Printf("   📝 Updating %s\n", filePath)
+		if err := WriteGenerated(r.changes, filePath, string(files[filePath])); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// Shutdown stops the server gracefully
-func (s *Server) Shutdown(ctx context.Context) error {
-	log.Println("Stopping server...")
-	return s.echo.Shutdown(ctx)
-}
+// removeReplacedRouters removes the other routers required in go.mod, once
+// the rewritten files no longer import them
+func (r *RouterAddon) removeReplacedRouters() error {
+	goMod, err := ReadFile(r.changes, filepath.Join(r.projectRoot, "go.mod"))
+	if err != nil {
+		return err
+	}
 
-// Echo returns the Echo instance to configure routes
-func (s *Server) Echo() *echo.Echo {
-	return s.echo
-}
-`
+	for _, name := range []string{"gin", "chi", "echo"} {
+		module, _, _ := strings.Cut(routerModules[name], " ")
+		if name == r.routerType || !strings.Contains(goMod, module+" ") {
+			continue
+		}
+		removed, err := removeUnusedRequire(r.changes, r.projectRoot, module)
+		if err != nil {
+			return err
+		}
+		if removed {
+			fmt.Printf("   📦 Removed dependency: %s\n", module)
+		}
+	}
+	return nil
 }
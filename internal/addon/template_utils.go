@@ -3,11 +3,13 @@ package addon
 import (
 	"bytes"
 	"fmt"
+	"go/format"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
 
+	"github.com/geomark27/loom-go/internal/changeset"
 	"github.com/geomark27/loom-go/internal/generator"
 )
 
@@ -32,31 +34,40 @@ func GetModuleName(projectRoot string) (string, error) {
 	return "", fmt.Errorf("module name not found in go.mod")
 }
 
-// GenerateFileFromTemplate generates a file from a template
-func GenerateFileFromTemplate(templateName, targetPath string, data map[string]interface{}) error {
+// GenerateFileFromTemplate stages a file rendered from a template, merging
+// the edits made to the version generated before
+func GenerateFileFromTemplate(changes *changeset.Set, templateName, targetPath string, data map[string]interface{}) error {
 	// Get template content
 	content, err := generator.GetTemplateContent(templateName)
 	if err != nil {
 		return fmt.Errorf("failed to get template %s: %w", templateName, err)
 	}
 
-	// Parse template
-	tmpl, err := template.New(templateName).Parse(content)
+	// Parse template; a key missing from data is an error rather than
+	// "<no value>" in the generated code
+	tmpl, err := template.New(templateName).Option("missingkey=error").Parse(content)
 	if err != nil {
 		return fmt.Errorf("failed to parse template %s: %w", templateName, err)
 	}
 
-	// Create target file
-	file, err := os.Create(targetPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", targetPath, err)
-	}
-	defer file.Close()
-
 	// Execute template
-	if err := tmpl.Execute(file, data); err != nil {
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
 		return fmt.Errorf("failed to execute template %s: %w", templateName, err)
 	}
 
+	// gofmt Go files; an unparsable file is staged as is and rejected when
+	// the change set is committed
+	src := out.Bytes()
+	if strings.HasSuffix(targetPath, ".go") {
+		if formatted, err := format.Source(src); err == nil {
+			src = formatted
+		}
+	}
+
+	if _, err := changes.WriteGenerated(targetPath, src); err != nil {
+		return fmt.Errorf("failed to create file %s: %w", targetPath, err)
+	}
+
 	return nil
 }
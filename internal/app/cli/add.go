@@ -9,7 +9,9 @@ import (
 )
 
 var (
-	addForce bool
+	addForce  bool
+	addDryRun bool
+	addDriver string
 )
 
 var addCmd = &cobra.Command{
@@ -23,16 +25,28 @@ implementing authentication, and more.
 Available categories:
   router      - HTTP Frameworks (gin, chi, echo)
   orm         - ORMs (gorm, sqlc)
-  database    - Databases (postgres, mysql, mongodb, redis)
+  database    - Databases (postgres, mysql, sqlite, sqlserver, mongodb, redis)
   auth        - Authentication (jwt, oauth2)
   docker      - Containerization
 
 Examples:
   loom add router gin          # Switch to Gin
   loom add orm gorm            # Add GORM
+  loom add orm gorm --driver=sqlite  # Add GORM on SQLite
   loom add database postgres   # Configure PostgreSQL
   loom add auth jwt            # Add JWT auth
-  loom add docker              # Add Dockerfile`,
+  loom add docker              # Add Dockerfile
+
+Every file the addon creates or edits (go.mod, config.go, .env.example,
+Makefile...) is written at once; if anything fails, nothing is changed.
+Use --dry-run to print the changes as a unified diff instead.
+
+GORM uses the SQL database installed in the project (postgres, mysql,
+sqlite or sqlserver), PostgreSQL when there is none, or the one given with
+--driver. config.go gets the DB_* fields and the DSN builder of the driver.
+
+Replacing a conflicting addon with --force (switching routers, for
+instance) uninstalls it first, like 'loom remove' does.`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runAdd,
 }
@@ -41,6 +55,9 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 
 	addCmd.Flags().BoolVar(&addForce, "force", false, "Force installation (replaces existing)")
+	addCmd.Flags().BoolVar(&addDryRun, "dry-run", false, "Show the changes as a diff without writing them")
+	addCmd.Flags().StringVar(&addDriver, "driver", "", "SQL driver of 'orm gorm' (postgres, mysql, sqlite, sqlserver)")
+	addVerifyFlags(addCmd.Flags())
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
@@ -72,12 +89,33 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unrecognized addon: %s %s", category, name)
 	}
 
+	if addDriver != "" {
+		if addonName != "gorm" {
+			return fmt.Errorf("--driver only applies to 'loom add orm gorm'")
+		}
+		if err := manager.SetORMDriver(addDriver); err != nil {
+			return err
+		}
+	}
+
 	// Install addon
 	fmt.Printf("📦 Adding %s %s...\n\n", category, name)
 
 	if err := manager.InstallAddon(addonName, addForce); err != nil {
 		return err
 	}
+	if err := applyChanges(manager, addDryRun); err != nil {
+		return err
+	}
+	if addDryRun {
+		fmt.Println("\n💡 Run without --dry-run to apply the changes")
+		return nil
+	}
+	printConflicts(manager.Conflicts())
+
+	if _, err := verifyProject(cmd, projectInfo.RootPath, written); err != nil {
+		return err
+	}
 
 	// Show next steps
 	showNextSteps(category, name)
@@ -89,7 +127,7 @@ func mapCategoryToAddon(category, name string) string {
 	categories := map[string][]string{
 		"router":   {"gin", "chi", "echo"},
 		"orm":      {"gorm", "sqlc"},
-		"database": {"postgres", "mysql", "mongodb", "redis"},
+		"database": {"postgres", "mysql", "sqlite", "sqlserver", "mongodb", "redis"},
 		"auth":     {"jwt", "oauth2"},
 	}
 
@@ -124,12 +162,14 @@ func showAvailableAddons() error {
 	fmt.Println("   loom add router echo     - Echo Framework")
 
 	fmt.Println("\n💾 ORMs:")
-	fmt.Println("   loom add orm gorm        - GORM")
+	fmt.Println("   loom add orm gorm        - GORM (--driver=postgres|mysql|sqlite|sqlserver)")
 	fmt.Println("   loom add orm sqlc        - sqlc")
 
 	fmt.Println("\n🗄️  Databases:")
 	fmt.Println("   loom add database postgres   - PostgreSQL")
 	fmt.Println("   loom add database mysql      - MySQL")
+	fmt.Println("   loom add database sqlite     - SQLite (pure Go driver)")
+	fmt.Println("   loom add database sqlserver  - SQL Server")
 	fmt.Println("   loom add database mongodb    - MongoDB")
 	fmt.Println("   loom add database redis      - Redis")
 
@@ -156,20 +196,21 @@ func showNextSteps(category, name string) {
 
 	case "orm":
 		fmt.Println("   1. Run: go mod tidy")
-		fmt.Println("   2. Configure the database connection")
-		fmt.Println("   3. Update your repositories to use the ORM")
+		fmt.Println("   2. Copy .env.example to .env and set the DB_* credentials")
+		fmt.Println("   3. Run migrations: make db-migrate")
+		fmt.Println("   4. Update your repositories to use the ORM")
 
 	case "database":
 		fmt.Println("   1. Run: go mod tidy")
 		fmt.Println("   2. Copy .env.example to .env and configure credentials")
-		if name == "postgres" || name == "mysql" {
+		if name == "postgres" || name == "mysql" || name == "sqlserver" {
 			fmt.Println("   3. Consider running: loom add docker")
 		}
 
 	case "auth":
 		fmt.Println("   1. Run: go mod tidy")
 		fmt.Println("   2. Copy .env.example to .env and change JWT_SECRET")
-		fmt.Println("   3. Implement authentication endpoints")
+		fmt.Println("   3. Issue and verify tokens with auth.NewJWTManager(cfg) (internal/auth/jwt.go)")
 
 	case "docker":
 		fmt.Println("   1. Build the image: docker-compose build")
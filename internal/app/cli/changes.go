@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/state"
+)
+
+// stager is a generator (or addon manager) that stages its writes in a
+// change set until it is committed
+type stager interface {
+	Changes() *changeset.Set
+	Commit() error
+}
+
+// reviewer is a stager that lets the user keep or merge each overwrite
+type reviewer interface {
+	stager
+	Skip(path string)
+	Merge(path string) (bool, error)
+}
+
+// written lists the files created or updated by the last applyChanges;
+// the verification phase only reformats those
+var written []string
+
+// applyChanges writes the changes staged by gen, or prints them as a
+// unified diff with --dry-run
+func applyChanges(gen stager, dryRun bool) error {
+	if !dryRun {
+		written = written[:0]
+		for _, change := range gen.Changes().Changes() {
+			if change.Kind != changeset.Delete {
+				written = append(written, change.Path)
+			}
+		}
+		return gen.Commit()
+	}
+
+	changes := gen.Changes()
+	if changes.Empty() {
+		fmt.Println("📋 No changes")
+		return nil
+	}
+	fmt.Println("📋 Changes that would be made:")
+	fmt.Println()
+	return changes.Diff(os.Stdout, useColor())
+}
+
+// applyGenerated applies the changes staged by a generator. With --force
+// on a terminal, every existing file about to be overwritten is shown as a
+// diff first and the user accepts, skips or merges it. Returns the paths
+// that were skipped.
+func applyGenerated(gen reviewer, force, dryRun bool) (map[string]bool, error) {
+	skipped := make(map[string]bool)
+	if force && !dryRun && isTerminal() {
+		var err error
+		if skipped, err = reviewOverwrites(gen); err != nil {
+			return nil, err
+		}
+	}
+	return skipped, applyChanges(gen, dryRun)
+}
+
+// reviewOverwrites asks what to do with each staged overwrite
+func reviewOverwrites(gen reviewer) (map[string]bool, error) {
+	skipped := make(map[string]bool)
+	w := newWizard()
+	color := useColor()
+
+	for _, change := range gen.Changes().Changes() {
+		if change.Kind != changeset.Update {
+			continue
+		}
+
+		diff := change.Unified()
+		if color {
+			diff = changeset.Colorize(diff)
+		}
+		fmt.Fprintf(w.out, "\n%s\n", diff)
+		answer, err := w.choose(fmt.Sprintf("Overwrite %s? accept, skip or merge", change.Path), []string{"a", "s", "m"}, "a")
+		if err != nil {
+			return nil, err
+		}
+
+		switch answer {
+		case "s":
+			gen.Skip(change.Path)
+			skipped[change.Path] = true
+			fmt.Fprintf(w.out, "   ⏭️  %s kept as it is\n", change.Path)
+		case "m":
+			conflicts, err := gen.Merge(change.Path)
+			if err != nil {
+				return nil, fmt.Errorf("error merging %s: %w", change.Path, err)
+			}
+			if conflicts {
+				fmt.Fprintf(w.out, "   ⚠️  %s merged with conflicts, resolve the <<<<<<< markers\n", change.Path)
+			} else {
+				fmt.Fprintf(w.out, "   🔀 %s merged\n", change.Path)
+			}
+		}
+	}
+	fmt.Fprintln(w.out)
+
+	return skipped, nil
+}
+
+// trackFile records a file staged outside the generators in the
+// generation state, saved with the other staged writes
+func trackFile(changes *changeset.Set, root, path string, content []byte, generator string) error {
+	return trackFiles(changes, root, generator, map[string][]byte{path: content})
+}
+
+// trackFiles records several files of one generator at once: the state is
+// loaded and saved a single time, so every file stays recorded
+func trackFiles(changes *changeset.Set, root, generator string, files map[string][]byte) error {
+	st, err := state.Load(root)
+	if err != nil {
+		fmt.Printf("⚠️  %v (the files of %s will not be tracked)\n", err, generator)
+		return nil
+	}
+	for path, content := range files {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		st.Record(filepath.ToSlash(rel), content, generator, "")
+	}
+	if err := st.SaveTo(changes); err != nil {
+		return fmt.Errorf("error saving generation state: %w", err)
+	}
+	return nil
+}
+
+// printConflicts lists the files merged with conflicts
+func printConflicts(conflicts []string) {
+	if len(conflicts) == 0 {
+		return
+	}
+	fmt.Println("\n⚠️  Merged with conflicts (resolve the <<<<<<< markers):")
+	for _, file := range conflicts {
+		fmt.Printf("   %s\n", file)
+	}
+}
+
+// unskipped returns the files that were not skipped
+func unskipped(files []string, skipped map[string]bool) []string {
+	var kept []string
+	for _, file := range files {
+		if !skipped[file] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// useColor reports whether output goes to a terminal that wants colors
+// (see https://no-color.org)
+func useColor() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminalFile(os.Stdout)
+}
@@ -5,65 +5,241 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/geomark27/loom-go/internal/generator"
 	"github.com/spf13/cobra"
 )
 
-// Commands with colon syntax (loom db:migrate, loom db:fresh, loom db:seed)
+// Commands with colon syntax (loom db:migrate, loom db:rollback, ...)
 var dbMigrateCmd = &cobra.Command{
 	Use:   "db:migrate",
 	Short: "Run database migrations",
-	Long:  `Run all pending database migrations using GORM AutoMigrate.`,
-	RunE:  runDBMigrate,
+	Long: `Run the pending migrations of internal/database/migrations.
+
+The migrations applied together form a batch, recorded in the
+schema_migrations table. Create new ones with 'loom make migration'.`,
+	RunE: runDBMigrate,
+}
+
+var dbRollbackCmd = &cobra.Command{
+	Use:   "db:rollback",
+	Short: "Roll back database migrations",
+	Long: `Revert the last batch of migrations, or the last N migrations with --step.
+
+Outside production the database name has to be typed to confirm; with
+ENVIRONMENT=production the command is refused unless --force is given.
+ENVIRONMENT is read from the shell, then from the project .env.
+
+Examples:
+  loom db:rollback
+  loom db:rollback --step=2 --snapshot`,
+	RunE: runDBRollback,
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "db:status",
+	Short: "Show the status of each migration",
+	Long:  `List the migrations, whether they have been applied and in which batch.`,
+	RunE:  runDBStatus,
+}
+
+var dbResetCmd = &cobra.Command{
+	Use:   "db:reset",
+	Short: "Roll back all database migrations",
+	Long: `Revert every applied migration, newest first.
+
+⚠️  WARNING: This is destructive! The data of the migrated tables is lost.
+The database name has to be typed to confirm; with ENVIRONMENT=production
+the command is refused unless --force is given (ENVIRONMENT is read from
+the shell, then from the project .env). --snapshot backs the database up
+into backups/ first.`,
+	RunE: runDBReset,
 }
 
 var dbFreshCmd = &cobra.Command{
 	Use:   "db:fresh",
 	Short: "Drop all tables and re-run migrations",
-	Long: `Drop all tables registered in models_all.go and re-run migrations.
-	
+	Long: `Drop all tables of the database and re-run every migration.
+
 ⚠️  WARNING: This is destructive! All data will be lost.
-Use only in development environments.`,
+The database name has to be typed to confirm; with ENVIRONMENT=production
+the command is refused unless --force is given (ENVIRONMENT is read from
+the shell, then from the project .env). --snapshot backs the database up
+into backups/ first (pg_dump, mysqldump or a copy of the
+SQLite file).`,
 	RunE: runDBFresh,
 }
 
 var dbSeedCmd = &cobra.Command{
 	Use:   "db:seed",
 	Short: "Run database seeders",
-	Long:  `Execute all seeders registered in seeders_all.go.`,
-	RunE:  runDBSeed,
+	Long: `Execute the seeders registered in seeders_all.go, each after the
+seeders it depends on.
+
+--class runs some seeders and --tag the seeders of a group, with their
+dependencies. Seeders restricted to other environments (ENVIRONMENT) are
+skipped, and --once skips the seeders that have already run, as recorded
+in the seeder_runs table.
+
+Examples:
+  loom db:seed
+  loom db:seed --class=ProductSeeder
+  loom db:seed --tag=demo --once`,
+	RunE: runDBSeed,
 }
 
 var (
 	seedAfterFresh   bool
 	seedAfterMigrate bool
+	rollbackSteps    int
+	forceDestructive bool
+	snapshotFirst    bool
+	seedClasses      []string
+	seedTags         []string
+	seedOnce         bool
 )
 
 func init() {
 	// Add commands directly to root (loom db:migrate, loom db:fresh, loom db:seed)
 	rootCmd.AddCommand(dbMigrateCmd)
+	rootCmd.AddCommand(dbRollbackCmd)
+	rootCmd.AddCommand(dbStatusCmd)
+	rootCmd.AddCommand(dbResetCmd)
 	rootCmd.AddCommand(dbFreshCmd)
 	rootCmd.AddCommand(dbSeedCmd)
 
 	// Add --seed flag to migrate and fresh
 	dbMigrateCmd.Flags().BoolVar(&seedAfterMigrate, "seed", false, "Run seeders after migration")
 	dbFreshCmd.Flags().BoolVar(&seedAfterFresh, "seed", false, "Run seeders after fresh migration")
+
+	dbRollbackCmd.Flags().IntVar(&rollbackSteps, "step", 0, "Number of migrations to roll back (default: the last batch)")
+
+	dbSeedCmd.Flags().StringSliceVar(&seedClasses, "class", nil, "Run only these seeders (e.g. ProductSeeder)")
+	dbSeedCmd.Flags().StringSliceVar(&seedTags, "tag", nil, "Run only the seeders with these tags (e.g. demo)")
+	dbSeedCmd.Flags().BoolVar(&seedOnce, "once", false, "Skip the seeders that have already run")
+
+	// Guards of the commands losing data
+	for _, cmd := range []*cobra.Command{dbRollbackCmd, dbResetCmd, dbFreshCmd} {
+		cmd.Flags().BoolVar(&forceDestructive, "force", false, "Skip the confirmation (required when ENVIRONMENT=production)")
+		cmd.Flags().BoolVar(&snapshotFirst, "snapshot", false, "Back the database up into backups/ first")
+	}
 }
 
 func runDBMigrate(cmd *cobra.Command, args []string) error {
-	return executeConsoleCommand("migrate", seedAfterMigrate, false)
+	return executeConsoleCommand("migrate", seedFlags(seedAfterMigrate)...)
+}
+
+func runDBRollback(cmd *cobra.Command, args []string) error {
+	if rollbackSteps < 0 {
+		return fmt.Errorf("--step must be positive")
+	}
+	if err := refuseInProduction(cmd.Name()); err != nil {
+		return err
+	}
+	flags := guardFlags()
+	if rollbackSteps > 0 {
+		flags = append(flags, fmt.Sprintf("--step=%d", rollbackSteps))
+	}
+	return executeConsoleCommand("rollback", flags...)
+}
+
+func runDBStatus(cmd *cobra.Command, args []string) error {
+	return executeConsoleCommand("status")
+}
+
+func runDBReset(cmd *cobra.Command, args []string) error {
+	if err := refuseInProduction(cmd.Name()); err != nil {
+		return err
+	}
+	return executeConsoleCommand("reset", guardFlags()...)
 }
 
 func runDBFresh(cmd *cobra.Command, args []string) error {
-	return executeConsoleCommand("migrate", seedAfterFresh, true)
+	if err := refuseInProduction(cmd.Name()); err != nil {
+		return err
+	}
+	flags := append([]string{"--fresh"}, guardFlags()...)
+	return executeConsoleCommand("migrate", append(flags, seedFlags(seedAfterFresh)...)...)
 }
 
 func runDBSeed(cmd *cobra.Command, args []string) error {
-	return executeConsoleCommand("seed", false, false)
+	var flags []string
+	for _, class := range seedClasses {
+		flags = append(flags, "--class="+class)
+	}
+	for _, tag := range seedTags {
+		flags = append(flags, "--tag="+tag)
+	}
+	if seedOnce {
+		flags = append(flags, "--once")
+	}
+	return executeConsoleCommand("seed", flags...)
+}
+
+// seedFlags returns the console flag running the seeders, when asked for
+func seedFlags(seed bool) []string {
+	if seed {
+		return []string{"--seed"}
+	}
+	return nil
 }
 
-func executeConsoleCommand(command string, withSeed bool, fresh bool) error {
+// guardFlags returns the console flags of --force and --snapshot
+func guardFlags() []string {
+	var flags []string
+	if forceDestructive {
+		flags = append(flags, "--force")
+	}
+	if snapshotFirst {
+		flags = append(flags, "--snapshot")
+	}
+	return flags
+}
+
+// refuseInProduction stops a command losing data (db:rollback, db:reset,
+// db:fresh) with ENVIRONMENT=production unless --force is given. The
+// console asks for confirmation too; this refuses before it is built.
+func refuseInProduction(command string) error {
+	root := ""
+	if projectInfo, err := generator.DetectProject(); err == nil {
+		root = projectInfo.RootPath
+	}
+	if projectEnvironment(root) == "production" && !forceDestructive {
+		return fmt.Errorf("❌ Refusing to run 'loom %s' with ENVIRONMENT=production: rerun with --force", command)
+	}
+	return nil
+}
+
+// projectEnvironment returns the ENVIRONMENT of the project in root. The
+// shell variable wins, as in config.Load; without it the project .env is
+// read, since the generated config does not load it but that is where the
+// project README tells to set it.
+func projectEnvironment(root string) string {
+	if env := os.Getenv("ENVIRONMENT"); env != "" {
+		return env
+	}
+	if root == "" {
+		return ""
+	}
+	content, err := os.ReadFile(filepath.Join(root, ".env"))
+	if err != nil {
+		return ""
+	}
+	env := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) == "ENVIRONMENT" {
+			env = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return env
+}
+
+// executeConsoleCommand runs a command of the project console
+// (cmd/console/main.go) with its flags
+func executeConsoleCommand(command string, flags ...string) error {
 	// Detect project
 	projectInfo, err := generator.DetectProject()
 	if err != nil {
@@ -79,15 +255,7 @@ Run 'loom add orm gorm' first to generate the database structure.`)
 	}
 
 	// Build command arguments
-	cmdArgs := []string{"run", "cmd/console/main.go", command}
-
-	if fresh {
-		cmdArgs = append(cmdArgs, "--fresh")
-	}
-
-	if withSeed {
-		cmdArgs = append(cmdArgs, "--seed")
-	}
+	cmdArgs := append([]string{"run", "cmd/console/main.go", command}, flags...)
 
 	// Execute go run cmd/console/main.go <command>
 	fmt.Printf("🚀 Running: go %s\n\n", joinArgs(cmdArgs))
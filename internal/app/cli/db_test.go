@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRefuseInProduction(t *testing.T) {
+	defer func() { forceDestructive = false }()
+
+	tests := []struct {
+		environment string
+		force       bool
+		refused     bool
+	}{
+		{environment: "production", refused: true},
+		{environment: "production", force: true},
+		{environment: "development"},
+		{environment: ""},
+	}
+	for _, tt := range tests {
+		t.Setenv("ENVIRONMENT", tt.environment)
+		forceDestructive = tt.force
+
+		for _, cmd := range []string{dbRollbackCmd.Name(), dbResetCmd.Name(), dbFreshCmd.Name()} {
+			err := refuseInProduction(cmd)
+			if (err != nil) != tt.refused {
+				t.Errorf("%s with ENVIRONMENT=%q, force %v: error = %v, want refused %v", cmd, tt.environment, tt.force, err, tt.refused)
+			}
+			if err != nil && !strings.Contains(err.Error(), "'loom "+cmd+"'") {
+				t.Errorf("refusal does not name 'loom %s': %v", cmd, err)
+			}
+		}
+	}
+}
+
+// TestProjectEnvironment checks that the project .env is read when the
+// shell does not set ENVIRONMENT, and that the shell wins otherwise
+func TestProjectEnvironment(t *testing.T) {
+	root := t.TempDir()
+	env := "# settings\nPORT=8080\nexport ENVIRONMENT=\"production\"\n"
+	if err := os.WriteFile(filepath.Join(root, ".env"), []byte(env), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ENVIRONMENT", "")
+	if got := projectEnvironment(root); got != "production" {
+		t.Errorf("projectEnvironment with .env = %q, want production", got)
+	}
+	if got := projectEnvironment(t.TempDir()); got != "" {
+		t.Errorf("projectEnvironment without .env = %q, want empty", got)
+	}
+	t.Setenv("ENVIRONMENT", "development")
+	if got := projectEnvironment(root); got != "development" {
+		t.Errorf("projectEnvironment with the shell variable = %q, want development", got)
+	}
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/geomark27/loom-go/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var destroyCmd = &cobra.Command{
+	Use:   "destroy [kind] [name]",
+	Short: "Remove a generated module, model, seeder or migration",
+	Long: `Remove what 'loom generate' or 'loom make' created for a component,
+the inverse of those commands.
+
+Kinds:
+  module      the module files, its wiring in server.go (and routes.go in
+              layered projects) and its section of docs/API.md
+  model       the model file and its entry in models_all.go
+  seeder      the seeder file and its entry in seeders_all.go
+  migration   the files of a migration, named as 'loom make migration'
+              prints it (create_products_table); refused while the
+              migration is applied, since it could not be rolled back
+  handler, service, middleware and pack components are removed too
+
+Only the files recorded in .loom/generated.json are removed, and only
+when they are unchanged since they were generated: edited files are
+kept unless --force is set. --force also deletes an applied migration.
+Whatever could not be undone (edited files, wiring changed by hand) is
+listed at the end to be removed by hand.
+
+Examples:
+  loom destroy module products
+  loom destroy model Category
+  loom destroy seeder Category
+  loom destroy migration create_products_table
+  loom destroy module products --dry-run
+  loom destroy module products --force`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDestroy,
+}
+
+func init() {
+	rootCmd.AddCommand(destroyCmd)
+
+	destroyCmd.Flags().Bool("force", false, "Delete files edited since they were generated")
+	destroyCmd.Flags().Bool("dry-run", false, "Show what would be removed without changing files")
+	addVerifyFlags(destroyCmd.Flags())
+}
+
+func runDestroy(cmd *cobra.Command, args []string) error {
+	kind, name := strings.ToLower(args[0]), args[1]
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	projectInfo, err := detectProject()
+	if err != nil {
+		return err
+	}
+
+	if err := generator.ValidateComponentName(name); err != nil {
+		return fmt.Errorf("invalid name: %w", err)
+	}
+
+	// Migrations are tracked under the name 'loom make migration' prints
+	if kind == "migration" {
+		name = migrationName(name)
+		if !force && !dryRun {
+			if err := refuseAppliedMigration(projectInfo.RootPath, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("🔍 Project detected: %s (%s)\n", projectInfo.Name, projectInfo.Architecture)
+	fmt.Printf("🗑️  Destroying %s: %s\n\n", kind, name)
+
+	gen := generator.NewModuleGenerator(projectInfo)
+	changes, err := gen.Destroy(kind, name, force)
+	if err != nil {
+		return fmt.Errorf("error destroying %s %s: %w", kind, name, err)
+	}
+
+	counts := make(map[generator.ChangeKind]int)
+	var leftover []generator.FileChange
+	for _, change := range changes {
+		counts[change.Kind]++
+		if change.Kind == generator.ChangeSkipped {
+			leftover = append(leftover, change)
+			continue
+		}
+		line := fmt.Sprintf("   %s %-7s %s", changeIcon(change.Kind), change.Kind, change.Path)
+		if change.Reason != "" {
+			line += " (" + change.Reason + ")"
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Printf("\n📊 %d deleted, %d updated, %d left behind\n",
+		counts[generator.ChangeDelete], counts[generator.ChangeUpdate], len(leftover))
+	if len(leftover) > 0 {
+		fmt.Println("\n⏭️  Left behind:")
+		for _, change := range leftover {
+			fmt.Printf("   %s (%s)\n", change.Path, change.Reason)
+		}
+	}
+
+	fmt.Println()
+	if err := applyChanges(gen, dryRun); err != nil {
+		return fmt.Errorf("error destroying %s %s: %w", kind, name, err)
+	}
+	if dryRun {
+		fmt.Println("\n💡 Run without --dry-run to remove the files")
+		return nil
+	}
+
+	if counts[generator.ChangeDelete] == 0 && counts[generator.ChangeUpdate] == 0 {
+		fmt.Println("⚠️  Nothing was removed")
+		return nil
+	}
+	fmt.Printf("✅ %s %s destroyed\n", capitalizeFirst(kind), name)
+	_, err = verifyProject(cmd, projectInfo.RootPath, written)
+	return err
+}
+
+// refuseAppliedMigration stops 'loom destroy migration' from deleting a
+// migration the database has applied: 'loom db:rollback' could no longer
+// revert it. The status comes from the project console.
+func refuseAppliedMigration(root, name string) error {
+	st, err := state.Load(root)
+	if err != nil {
+		return err
+	}
+	paths := st.Paths("migration:" + name)
+	if len(paths) == 0 {
+		// Destroy reports that nothing is tracked
+		return nil
+	}
+
+	fmt.Printf("🔎 Checking the status of migration %s...\n", name)
+	statusCmd := exec.Command("go", "run", "cmd/console/main.go", "status")
+	statusCmd.Dir = root
+	output, err := statusCmd.Output()
+	if err != nil {
+		return fmt.Errorf("❌ Could not check whether migration %s is applied (%v): rerun with --force to delete it anyway", name, err)
+	}
+
+	if applied := appliedMigrations(output, paths); len(applied) > 0 {
+		return fmt.Errorf("❌ Migration %s is applied: run 'loom db:rollback' first, or rerun with --force to delete it anyway", strings.Join(applied, ", "))
+	}
+	return nil
+}
+
+// appliedMigrations returns the migrations among the files at paths that
+// the output of the console status command lists as applied ("Ran")
+func appliedMigrations(status []byte, paths []string) []string {
+	files := make(map[string]bool)
+	for _, p := range paths {
+		base := path.Base(p)
+		for _, ext := range []string{".up.sql", ".down.sql", ".go"} {
+			base = strings.TrimSuffix(base, ext)
+		}
+		files[base] = true
+	}
+
+	var applied []string
+	for _, line := range strings.Split(string(status), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && files[fields[0]] && fields[1] == "Ran" {
+			applied = append(applied, fields[0])
+		}
+	}
+	return applied
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAppliedMigrations(t *testing.T) {
+	status := []byte(`MIGRATION                             STATUS   BATCH
+00000000000000_create_users_table     Ran      1
+20261017081552_add_stock_to_products  Ran      2
+20261017090000_add_price_to_products  Pending  -
+`)
+	tests := []struct {
+		paths []string
+		want  []string
+	}{
+		{paths: []string{"internal/database/migrations/20261017081552_add_stock_to_products.go"}, want: []string{"20261017081552_add_stock_to_products"}},
+		{paths: []string{"internal/database/migrations/20261017090000_add_price_to_products.go"}},
+		{paths: []string{
+			"database/migrations/20261017081552_add_stock_to_products.up.sql",
+			"database/migrations/20261017081552_add_stock_to_products.down.sql",
+		}, want: []string{"20261017081552_add_stock_to_products"}},
+	}
+	for _, tt := range tests {
+		if got := appliedMigrations(status, tt.paths); !slices.Equal(got, tt.want) {
+			t.Errorf("appliedMigrations(%v) = %v, want %v", tt.paths, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"fmt"
+
+	"github.com/geomark27/loom-go/internal/addon"
+	"github.com/geomark27/loom-go/internal/generator"
 	"github.com/spf13/cobra"
 )
 
@@ -18,8 +22,24 @@ Examples:
   loom generate handler orders
   loom generate service email
   loom generate model Category
-  loom generate middleware auth`,
+  loom generate middleware auth
+
+After generating, the project is gofmt'd and checked with go mod tidy,
+go build and go vet (parse and type-check only with --offline). Use
+--verify=false to skip the check.`,
 	Aliases: []string{"gen", "g"},
+	// Every subcommand ends with the verification phase
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			return nil
+		}
+		projectInfo, err := generator.DetectProject()
+		if err != nil {
+			return nil
+		}
+		_, err = verifyProject(cmd, projectInfo.RootPath, written)
+		return err
+	},
 }
 
 func init() {
@@ -28,4 +48,20 @@ func init() {
 	// Global flags for all generate subcommands
 	generateCmd.PersistentFlags().Bool("force", false, "Overwrite existing files")
 	generateCmd.PersistentFlags().Bool("dry-run", false, "Show what would be generated without creating files")
+	addVerifyFlags(generateCmd.PersistentFlags())
+}
+
+// detectProject detects the current project, the router the generated
+// handlers, routes and middleware must compile against and its ORM
+func detectProject() (*generator.ProjectInfo, error) {
+	projectInfo, err := generator.DetectProject()
+	if err != nil {
+		return nil, fmt.Errorf("error: no valid Loom project detected. %w", err)
+	}
+
+	detector := addon.NewProjectDetector(projectInfo.RootPath)
+	projectInfo.Router = detector.DetectRouter()
+	projectInfo.ORM = detector.DetectORM()
+
+	return projectInfo, nil
 }
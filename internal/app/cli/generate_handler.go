@@ -33,9 +33,9 @@ func runGenerateHandler(cmd *cobra.Command, args []string) error {
 	force, _ := cmd.Flags().GetBool("force")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-	projectInfo, err := generator.DetectProject()
+	projectInfo, err := detectProject()
 	if err != nil {
-		return fmt.Errorf("error: no valid Loom project detected. %w", err)
+		return err
 	}
 
 	if err := generator.ValidateComponentName(name); err != nil {
@@ -46,25 +46,26 @@ func runGenerateHandler(cmd *cobra.Command, args []string) error {
 	fmt.Printf("📦 Generating handler: %s\n\n", name)
 
 	gen := generator.NewModuleGenerator(projectInfo)
-	files, err := gen.GenerateHandler(name, force, dryRun)
+	files, err := gen.GenerateHandler(name, force)
 	if err != nil {
 		return fmt.Errorf("error generating handler: %w", err)
 	}
 
+	skipped, err := applyGenerated(gen, force, dryRun)
+	if err != nil {
+		return fmt.Errorf("error generating handler: %w", err)
+	}
 	if dryRun {
-		fmt.Println("📋 File that would be generated:")
-		for _, file := range files {
-			fmt.Printf("   ✨ %s\n", file)
-		}
 		fmt.Println("\n💡 Run without --dry-run to create the file")
 		return nil
 	}
 
 	fmt.Println("✅ Handler generated successfully!")
 	fmt.Println("\n📝 File created:")
-	for _, file := range files {
+	for _, file := range unskipped(files, skipped) {
 		fmt.Printf("   ✨ %s\n", file)
 	}
+	printConflicts(gen.Conflicts())
 
 	return nil
 }
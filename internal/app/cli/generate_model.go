@@ -8,7 +8,7 @@ import (
 )
 
 var generateModelCmd = &cobra.Command{
-	Use:   "model [name]",
+	Use:   "model [name] [field:type...]",
 	Short: "Generate a data model",
 	Long: `Generate a model file with the data structure.
 
@@ -16,11 +16,14 @@ The file will be generated in the appropriate location according to the architec
   - Layered: internal/app/models/{name}.go
   - Modular: internal/modules/{name}/model.go
 
+Fields use the same syntax as 'loom generate module'.
+
 Examples:
   loom generate model Product
+  loom generate model Product name:string price:float64
   loom generate model User --force`,
 	Aliases: []string{"mod"},
-	Args:    cobra.ExactArgs(1),
+	Args:    cobra.MinimumNArgs(1),
 	RunE:    runGenerateModel,
 }
 
@@ -33,38 +36,44 @@ func runGenerateModel(cmd *cobra.Command, args []string) error {
 	force, _ := cmd.Flags().GetBool("force")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-	projectInfo, err := generator.DetectProject()
+	projectInfo, err := detectProject()
 	if err != nil {
-		return fmt.Errorf("error: no valid Loom project detected. %w", err)
+		return err
 	}
 
 	if err := generator.ValidateComponentName(name); err != nil {
 		return fmt.Errorf("invalid model name: %w", err)
 	}
 
+	fields, err := generator.ParseFields(args[1:])
+	if err != nil {
+		return fmt.Errorf("invalid fields: %w", err)
+	}
+
 	fmt.Printf("🔍 Project: %s (%s)\n", projectInfo.Name, projectInfo.Architecture)
 	fmt.Printf("📦 Generating model: %s\n\n", name)
 
 	gen := generator.NewModuleGenerator(projectInfo)
-	files, err := gen.GenerateModel(name, force, dryRun)
+	files, err := gen.GenerateModel(name, fields, force)
 	if err != nil {
 		return fmt.Errorf("error generating model: %w", err)
 	}
 
+	skipped, err := applyGenerated(gen, force, dryRun)
+	if err != nil {
+		return fmt.Errorf("error generating model: %w", err)
+	}
 	if dryRun {
-		fmt.Println("📋 File that would be generated:")
-		for _, file := range files {
-			fmt.Printf("   ✨ %s\n", file)
-		}
 		fmt.Println("\n💡 Run without --dry-run to create the file")
 		return nil
 	}
 
 	fmt.Println("✅ Model generated successfully!")
 	fmt.Println("\n📝 File created:")
-	for _, file := range files {
+	for _, file := range unskipped(files, skipped) {
 		fmt.Printf("   ✨ %s\n", file)
 	}
+	printConflicts(gen.Conflicts())
 
 	return nil
 }
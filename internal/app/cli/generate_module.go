@@ -2,13 +2,14 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/geomark27/loom-go/internal/generator"
 	"github.com/spf13/cobra"
 )
 
 var generateModuleCmd = &cobra.Command{
-	Use:   "module [name]",
+	Use:   "module [name] [field:type[:modifier]...]",
 	Short: "Generates a complete module (handler, service, repository, model, DTO)",
 	Long: `Generates a complete module with all its layers in the current project.
 
@@ -32,28 +33,69 @@ For Modular architecture, it generates:
   - internal/modules/{name}/validator.go
   - internal/modules/{name}/errors.go
 
+Fields are declared as name:type with optional modifiers:
+  Types:      string, text, int, int64, uint, float32, float64, bool, time, date
+  Modifiers:  unique, nullable, index, default=<value>, size=<n>
+
+Fields flow into the model (GORM tags), the Create/Update DTOs
+(validate tags), repository lookups for unique fields and docs/API.md.
+Without fields a single "name:string" field is generated.
+
+Relations link the module to other modules:
+  --belongs-to=user      user_id foreign key, User association and
+                         GET /users/{id}/<names> listing the user's items
+  --has-many=orders      Orders association (orders declare --belongs-to)
+  --many-to-many=tags    Tags association, tag_ids in the DTOs and a join
+                         model registered in models_all.go (when GORM is installed)
+Modular modules do not share models, so they only support --belongs-to.
+
+Handlers, routes and middleware target the router found in go.mod
+(gin, chi, echo or gorilla/mux); other projects get net/http handlers.
+
+The module is wired into the server automatically: layered projects get
+the repository, service and handler constructed in server.go and a route
+group in routes.go, served under the plural name (/order_items for
+order_item); modular projects get NewModule(eventBus) and
+RegisterRoutes(api) in server.go. Use --no-wire to print the code instead.
+
+Repositories keep the items in memory, unless GORM is installed: then
+they query the database through the connection of internal/database
+(database.GetDB() is passed to them in server.go).
+
 Examples:
   loom generate module products
-  loom generate module users --force
-  loom generate module orders --dry-run`,
+  loom generate module products name:string price:float64 stock:int
+  loom generate module customers email:string:unique bio:text:nullable
+  loom generate module plans name:string active:bool:default=true
+  loom generate module orders total:float64 --belongs-to=user
+  loom generate module products name:string --many-to-many=tags
+  loom generate module products --force
+  loom generate module orders --dry-run
+  loom generate module payments --no-wire`,
 	Aliases: []string{"mod", "m"},
-	Args:    cobra.ExactArgs(1),
+	Args:    cobra.MinimumNArgs(1),
 	RunE:    runGenerateModule,
 }
 
 func init() {
 	generateCmd.AddCommand(generateModuleCmd)
+
+	generateModuleCmd.Flags().StringSlice("belongs-to", nil, "Parent modules (adds <module>_id and a nested route)")
+	generateModuleCmd.Flags().StringSlice("has-many", nil, "Child modules (adds a slice association)")
+	generateModuleCmd.Flags().StringSlice("many-to-many", nil, "Related modules (adds the association and a join model)")
+	generateModuleCmd.Flags().Bool("no-wire", false, "Do not register the module in the server")
 }
 
 func runGenerateModule(cmd *cobra.Command, args []string) error {
 	moduleName := args[0]
 	force, _ := cmd.Flags().GetBool("force")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	noWire, _ := cmd.Flags().GetBool("no-wire")
 
 	// Detect the current project (without arguments)
-	projectInfo, err := generator.DetectProject()
+	projectInfo, err := detectProject()
 	if err != nil {
-		return fmt.Errorf("error: no valid Loom project detected. %w", err)
+		return err
 	}
 
 	// Validate the module name
@@ -61,61 +103,108 @@ func runGenerateModule(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid module name: %w", err)
 	}
 
+	// Parse the field declarations (name:type[:modifier...])
+	fields, err := generator.ParseFields(args[1:])
+	if err != nil {
+		return fmt.Errorf("invalid fields: %w", err)
+	}
+
+	relations, err := parseRelationFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("🔍 Project detected: %s\n", projectInfo.Name)
 	fmt.Printf("📐 Architecture: %s\n", projectInfo.Architecture)
-	fmt.Printf("📦 Generating module: %s\n\n", moduleName)
+	fmt.Printf("🌐 Router: %s\n", generator.TargetRouter(projectInfo.Router))
+	fmt.Printf("📦 Generating module: %s\n", moduleName)
+	if len(fields) > 0 {
+		names := make([]string, 0, len(fields))
+		for _, f := range fields {
+			names = append(names, f.Name+":"+f.Type)
+		}
+		fmt.Printf("🧩 Fields: %s\n", strings.Join(names, ", "))
+	}
+	if len(relations) > 0 {
+		names := make([]string, 0, len(relations))
+		for _, r := range relations {
+			names = append(names, r.Kind+" "+r.Entity)
+		}
+		fmt.Printf("🔗 Relations: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Println()
 
 	// Create the generator
 	gen := generator.NewModuleGenerator(projectInfo)
 
+	if unsupported := gen.UnsupportedRelations(relations); len(unsupported) > 0 {
+		return fmt.Errorf("%s relation to %s is not supported in modular projects (modules only reference each other with --belongs-to)",
+			unsupported[0].Kind, unsupported[0].Entity)
+	}
+
 	// Generate the module (returns the list of files)
-	files, err := gen.GenerateModule(moduleName, force, dryRun)
+	files, err := gen.GenerateModule(moduleName, fields, relations, force)
 	if err != nil {
 		return fmt.Errorf("error generating module: %w", err)
 	}
 
-	// Dry-run mode
-	if dryRun {
-		fmt.Println("📋 Files that would be generated:")
-		for _, file := range files {
-			fmt.Printf("   ✨ %s\n", file)
+	// Wire it into the server, staged with the module files
+	var patched []string
+	wired := false
+	if !noWire {
+		patched, err = gen.WireModule(moduleName, relations)
+		if err != nil {
+			fmt.Printf("⚠️  Could not wire the module automatically: %v\n\n", err)
+		} else {
+			wired = true
 		}
+	}
+
+	skipped, err := applyGenerated(gen, force, dryRun)
+	if err != nil {
+		return fmt.Errorf("error generating module: %w", err)
+	}
+	if dryRun {
 		fmt.Println("\n💡 Run without --dry-run to create the files")
 		return nil
 	}
 
 	fmt.Println("✅ Module generated successfully!")
 	fmt.Println("\n📝 Files created:")
-	for _, file := range files {
+	for _, file := range unskipped(files, skipped) {
 		fmt.Printf("   ✨ %s\n", file)
 	}
+	printConflicts(gen.Conflicts())
+	if wired {
+		printWiring(patched)
+	}
 
 	fmt.Println("\n📝 Next steps:")
-
-	if projectInfo.Architecture == "modular" {
-		fmt.Printf("   1. Register the router in cmd/loom/main.go:\n")
-		fmt.Printf("      %sRouter := %s.NewRouter()\n", moduleName, moduleName)
-		fmt.Printf("      router.PathPrefix(\"/%s\").Handler(%sRouter)\n\n", moduleName, moduleName)
-	} else {
-		fmt.Printf("   1. Register the routes in cmd/loom/main.go:\n")
-		fmt.Printf("      %sHandler := handlers.New%sHandler()\n", moduleName, toPascalCase(moduleName))
-		fmt.Printf("      router.HandleFunc(\"/%s\", %sHandler.Create).Methods(\"POST\")\n\n", moduleName, moduleName)
+	step := 1
+	if !wired {
+		fmt.Printf("   %d. Register the module in the server:\n", step)
+		for _, line := range strings.Split(strings.TrimRight(gen.ManualWiring(moduleName, relations), "\n"), "\n") {
+			fmt.Printf("      %s\n", line)
+		}
+		fmt.Println()
+		step++
 	}
 
-	fmt.Println("   2. Run: go mod tidy")
-	fmt.Println("   3. Implement the business logic in the generated files")
+	fmt.Printf("   %d. Run: go mod tidy\n", step)
+	fmt.Printf("   %d. Implement the business logic in the generated files\n", step+1)
 
 	return nil
 }
 
-// toPascalCase converts a string to PascalCase
-func toPascalCase(s string) string {
-	if len(s) == 0 {
-		return s
+// printWiring reports the server files patched to wire a module
+func printWiring(patched []string) {
+	if len(patched) == 0 {
+		fmt.Println("\n🔌 Module already wired into the server")
+		return
 	}
-	// Convert first letter to uppercase
-	if s[0] >= 'a' && s[0] <= 'z' {
-		return string(s[0]-32) + s[1:]
+
+	fmt.Println("\n🔌 Module wired into the server:")
+	for _, file := range patched {
+		fmt.Printf("   📝 %s\n", file)
 	}
-	return s
 }
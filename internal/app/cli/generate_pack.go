@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+// registerPackKinds adds a "loom generate <kind>" subcommand for every
+// component kind declared by the installed template packs. Kinds named
+// like a built-in generator (or a kind of another pack) are skipped.
+func registerPackKinds() {
+	packs, errs := generator.LoadPacks()
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+
+	for _, pack := range packs {
+		for i := range pack.Kinds {
+			kind := pack.Kinds[i]
+			if existing, _, err := generateCmd.Find([]string{kind.Name}); err == nil && existing != generateCmd {
+				fmt.Fprintf(os.Stderr, "⚠️  pack %s: kind %q conflicts with an existing command, skipped\n", pack.Name, kind.Name)
+				continue
+			}
+			generateCmd.AddCommand(packKindCommand(kind))
+		}
+	}
+}
+
+// packKindCommand builds the cobra command of a pack kind
+func packKindCommand(kind generator.PackKind) *cobra.Command {
+	short := kind.Short
+	if short == "" {
+		short = fmt.Sprintf("Generates a %s (pack %s)", kind.Name, kind.Pack.Name)
+	}
+
+	cmd := &cobra.Command{
+		Use:     kind.Name + " [name]",
+		Short:   short,
+		Long:    kind.Long,
+		Aliases: kind.Aliases,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGeneratePackKind(cmd, kind, args[0])
+		},
+	}
+
+	for _, flag := range kind.Flags {
+		cmd.Flags().String(flag.Name, flag.Default, flag.Description)
+		if flag.Required {
+			cmd.MarkFlagRequired(flag.Name)
+		}
+	}
+
+	return cmd
+}
+
+func runGeneratePackKind(cmd *cobra.Command, kind generator.PackKind, name string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	projectInfo, err := detectProject()
+	if err != nil {
+		return err
+	}
+
+	if err := generator.ValidateComponentName(name); err != nil {
+		return fmt.Errorf("invalid %s name: %w", kind.Name, err)
+	}
+
+	flags := make(map[string]string, len(kind.Flags))
+	for _, flag := range kind.Flags {
+		flags[flag.Name], _ = cmd.Flags().GetString(flag.Name)
+	}
+
+	fmt.Printf("🔍 Project: %s (%s)\n", projectInfo.Name, projectInfo.Architecture)
+	fmt.Printf("📦 Generating %s: %s (pack %s)\n\n", kind.Name, name, kind.Pack.Name)
+
+	gen := generator.NewModuleGenerator(projectInfo)
+	files, err := gen.GenerateKind(kind, name, flags, force)
+	if err != nil {
+		return fmt.Errorf("error generating %s: %w", kind.Name, err)
+	}
+
+	skipped, err := applyGenerated(gen, force, dryRun)
+	if err != nil {
+		return fmt.Errorf("error generating %s: %w", kind.Name, err)
+	}
+	if dryRun {
+		fmt.Println("\n💡 Run without --dry-run to create the files")
+		return nil
+	}
+
+	fmt.Printf("✅ %s generated successfully!\n", strings.Title(kind.Name))
+	fmt.Println("\n📝 Files created or updated:")
+	for _, file := range unskipped(files, skipped) {
+		fmt.Printf("   ✨ %s\n", file)
+	}
+	printConflicts(gen.Conflicts())
+
+	return nil
+}
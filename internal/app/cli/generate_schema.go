@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+var generateSchemaCmd = &cobra.Command{
+	Use:   "from-schema [file]",
+	Short: "Generates every module declared in a YAML/JSON schema file",
+	Long: `Generates one module per entity declared in a schema file, using the
+project's architecture (Layered or Modular).
+
+The schema declares entities with their fields, relations and indexes:
+
+  entities:
+    - name: category
+      fields:
+        - name:string:unique
+    - name: product
+      fields:
+        - name:string
+        - price:float64:default=0
+        - { name: sku, type: string, unique: true, size: 64 }
+      relations:
+        - { type: belongs_to, entity: category }
+      indexes:
+        - { fields: [name, price] }
+
+Fields use the same name:type[:modifier...] syntax as "generate module"
+or an object with name, type, unique, nullable, index, default and size.
+Relations are belongs_to, has_many or many_to_many, with the same
+effect as the --belongs-to, --has-many and --many-to-many flags of
+"generate module".
+
+The command can be re-run after editing the schema. Files still identical
+to what Loom generated are rewritten, new files are created and files
+edited by hand are merged with the new version: regions changed on both
+sides are left between conflict markers. Edited files generated before
+Loom kept their original content are skipped (use --force to overwrite
+them). Use --dry-run to review the changes first.
+
+Each module is also wired into the server (see "generate module"); use
+--no-wire to skip it.
+
+Examples:
+  loom generate from-schema entities.yaml
+  loom generate from-schema entities.json --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGenerateSchema,
+}
+
+func init() {
+	generateCmd.AddCommand(generateSchemaCmd)
+
+	generateSchemaCmd.Flags().Bool("no-wire", false, "Do not register the modules in the server")
+}
+
+func runGenerateSchema(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	noWire, _ := cmd.Flags().GetBool("no-wire")
+
+	// Detect the current project
+	projectInfo, err := detectProject()
+	if err != nil {
+		return err
+	}
+
+	schema, err := generator.LoadSchema(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔍 Project detected: %s\n", projectInfo.Name)
+	fmt.Printf("📐 Architecture: %s\n", projectInfo.Architecture)
+	fmt.Printf("📄 Schema: %s (%d entities)\n", args[0], len(schema.Entities))
+	if dryRun {
+		fmt.Println("📋 Dry run: no files will be written")
+	}
+	fmt.Println()
+
+	gen := generator.NewModuleGenerator(projectInfo)
+	counts := make(map[generator.ChangeKind]int)
+
+	for _, entity := range schema.Entities {
+		fields, err := entity.ResolveFields()
+		if err != nil {
+			return fmt.Errorf("entity %s: %w", entity.Name, err)
+		}
+
+		relations := entity.ResolveRelations()
+		for _, relation := range gen.UnsupportedRelations(relations) {
+			fmt.Printf("⚠️  %s: %s relation to %s is not supported by %s modules (skipped)\n",
+				entity.Name, relation.Kind, relation.Entity, projectInfo.Architecture)
+		}
+
+		changes, err := gen.SyncModule(entity.Name, fields, relations, force)
+		if err != nil {
+			return fmt.Errorf("error generating %s: %w", entity.Name, err)
+		}
+
+		fmt.Printf("📦 %s\n", entity.Name)
+		unchanged := 0
+		for _, change := range changes {
+			counts[change.Kind]++
+			if change.Kind == generator.ChangeUnchanged {
+				unchanged++
+				continue
+			}
+			line := fmt.Sprintf("   %s %-9s %s", changeIcon(change.Kind), change.Kind, change.Path)
+			if change.Reason != "" {
+				line += " (" + change.Reason + ")"
+			}
+			fmt.Println(line)
+		}
+
+		wired := false
+		if !noWire {
+			patched, err := gen.WireModule(entity.Name, relations)
+			if err != nil {
+				fmt.Printf("   ⚠️  not wired: %v\n", err)
+			}
+			for _, file := range patched {
+				fmt.Printf("   🔌 %-9s %s\n", "wired", file)
+				wired = true
+			}
+		}
+
+		if unchanged == len(changes) && !wired {
+			fmt.Println("   ✓ up to date")
+		}
+	}
+
+	fmt.Printf("\n📊 %d created, %d updated, %d merged, %d conflicted, %d unchanged, %d skipped\n",
+		counts[generator.ChangeCreate], counts[generator.ChangeUpdate],
+		counts[generator.ChangeMerged], counts[generator.ChangeConflict],
+		counts[generator.ChangeUnchanged], counts[generator.ChangeSkipped])
+
+	fmt.Println()
+	if _, err := applyGenerated(gen, force, dryRun); err != nil {
+		return fmt.Errorf("error applying schema: %w", err)
+	}
+	if dryRun {
+		fmt.Println("\n💡 Run without --dry-run to apply the changes")
+	}
+
+	return nil
+}
+
+// changeIcon returns the icon printed next to a file change
+func changeIcon(kind generator.ChangeKind) string {
+	switch kind {
+	case generator.ChangeCreate:
+		return "✨"
+	case generator.ChangeUpdate:
+		return "📝"
+	case generator.ChangeMerged:
+		return "🔀"
+	case generator.ChangeConflict:
+		return "⚠️ "
+	case generator.ChangeDelete:
+		return "🗑️ "
+	case generator.ChangeSkipped:
+		return "⏭️ "
+	}
+	return "  "
+}
@@ -6,19 +6,21 @@ import (
 
 var makeCmd = &cobra.Command{
 	Use:   "make",
-	Short: "Generate database-related components (models, seeders)",
+	Short: "Generate database-related components (models, seeders, migrations)",
 	Long: `Generate database-related components for GORM.
 
 These commands require that you have previously run 'loom add orm gorm'
 to set up the database structure.
 
 Available subcommands:
-  model   - Generate a GORM model with auto-registration
-  seeder  - Generate a seeder with auto-registration
+  model      - Generate a GORM model with auto-registration
+  seeder     - Generate a seeder with auto-registration
+  migration  - Generate a timestamped migration
 
 Examples:
   loom make model Product
-  loom make seeder Product`,
+  loom make seeder Product
+  loom make migration create_products_table --model=Product`,
 	Aliases: []string{"mk"},
 }
 
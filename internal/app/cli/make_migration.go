@@ -0,0 +1,302 @@
+package cli
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+var makeMigrationCmd = &cobra.Command{
+	Use:   "migration [name]",
+	Short: "Generate a timestamped database migration",
+	Long: `Generate a versioned migration with its up and down steps.
+
+This command requires that you have previously run 'loom add orm gorm'.
+
+Migrations are named after the time they are created, run in that order
+with 'loom db:migrate' and are reverted with 'loom db:rollback'.
+A Go migration registers itself; with --sql, a pair of .up.sql and
+.down.sql files is created instead and embedded in the console.
+
+--model fills in a Go migration creating (and dropping) the table of a
+model registered in models_all.go.
+
+--auto compares the models of models_all.go (their GORM tags) with the
+schema of the database configured in .env, and writes the statements
+adding or dropping tables, columns, indexes and foreign keys and changing
+column types. Apply the pending migrations first, and review the result:
+what cannot be migrated automatically is left as TODO comments.
+
+Location:
+  internal/database/migrations/{timestamp}_{name}.go
+  internal/database/migrations/sql/{timestamp}_{name}.up.sql (--sql)
+
+Examples:
+  loom make migration create_products_table --model=Product
+  loom make migration add_price_to_products --sql
+  loom make migration add_stock_to_products --auto`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMakeMigration,
+}
+
+func init() {
+	makeCmd.AddCommand(makeMigrationCmd)
+	makeMigrationCmd.Flags().Bool("sql", false, "Write .up.sql and .down.sql files instead of a Go migration")
+	makeMigrationCmd.Flags().String("model", "", "Model whose table the migration creates")
+	makeMigrationCmd.Flags().Bool("auto", false, "Diff the models against the database schema")
+}
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+func runMakeMigration(cmd *cobra.Command, args []string) error {
+	useSQL, _ := cmd.Flags().GetBool("sql")
+	model, _ := cmd.Flags().GetString("model")
+	auto, _ := cmd.Flags().GetBool("auto")
+
+	if useSQL && model != "" {
+		return fmt.Errorf("--model creates a Go migration and cannot be used with --sql")
+	}
+	if auto && (useSQL || model != "") {
+		return fmt.Errorf("--auto cannot be used with --sql or --model")
+	}
+
+	// Detect project
+	projectInfo, err := generator.DetectProject()
+	if err != nil {
+		return fmt.Errorf("error: no valid Loom project detected. %w", err)
+	}
+
+	name := migrationName(args[0])
+	if !migrationNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid migration name %q: use letters, digits and underscores", args[0])
+	}
+
+	migrationsDir := filepath.Join(projectInfo.RootPath, "internal", "database", "migrations")
+	changes := changeset.New()
+
+	// Check if the migrator exists (GORM addon installed)
+	if !changes.Exists(filepath.Join(migrationsDir, "migrator.go")) {
+		return fmt.Errorf("migrations not set up. Run 'loom add orm gorm' first")
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	if existing, _ := filepath.Glob(filepath.Join(migrationsDir, version+"_*")); len(existing) > 0 {
+		return fmt.Errorf("a migration with version %s already exists, try again in a second", version)
+	}
+	if existing, _ := filepath.Glob(filepath.Join(migrationsDir, "sql", version+"_*")); len(existing) > 0 {
+		return fmt.Errorf("a migration with version %s already exists, try again in a second", version)
+	}
+
+	fmt.Printf("🔍 Project: %s (%s)\n", projectInfo.Name, projectInfo.Architecture)
+	fmt.Printf("🗄️  Creating migration: %s_%s\n\n", version, name)
+
+	if auto {
+		return makeAutoMigration(changes, projectInfo.RootPath, migrationsDir, version, name)
+	}
+
+	var files [][2]string // path, content
+	if useSQL {
+		base := filepath.Join(migrationsDir, "sql", version+"_"+name)
+		files = append(files,
+			[2]string{base + ".up.sql", fmt.Sprintf("-- %s: write the schema changes here\n", name)},
+			[2]string{base + ".down.sql", fmt.Sprintf("-- %s: revert the changes of the up migration here\n", name)})
+
+		// The SQL files are embedded by sql.go, written with the first one
+		embedPath := filepath.Join(migrationsDir, "sql.go")
+		if !changes.Exists(embedPath) {
+			files = append(files, [2]string{embedPath, sqlEmbedContent})
+		}
+	} else {
+		var modelsImport string
+		if model != "" {
+			model = capitalizeFirst(model)
+			modelsImport, err = modelsImportPath(changes, projectInfo.RootPath)
+			if err != nil {
+				return err
+			}
+		}
+		path := filepath.Join(migrationsDir, version+"_"+name+".go")
+		files = append(files, [2]string{path, generateMigrationContent(version, name, model, modelsImport)})
+	}
+
+	tracked := make(map[string][]byte, len(files))
+	for _, file := range files {
+		path, content := file[0], []byte(file[1])
+		if err := changes.WriteFile(path, content); err != nil {
+			return fmt.Errorf("failed to write migration: %w", err)
+		}
+		tracked[path] = content
+	}
+	// 'loom destroy migration <name>' removes the tracked files
+	if err := trackFiles(changes, projectInfo.RootPath, "migration:"+name, tracked); err != nil {
+		return err
+	}
+
+	if err := changes.Commit(); err != nil {
+		return fmt.Errorf("failed to write migration: %w", err)
+	}
+
+	for _, file := range files {
+		fmt.Printf("   ✅ Created: %s\n", file[0])
+	}
+
+	fmt.Println("\n✅ Migration created successfully!")
+	fmt.Println("\n📝 Next steps:")
+	if model == "" {
+		fmt.Println("   1. Write the up and down steps of the migration")
+		fmt.Println("   2. Run 'loom db:migrate' to apply it")
+	} else {
+		fmt.Println("   1. Run 'loom db:migrate' to apply it")
+	}
+
+	return nil
+}
+
+// makeAutoMigration has the project console diff the models against the
+// database schema and write the migration, then tracks it
+func makeAutoMigration(changes *changeset.Set, root, migrationsDir, version, name string) error {
+	if !changes.Exists(filepath.Join(migrationsDir, "autodiff.go")) {
+		return fmt.Errorf("the migrations of this project cannot be diffed: reinstall the ORM with 'loom add orm gorm --force'")
+	}
+
+	if err := executeConsoleCommand("diff", "--name="+name, "--version="+version); err != nil {
+		return err
+	}
+
+	path := filepath.Join(migrationsDir, version+"_"+name+".go")
+	content, err := changes.ReadFile(path)
+	if err != nil {
+		// The schema already matches the models
+		return nil
+	}
+
+	// Tracked like the other migrations, for 'loom destroy migration <name>'
+	if err := trackFile(changes, root, path, content, "migration:"+name); err != nil {
+		return err
+	}
+	if err := changes.Commit(); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	fmt.Println("\n✅ Migration created successfully!")
+	fmt.Println("\n📝 Next steps:")
+	fmt.Printf("   1. Review %s\n", path)
+	fmt.Println("   2. Run 'loom db:migrate' to apply it")
+	return nil
+}
+
+// migrationName turns a migration name into snake_case
+// ("CreateProductsTable" and "create-products-table" give
+// "create_products_table")
+func migrationName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '-' || r == ' ':
+			b.WriteRune('_')
+		case unicode.IsUpper(r):
+			if i > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// modelsImportPath returns the package models_all.go imports as
+// "models", where the models registered with 'loom make model' live
+func modelsImportPath(changes *changeset.Set, root string) (string, error) {
+	modelsAllPath := filepath.Join(root, "internal", "database", "models_all.go")
+	content, err := changes.ReadFile(modelsAllPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read models_all.go: %w", err)
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), modelsAllPath, content, parser.ImportsOnly)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse models_all.go: %w", err)
+	}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if (spec.Name != nil && spec.Name.Name == "models") || (spec.Name == nil && filepath.Base(path) == "models") {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("models_all.go does not import a models package")
+}
+
+func generateMigrationContent(version, name, model, modelsImport string) string {
+	if model == "" {
+		return fmt.Sprintf(`package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	Register(Migration{
+		Version: %q,
+		Name:    %q,
+		Up: func(tx *gorm.DB) error {
+			// Write the schema changes here, e.g.:
+			// return tx.Exec("ALTER TABLE products ADD COLUMN price numeric").Error
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Revert the changes of Up here, e.g.:
+			// return tx.Exec("ALTER TABLE products DROP COLUMN price").Error
+			return nil
+		},
+	})
+}
+`, version, name)
+	}
+
+	return fmt.Sprintf(`package migrations
+
+import (
+	models %q
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(Migration{
+		Version: %q,
+		Name:    %q,
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.%s{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.%s{})
+		},
+	})
+}
+`, modelsImport, version, name, model, model)
+}
+
+// sqlEmbedContent embeds the SQL migrations into the migrations package
+const sqlEmbedContent = `package migrations
+
+import "embed"
+
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+func init() {
+	if err := RegisterFS(sqlFiles, "sql"); err != nil {
+		panic(err)
+	}
+}
+`
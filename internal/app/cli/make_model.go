@@ -2,11 +2,12 @@ package cli
 
 import (
 	"fmt"
-	"os"
+	"go/format"
 	"path/filepath"
 	"strings"
 	"unicode"
 
+	"github.com/geomark27/loom-go/internal/changeset"
 	"github.com/geomark27/loom-go/internal/generator"
 	"github.com/spf13/cobra"
 )
@@ -24,12 +25,19 @@ The model will be generated with:
   - GORM tags for common fields
   - Automatic registration in internal/database/models_all.go
 
+Relations add foreign keys and GORM associations:
+  --belongs-to=user      UserID foreign key and User association
+  --has-many=orders      Orders association (orders need a product_id column)
+  --many-to-many=tags    Tags association and a ProductTag join model,
+                         registered in models_all.go
+
 Location depends on architecture:
   - Layered: internal/app/models/{name}.go
   - Modular: internal/models/{name}.go
 
 Examples:
   loom make model Product
+  loom make model Product --belongs-to=category --many-to-many=tags
   loom make model Category --force`,
 	Args: cobra.ExactArgs(1),
 	RunE: runMakeModel,
@@ -38,6 +46,9 @@ Examples:
 func init() {
 	makeCmd.AddCommand(makeModelCmd)
 	makeModelCmd.Flags().Bool("force", false, "Overwrite existing files")
+	makeModelCmd.Flags().StringSlice("belongs-to", nil, "Parent entities (adds <entity>_id and the association)")
+	makeModelCmd.Flags().StringSlice("has-many", nil, "Child entities (adds a slice association)")
+	makeModelCmd.Flags().StringSlice("many-to-many", nil, "Related entities (adds the association and a join model)")
 }
 
 func runMakeModel(cmd *cobra.Command, args []string) error {
@@ -55,6 +66,11 @@ func runMakeModel(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid model name: %w", err)
 	}
 
+	relations, err := parseRelationFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	// Capitalize first letter for struct name
 	structName := capitalizeFirst(name)
 	fileName := strings.ToLower(name)
@@ -71,13 +87,16 @@ func runMakeModel(cmd *cobra.Command, args []string) error {
 		modelsAllPath = filepath.Join(projectInfo.RootPath, "internal", "database", "models_all.go")
 	}
 
+	// The model and its registration are written together
+	changes := changeset.New()
+
 	// Check if models_all.go exists (GORM addon installed)
-	if _, err := os.Stat(modelsAllPath); os.IsNotExist(err) {
+	if !changes.Exists(modelsAllPath) {
 		return fmt.Errorf("GORM not installed. Run 'loom add orm gorm' first")
 	}
 
 	// Check if model file already exists
-	if _, err := os.Stat(modelPath); err == nil && !force {
+	if changes.Exists(modelPath) && !force {
 		return fmt.Errorf("model %s already exists. Use --force to overwrite", fileName)
 	}
 
@@ -85,38 +104,51 @@ func runMakeModel(cmd *cobra.Command, args []string) error {
 	fmt.Printf("📦 Creating GORM model: %s\n\n", structName)
 
 	// Generate model file
-	modelContent := generateGORMModelContent(structName, projectInfo.ModuleName)
+	modelContent := generateGORMModelContent(structName, relations)
 
-	// Create directory if needed
-	if err := os.MkdirAll(filepath.Dir(modelPath), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	// Stage model file
+	if err := changes.WriteFile(modelPath, []byte(modelContent)); err != nil {
+		return fmt.Errorf("failed to write model file: %w", err)
 	}
 
-	// Write model file
-	if err := os.WriteFile(modelPath, []byte(modelContent), 0644); err != nil {
+	// Update models_all.go
+	var messages []string
+	registered := append([]string{structName}, generator.JoinModelNames(structName, relations)...)
+	for _, model := range registered {
+		added, err := generator.RegisterModel(changes, modelsAllPath, model)
+		if err != nil {
+			messages = append(messages,
+				fmt.Sprintf("   ⚠️  Warning: Could not auto-register model: %v", err),
+				fmt.Sprintf("   💡 Manually add '&models.%s{}' to AllModels in models_all.go", model))
+		} else if added {
+			messages = append(messages, fmt.Sprintf("   ✅ Registered %s in: %s", model, modelsAllPath))
+		}
+	}
+
+	// Track the file so that 'loom destroy model' can remove it
+	if err := trackFile(changes, projectInfo.RootPath, modelPath, []byte(modelContent), "model:"+fileName); err != nil {
+		return err
+	}
+
+	if err := changes.Commit(); err != nil {
 		return fmt.Errorf("failed to write model file: %w", err)
 	}
 
 	fmt.Printf("   ✅ Created: %s\n", modelPath)
-
-	// Update models_all.go
-	if err := addModelToRegistry(modelsAllPath, structName, projectInfo); err != nil {
-		fmt.Printf("   ⚠️  Warning: Could not auto-register model: %v\n", err)
-		fmt.Printf("   💡 Manually add '&models.%s{}' to AllModels in models_all.go\n", structName)
-	} else {
-		fmt.Printf("   ✅ Registered in: %s\n", modelsAllPath)
+	for _, message := range messages {
+		fmt.Println(message)
 	}
 
 	fmt.Println("\n✅ Model created successfully!")
 	fmt.Println("\n📝 Next steps:")
 	fmt.Printf("   1. Edit %s to add your fields\n", modelPath)
-	fmt.Println("   2. Run 'make db-migrate' to create the table")
+	fmt.Printf("   2. Run 'loom make migration create_%ss_table --model=%s' and 'loom db:migrate' to create the table\n", fileName, structName)
 
 	return nil
 }
 
-func generateGORMModelContent(structName, moduleName string) string {
-	return fmt.Sprintf(`package models
+func generateGORMModelContent(structName string, relations []generator.Relation) string {
+	content := fmt.Sprintf(`package models
 
 import "gorm.io/gorm"
 
@@ -125,7 +157,7 @@ type %s struct {
 	gorm.Model
 	Name        string %s
 	Description string %s
-	// Add your fields here
+%s	// Add your fields here
 	// Example:
 	// Price    float64 %s
 	// Stock    int     %s
@@ -136,54 +168,34 @@ type %s struct {
 // func (%s) TableName() string {
 //     return "%ss"
 // }
-`, structName, strings.ToLower(structName), structName,
+%s`, structName, strings.ToLower(structName), structName,
 		"`gorm:\"size:100;not null\" json:\"name\"`",
 		"`gorm:\"type:text\" json:\"description\"`",
+		generator.GORMRelationLines(structName, relations),
 		"`gorm:\"not null;default:0\" json:\"price\"`",
 		"`gorm:\"default:0\" json:\"stock\"`",
 		"`gorm:\"default:true\" json:\"is_active\"`",
-		structName, strings.ToLower(structName))
-}
-
-func addModelToRegistry(modelsAllPath, structName string, projectInfo *generator.ProjectInfo) error {
-	content, err := os.ReadFile(modelsAllPath)
-	if err != nil {
-		return err
-	}
-
-	contentStr := string(content)
+		structName, strings.ToLower(structName),
+		generator.GORMJoinModels(structName, relations))
 
-	// Check if already registered (not in comments)
-	registryLine := fmt.Sprintf("&models.%s{}", structName)
-	if strings.Contains(contentStr, registryLine) && !strings.Contains(contentStr, "// "+registryLine) {
-		return nil // Already registered
+	// Align the struct tags of the relation fields
+	if formatted, err := format.Source([]byte(content)); err == nil {
+		return string(formatted)
 	}
+	return content
+}
 
-	// Find the &models.User{}, line (the actual entry, not a comment)
-	lines := strings.Split(contentStr, "\n")
-	var insertIndex int = -1
-
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		// Look for actual model entries (start with &models. and end with {},)
-		if strings.HasPrefix(trimmed, "&models.") && strings.HasSuffix(trimmed, "{},") {
-			insertIndex = i
-		}
-	}
+// parseRelationFlags reads --belongs-to, --has-many and --many-to-many
+func parseRelationFlags(cmd *cobra.Command) ([]generator.Relation, error) {
+	belongsTo, _ := cmd.Flags().GetStringSlice("belongs-to")
+	hasMany, _ := cmd.Flags().GetStringSlice("has-many")
+	manyToMany, _ := cmd.Flags().GetStringSlice("many-to-many")
 
-	if insertIndex == -1 {
-		return fmt.Errorf("could not find AllModels entries")
+	relations, err := generator.ParseRelations(belongsTo, hasMany, manyToMany)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relations: %w", err)
 	}
-
-	// Insert new model after the last entry
-	newLine := fmt.Sprintf("\t&models.%s{},", structName)
-	newLines := make([]string, 0, len(lines)+1)
-	newLines = append(newLines, lines[:insertIndex+1]...)
-	newLines = append(newLines, newLine)
-	newLines = append(newLines, lines[insertIndex+1:]...)
-
-	newContent := strings.Join(newLines, "\n")
-	return os.WriteFile(modelsAllPath, []byte(newContent), 0644)
+	return relations, nil
 }
 
 func capitalizeFirst(s string) string {
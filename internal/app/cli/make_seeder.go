@@ -2,10 +2,11 @@ package cli
 
 import (
 	"fmt"
-	"os"
+	"go/format"
 	"path/filepath"
 	"strings"
 
+	"github.com/geomark27/loom-go/internal/changeset"
 	"github.com/geomark27/loom-go/internal/generator"
 	"github.com/spf13/cobra"
 )
@@ -22,11 +23,16 @@ The seeder will be generated with:
   - Template for seed data
   - Automatic registration in internal/database/seeders/seeders_all.go
 
+--depends makes the seeder run after other seeders, --tag puts it in
+groups run with 'loom db:seed --tag' and --env restricts it to some
+environments (keep demo data out of production).
+
 Location:
   internal/database/seeders/{name}_seeder.go
 
 Examples:
-  loom make seeder Product
+  loom make seeder Product --depends=Category
+  loom make seeder DemoOrder --tag=demo --env=development,testing
   loom make seeder Category --force`,
 	Args: cobra.ExactArgs(1),
 	RunE: runMakeSeeder,
@@ -35,11 +41,17 @@ Examples:
 func init() {
 	makeCmd.AddCommand(makeSeederCmd)
 	makeSeederCmd.Flags().Bool("force", false, "Overwrite existing files")
+	makeSeederCmd.Flags().StringSlice("depends", nil, "Seeders to run first (e.g. User,Category)")
+	makeSeederCmd.Flags().StringSlice("tag", nil, "Tags of the seeder (e.g. demo)")
+	makeSeederCmd.Flags().StringSlice("env", nil, "Environments the seeder runs in (default: all)")
 }
 
 func runMakeSeeder(cmd *cobra.Command, args []string) error {
 	name := args[0]
 	force, _ := cmd.Flags().GetBool("force")
+	depends, _ := cmd.Flags().GetStringSlice("depends")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	envs, _ := cmd.Flags().GetStringSlice("env")
 
 	// Detect project
 	projectInfo, err := generator.DetectProject()
@@ -60,13 +72,16 @@ func runMakeSeeder(cmd *cobra.Command, args []string) error {
 	seederPath := filepath.Join(projectInfo.RootPath, "internal", "database", "seeders", fileName+"_seeder.go")
 	seedersAllPath := filepath.Join(projectInfo.RootPath, "internal", "database", "seeders", "seeders_all.go")
 
+	// The seeder and its registration are written together
+	changes := changeset.New()
+
 	// Check if seeders_all.go exists (GORM addon installed)
-	if _, err := os.Stat(seedersAllPath); os.IsNotExist(err) {
+	if !changes.Exists(seedersAllPath) {
 		return fmt.Errorf("GORM not installed. Run 'loom add orm gorm' first")
 	}
 
 	// Check if seeder file already exists
-	if _, err := os.Stat(seederPath); err == nil && !force {
+	if changes.Exists(seederPath) && !force {
 		return fmt.Errorf("seeder %s already exists. Use --force to overwrite", fileName)
 	}
 
@@ -83,22 +98,33 @@ func runMakeSeeder(cmd *cobra.Command, args []string) error {
 
 	// Generate seeder file
 	seederContent := generateSeederContent(structName, projectInfo.ModuleName, modelsPath)
+	seederContent += seederMethods(structName, depends, tags, envs)
 
-	// Create directory if needed
-	if err := os.MkdirAll(filepath.Dir(seederPath), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	// Sort the imports like gofmt, so the file stays as generated
+	if formatted, err := format.Source([]byte(seederContent)); err == nil {
+		seederContent = string(formatted)
 	}
 
-	// Write seeder file
-	if err := os.WriteFile(seederPath, []byte(seederContent), 0644); err != nil {
+	// Stage seeder file
+	if err := changes.WriteFile(seederPath, []byte(seederContent)); err != nil {
 		return fmt.Errorf("failed to write seeder file: %w", err)
 	}
 
-	fmt.Printf("   ✅ Created: %s\n", seederPath)
-
 	// Update seeders_all.go
-	if err := addSeederToRegistry(seedersAllPath, structName); err != nil {
-		fmt.Printf("   ⚠️  Warning: Could not auto-register seeder: %v\n", err)
+	_, registerErr := generator.RegisterSeeder(changes, seedersAllPath, structName)
+
+	// Track the file so that 'loom destroy seeder' can remove it
+	if err := trackFile(changes, projectInfo.RootPath, seederPath, []byte(seederContent), "seeder:"+fileName); err != nil {
+		return err
+	}
+
+	if err := changes.Commit(); err != nil {
+		return fmt.Errorf("failed to write seeder file: %w", err)
+	}
+
+	fmt.Printf("   ✅ Created: %s\n", seederPath)
+	if registerErr != nil {
+		fmt.Printf("   ⚠️  Warning: Could not auto-register seeder: %v\n", registerErr)
 		fmt.Printf("   💡 Manually add '&%sSeeder{}' to AllSeeders in seeders_all.go\n", structName)
 	} else {
 		fmt.Printf("   ✅ Registered in: %s\n", seedersAllPath)
@@ -107,7 +133,7 @@ func runMakeSeeder(cmd *cobra.Command, args []string) error {
 	fmt.Println("\n✅ Seeder created successfully!")
 	fmt.Println("\n📝 Next steps:")
 	fmt.Printf("   1. Edit %s to add your seed data\n", seederPath)
-	fmt.Println("   2. Run 'make db-seed' to execute seeders")
+	fmt.Printf("   2. Run 'loom db:seed --class=%sSeeder' to execute it\n", structName)
 
 	return nil
 }
@@ -155,44 +181,37 @@ func (s *%sSeeder) Run(db *gorm.DB) error {
 		structName, lowerName, lowerName)
 }
 
-func addSeederToRegistry(seedersAllPath, structName string) error {
-	content, err := os.ReadFile(seedersAllPath)
-	if err != nil {
-		return err
-	}
-
-	contentStr := string(content)
-	seederName := structName + "Seeder"
-
-	// Check if already registered
-	registryLine := "&" + seederName + "{}"
-	if strings.Contains(contentStr, registryLine) && !strings.Contains(contentStr, "// "+registryLine) {
-		return nil // Already registered
-	}
-
-	// Find the actual seeder entries (not comments)
-	lines := strings.Split(contentStr, "\n")
-	var insertIndex int = -1
-
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		// Look for actual seeder entries (start with & and contain Seeder{},)
-		if strings.HasPrefix(trimmed, "&") && strings.Contains(trimmed, "Seeder{},") {
-			insertIndex = i
+// seederMethods returns the methods declaring the dependencies, tags and
+// environments of a seeder
+func seederMethods(structName string, depends, tags, envs []string) string {
+	var b strings.Builder
+	if len(depends) > 0 {
+		seeders := make([]string, len(depends))
+		for i, dependency := range depends {
+			seeders[i] = "&" + strings.TrimSuffix(capitalizeFirst(dependency), "Seeder") + "Seeder{}"
 		}
+		fmt.Fprintf(&b, `
+// Dependencies returns the seeders to run before %[1]sSeeder
+func (s *%[1]sSeeder) Dependencies() []Seeder {
+	return []Seeder{%[2]s}
+}
+`, structName, strings.Join(seeders, ", "))
 	}
-
-	if insertIndex == -1 {
-		return fmt.Errorf("could not find AllSeeders entries")
+	if len(tags) > 0 {
+		fmt.Fprintf(&b, `
+// Tags returns the groups %[1]sSeeder belongs to
+func (s *%[1]sSeeder) Tags() []string {
+	return %#[2]v
+}
+`, structName, tags)
 	}
-
-	// Insert new seeder after the last entry
-	newLine := fmt.Sprintf("\t&%s{},", seederName)
-	newLines := make([]string, 0, len(lines)+1)
-	newLines = append(newLines, lines[:insertIndex+1]...)
-	newLines = append(newLines, newLine)
-	newLines = append(newLines, lines[insertIndex+1:]...)
-
-	newContent := strings.Join(newLines, "\n")
-	return os.WriteFile(seedersAllPath, []byte(newContent), 0644)
+	if len(envs) > 0 {
+		fmt.Fprintf(&b, `
+// Environments returns the environments %[1]sSeeder runs in
+func (s *%[1]sSeeder) Environments() []string {
+	return %#[2]v
+}
+`, structName, envs)
+	}
+	return b.String()
 }
@@ -2,10 +2,13 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/geomark27/loom-go/internal/addon"
 	"github.com/geomark27/loom-go/internal/generator"
 	"github.com/geomark27/loom-go/internal/version"
 	"github.com/spf13/cobra"
@@ -20,9 +23,25 @@ golang-standard/project-layout structure.
 The generated project will include:
 - Idiomatic directory structure
 - Configured go.mod
-- Basic web server with net/http
-- README.md with instructions`,
-	Args: cobra.ExactArgs(1),
+- Basic web server (gin by default, or chi, echo or net/http)
+- README.md with instructions
+
+Run without flags on a terminal to start a wizard asking for the
+architecture, router, ORM, database, auth, Docker, CI and initial
+modules. The chosen addons are installed and the modules generated
+right after the project is created. The answers can be saved as a
+preset and reused:
+
+  loom new
+  loom new shop --preset team.yaml
+  loom new shop --preset team.yaml --modular   # flags override the preset
+  loom new shop --router chi
+  loom new shop --with=gorm,postgres,jwt,docker
+
+Addons given with --with (or chosen in the wizard) are integrated when
+the project is created: config fields, .env.example, go.mod, Makefile
+targets and docker-compose services are generated together.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runNewCommand,
 }
 
@@ -30,10 +49,41 @@ var (
 	standalone bool
 	moduleName string
 	modular    bool
+	packName   string
+	routerName string
+	presetFile string
+	withAddons []string
 )
 
 func runNewCommand(cmd *cobra.Command, args []string) error {
-	projectName := args[0]
+	projectName := ""
+	if len(args) > 0 {
+		projectName = args[0]
+	}
+
+	preset := &generator.Preset{}
+	if presetFile != "" {
+		loaded, err := generator.LoadPreset(presetFile)
+		if err != nil {
+			return err
+		}
+		preset = loaded
+	}
+	if err := applyNewFlags(cmd, preset); err != nil {
+		return err
+	}
+
+	// Without flags, a terminal gets the wizard
+	if cmd.Flags().NFlag() == 0 && isTerminal() {
+		name, answers, err := runNewWizard(projectName, *preset)
+		if err != nil {
+			return err
+		}
+		projectName, preset = name, answers
+	}
+	if projectName == "" {
+		return fmt.Errorf("project name is required (usage: loom new [project-name])")
+	}
 
 	// Validate project name
 	if err := validateProjectName(projectName); err != nil {
@@ -45,7 +95,7 @@ func runNewCommand(cmd *cobra.Command, args []string) error {
 	projectPath := filepath.Join(baseDir, projectName)
 
 	// Determine module name
-	module := moduleName
+	module := preset.Module
 	if module == "" {
 		// Try to detect GitHub user from git config
 		githubUser := detectGitHubUser()
@@ -59,9 +109,10 @@ func runNewCommand(cmd *cobra.Command, args []string) error {
 
 	// Determine architecture
 	architecture := "layered"
-	if modular {
-		architecture = "modular"
+	if preset.Architecture != "" {
+		architecture = preset.Architecture
 	}
+	isModular := architecture == "modular"
 
 	// Create project configuration
 	config := &generator.ProjectConfig{
@@ -69,10 +120,26 @@ func runNewCommand(cmd *cobra.Command, args []string) error {
 		Path:         projectPath,
 		ModuleName:   module,
 		Description:  fmt.Sprintf("%s project generated with Loom", projectName),
-		UseHelpers:   !standalone, // UseHelpers is true by default, false if --standalone is active
-		IsModular:    modular,
+		UseHelpers:   !preset.Standalone, // UseHelpers is true by default, false if --standalone is active
+		IsModular:    isModular,
 		Architecture: architecture,
 		LoomVersion:  version.Current.String(), // Inject current Loom version dynamically
+		Router:       preset.Router,
+		CI:           preset.CI,
+		Addons:       preset.Addons(),
+		Installer:    addon.NewAddonManager(projectPath, architecture),
+	}
+
+	// A pack provides an alternative project skeleton
+	if preset.Pack != "" {
+		pack, err := generator.FindPack(preset.Pack)
+		if err != nil {
+			return err
+		}
+		if pack.Skeleton == nil {
+			return fmt.Errorf("pack %s does not provide a project skeleton", pack.Name)
+		}
+		config.Pack = pack
 	}
 
 	// Generate the project
@@ -81,14 +148,37 @@ func runNewCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("error generating project: %w", err)
 	}
 
+	// The project keeps the pack so its component kinds are available
+	if config.Pack != nil {
+		if err := generator.CopyPack(config.Pack, filepath.Join(projectPath, generator.ProjectPacksDir())); err != nil {
+			return fmt.Errorf("error installing pack %s in the project: %w", config.Pack.Name, err)
+		}
+	}
+
 	// Success message with architecture information
 	fmt.Printf("✅ Project '%s' created successfully in %s\n", projectName, projectPath)
+	if config.Pack != nil {
+		fmt.Printf("📦 Skeleton: pack %s %s\n", config.Pack.Name, config.Pack.Version)
+	}
+	fmt.Printf("🌐 Router: %s\n", config.HTTP().Name)
+
+	// Initial modules see the installed addons (GORM models are registered)
+	if len(preset.Modules) > 0 {
+		if err := generateInitialModules(projectPath, preset.Modules); err != nil {
+			return err
+		}
+	}
+
+	report, err := verifyProject(cmd, projectPath, trackedFiles(projectPath))
+	if err != nil {
+		return err
+	}
 
 	// Architecture information
 	if config.IsModular {
 		fmt.Printf("\n🏗️  Architecture: Modular (domain-based)\n")
 		fmt.Printf("   → Ideal for: Large projects (20+ endpoints), teams, microservices\n")
-		fmt.Printf("   → Modules: users (example generated)\n")
+		fmt.Printf("   → Modules: %s\n", strings.Join(append([]string{"users (example generated)"}, preset.Modules...), ", "))
 		fmt.Printf("\n💡 Tips:\n")
 		fmt.Printf("   • Use 'loom generate module <name>' to add modules\n")
 		fmt.Printf("   • Keep modules independent (use Event Bus for communication)\n")
@@ -112,12 +202,86 @@ func runNewCommand(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("\nNext steps:\n")
 	fmt.Printf("  cd %s\n", projectName)
-	fmt.Printf("  go mod tidy\n")
+	if !tidied(report) {
+		fmt.Printf("  go mod tidy\n")
+	}
 	fmt.Printf("  go run cmd/%s/main.go\n", projectName)
 
 	return nil
 }
 
+// applyNewFlags sets the preset values given explicitly on the command
+// line, so flags take precedence over a preset file
+func applyNewFlags(cmd *cobra.Command, preset *generator.Preset) error {
+	flags := cmd.Flags()
+	if flags.Changed("module") {
+		preset.Module = moduleName
+	}
+	if flags.Changed("standalone") {
+		preset.Standalone = standalone
+	}
+	if flags.Changed("modular") {
+		preset.Architecture = "layered"
+		if modular {
+			preset.Architecture = "modular"
+		}
+	}
+	if flags.Changed("pack") {
+		preset.Pack = packName
+	}
+	if flags.Changed("router") {
+		preset.Router = routerName
+	}
+	// Routers in --with pick the router of the skeleton
+	for _, name := range withAddons {
+		if slices.Contains(generator.PresetRouters, name) {
+			preset.Router = name
+			continue
+		}
+		if !slices.Contains(preset.With, name) {
+			preset.With = append(preset.With, name)
+		}
+	}
+	return preset.Validate()
+}
+
+// generateInitialModules generates and wires the initial modules of a
+// preset in a freshly created project
+func generateInitialModules(projectPath string, modules []string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(projectPath); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	projectInfo, err := detectProject()
+	if err != nil {
+		return err
+	}
+	gen := generator.NewModuleGenerator(projectInfo)
+
+	fmt.Println()
+	for _, name := range modules {
+		if _, err := gen.GenerateModule(name, nil, nil, false); err != nil {
+			return fmt.Errorf("error generating module %s: %w", name, err)
+		}
+		if _, err := gen.WireModule(name, nil); err != nil {
+			fmt.Printf("⚠️  Could not wire module %s automatically: %v\n", name, err)
+		}
+	}
+	if err := gen.Commit(); err != nil {
+		return fmt.Errorf("error generating modules: %w", err)
+	}
+	for _, name := range modules {
+		fmt.Printf("🧩 Module %s generated\n", name)
+	}
+
+	return nil
+}
+
 func validateProjectName(name string) error {
 	if name == "" {
 		return fmt.Errorf("name cannot be empty")
@@ -182,4 +346,9 @@ func init() {
 	newCmd.Flags().StringVarP(&moduleName, "module", "m", "", "Go module name (auto-detects from git config or uses project name)")
 	newCmd.Flags().BoolVar(&standalone, "standalone", false, "Generate project without Loom helpers (100% independent code)")
 	newCmd.Flags().BoolVar(&modular, "modular", false, "Generate modular architecture by domain (recommended for large projects with 20+ endpoints)")
+	newCmd.Flags().StringVar(&packName, "pack", "", "Use the project skeleton of an installed template pack")
+	newCmd.Flags().StringVar(&routerName, "router", "", "HTTP router of the project (gin, chi, echo, net/http)")
+	newCmd.Flags().StringVar(&presetFile, "preset", "", "Create the project from a preset file saved by the wizard")
+	addVerifyFlags(newCmd.Flags())
+	newCmd.Flags().StringSliceVar(&withAddons, "with", nil, "Addons integrated at creation (gorm, sqlc, postgres, mysql, sqlite, sqlserver, mongodb, redis, jwt, oauth2, docker)")
 }
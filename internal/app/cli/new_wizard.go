@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/generator"
+)
+
+// isTerminal reports whether standard input is an interactive terminal
+func isTerminal() bool {
+	return isTerminalFile(os.Stdin)
+}
+
+// isTerminalFile reports whether f is a character device other than the
+// null device (stdin is often redirected from /dev/null in scripts)
+func isTerminalFile(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// wizard asks the "loom new" questions on a terminal
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newWizard() *wizard {
+	return &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+}
+
+// ask prints a question and returns the trimmed answer, or def when the
+// answer is empty
+func (w *wizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+
+	line, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("wizard aborted: %w", err)
+	}
+
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// choose asks for one of options until a valid answer is given
+func (w *wizard) choose(question string, options []string, def string) (string, error) {
+	for {
+		answer, err := w.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, "/")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, option := range options {
+			if strings.EqualFold(answer, option) {
+				return option, nil
+			}
+		}
+		fmt.Fprintf(w.out, "   ⚠️  choose one of: %s\n", strings.Join(options, ", "))
+	}
+}
+
+// confirm asks a yes/no question
+func (w *wizard) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := w.ask(fmt.Sprintf("%s (%s)", question, hint), "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(w.out, "   ⚠️  answer y or n")
+	}
+}
+
+// optional turns an empty choice into "none" for display and back
+func optional(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// runNewWizard walks through the choices of a new project, starting from
+// the values of preset. Returns the project name and the answers.
+func runNewWizard(projectName string, preset generator.Preset) (string, *generator.Preset, error) {
+	w := newWizard()
+	fmt.Fprintln(w.out, "🧶 Loom project wizard (press Enter to keep the default)")
+	fmt.Fprintln(w.out)
+
+	var err error
+	for projectName == "" {
+		if projectName, err = w.ask("Project name", ""); err != nil {
+			return "", nil, err
+		}
+		if projectName != "" {
+			if verr := validateProjectName(projectName); verr != nil {
+				fmt.Fprintf(w.out, "   ⚠️  %v\n", verr)
+				projectName = ""
+			}
+		}
+	}
+
+	steps := []struct {
+		question string
+		options  []string
+		value    *string
+		none     bool
+	}{
+		{"Architecture", generator.PresetArchitectures, &preset.Architecture, false},
+		{"Router", generator.PresetRouters, &preset.Router, false},
+		{"ORM", generator.PresetORMs, &preset.ORM, true},
+		{"Database", generator.PresetDatabases, &preset.Database, true},
+		{"Auth", generator.PresetAuths, &preset.Auth, true},
+	}
+	for _, step := range steps {
+		options := step.options
+		def := *step.value
+		if step.none {
+			options = append([]string{"none"}, options...)
+			def = optional(def)
+		} else if def == "" {
+			def = options[0]
+		}
+
+		answer, err := w.choose(step.question, options, def)
+		if err != nil {
+			return "", nil, err
+		}
+		if answer == "none" {
+			answer = ""
+		}
+		*step.value = answer
+	}
+
+	if preset.Docker, err = w.confirm("Docker (Dockerfile and docker-compose)", preset.Docker); err != nil {
+		return "", nil, err
+	}
+	if preset.CI, err = w.confirm("CI (GitHub Actions workflow)", preset.CI); err != nil {
+		return "", nil, err
+	}
+
+	for {
+		answer, err := w.ask("Initial modules (comma separated, e.g. products,orders)", strings.Join(preset.Modules, ","))
+		if err != nil {
+			return "", nil, err
+		}
+		preset.Modules = splitList(answer)
+		if verr := preset.Validate(); verr != nil {
+			fmt.Fprintf(w.out, "   ⚠️  %v\n", verr)
+			continue
+		}
+		break
+	}
+
+	fmt.Fprintln(w.out)
+	fmt.Fprintf(w.out, "📋 %s: %s, %s, ORM %s, database %s, auth %s, docker %t, CI %t\n",
+		projectName, preset.Architecture, preset.Router, optional(preset.ORM), optional(preset.Database),
+		optional(preset.Auth), preset.Docker, preset.CI)
+	if len(preset.Modules) > 0 {
+		fmt.Fprintf(w.out, "   modules: %s\n", strings.Join(preset.Modules, ", "))
+	}
+
+	ok, err := w.confirm("Create the project", true)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, fmt.Errorf("project creation cancelled")
+	}
+
+	path, err := w.ask("Save these answers as a preset (file name, empty to skip)", "")
+	if err != nil {
+		return "", nil, err
+	}
+	if path != "" {
+		if err := preset.Save(path); err != nil {
+			return "", nil, fmt.Errorf("error saving preset: %w", err)
+		}
+		fmt.Fprintf(w.out, "💾 Preset saved to %s (reuse it with: loom new <name> --preset %s)\n", path, path)
+	}
+	fmt.Fprintln(w.out)
+
+	return projectName, &preset, nil
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+var packCmd = &cobra.Command{
+	Use:   "pack",
+	Short: "Install and manage template packs",
+	Long: `Install and manage template packs.
+
+A pack is a directory with a pack.yaml manifest. It can declare new
+component kinds for 'loom generate' and a project skeleton for
+'loom new --pack'. Installed packs are pinned in a lockfile with their
+version, source, git commit and checksum:
+  - Project packs: .loom/packs (lockfile .loom/packs.lock)
+  - User packs:    ~/.config/loom/packs (lockfile ~/.config/loom/packs.lock)
+
+Examples:
+  loom pack add ./packs/acme
+  loom pack add https://github.com/acme/loom-pack.git@v1.2.0
+  loom pack add /srv/git/acme-pack.git@v1.2.0 --user
+  loom pack list
+  loom pack remove acme`,
+}
+
+var packAddCmd = &cobra.Command{
+	Use:   "add [source]",
+	Short: "Install a pack from a directory or a git repository",
+	Long: `Install a pack from a local directory or a git repository.
+
+Append @<tag> (or any git ref) to a repository to pin a version. Local
+bare repositories work as git sources too.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPackAdd,
+}
+
+var packListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed packs and verify their checksums",
+	Args:  cobra.NoArgs,
+	RunE:  runPackList,
+}
+
+var packRemoveCmd = &cobra.Command{
+	Use:     "remove [name]",
+	Short:   "Uninstall a pack",
+	Aliases: []string{"rm"},
+	Args:    cobra.ExactArgs(1),
+	RunE:    runPackRemove,
+}
+
+var (
+	packUser  bool
+	packForce bool
+)
+
+func init() {
+	rootCmd.AddCommand(packCmd)
+	packCmd.AddCommand(packAddCmd)
+	packCmd.AddCommand(packListCmd)
+	packCmd.AddCommand(packRemoveCmd)
+
+	packCmd.PersistentFlags().BoolVar(&packUser, "user", false, "Use the per-user packs directory instead of the project")
+	packAddCmd.Flags().BoolVar(&packForce, "force", false, "Replace an installed pack with the same name")
+}
+
+// packsDir returns the packs directory selected by --user
+func packsDir() (string, error) {
+	if !packUser {
+		return generator.ProjectPacksDir(), nil
+	}
+	dir := generator.UserPacksDir()
+	if dir == "" {
+		return "", fmt.Errorf("could not determine the user config directory")
+	}
+	return dir, nil
+}
+
+func runPackAdd(cmd *cobra.Command, args []string) error {
+	dir, err := packsDir()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📦 Installing pack from %s\n", args[0])
+
+	pack, err := generator.InstallPack(args[0], dir, packForce)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Pack %s %s installed in %s\n", pack.Name, pack.Version, pack.Dir)
+	for _, kind := range pack.Kinds {
+		fmt.Printf("   ✨ loom generate %s\n", kind.Name)
+	}
+	if pack.Skeleton != nil {
+		fmt.Printf("   ✨ loom new <name> --pack=%s\n", pack.Name)
+	}
+
+	return nil
+}
+
+func runPackList(cmd *cobra.Command, args []string) error {
+	packs, errs := generator.LoadPacks()
+	for _, err := range errs {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	if len(packs) == 0 {
+		fmt.Println("No packs installed")
+		fmt.Println("💡 Install one with: loom pack add <dir|git-url@tag>")
+		return nil
+	}
+
+	for _, pack := range packs {
+		fmt.Printf("📦 %s %s (%s)\n", pack.Name, pack.Version, pack.Dir)
+		if pack.Description != "" {
+			fmt.Printf("   %s\n", pack.Description)
+		}
+		for _, kind := range pack.Kinds {
+			fmt.Printf("   • generate %s\n", kind.Name)
+		}
+		if pack.Skeleton != nil {
+			fmt.Println("   • project skeleton")
+		}
+		fmt.Printf("   %s\n", packLockStatus(pack))
+	}
+
+	return nil
+}
+
+// packLockStatus compares an installed pack with its lockfile entry
+func packLockStatus(pack *generator.Pack) string {
+	dir := filepath.Dir(pack.Dir)
+
+	lock, err := generator.LoadPackLock(dir)
+	if err != nil {
+		return "⚠️  " + err.Error()
+	}
+	locked, ok := lock.Packs[pack.Name]
+	if !ok {
+		return "⚠️  not in the lockfile"
+	}
+
+	checksum, err := generator.PackChecksum(pack.Dir)
+	if err != nil {
+		return "⚠️  " + err.Error()
+	}
+	if checksum != locked.Checksum {
+		return "⚠️  modified since it was installed (checksum mismatch)"
+	}
+
+	source := locked.Source
+	if locked.Ref != "" {
+		source += "@" + locked.Ref
+	}
+	return "🔒 " + source
+}
+
+func runPackRemove(cmd *cobra.Command, args []string) error {
+	dir, err := packsDir()
+	if err != nil {
+		return err
+	}
+
+	if err := generator.RemovePack(args[0], dir); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Pack %s removed\n", args[0])
+	return nil
+}
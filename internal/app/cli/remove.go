@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/addon"
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	removeForce  bool
+	removeDryRun bool
+)
+
+var removeCmd = &cobra.Command{
+	Use:   "remove [type] [name]",
+	Short: "Remove an addon from the project",
+	Long: `Uninstall an addon added with 'loom add', the inverse of that command.
+
+The addon's files are deleted and everything it changed is reverted:
+go.mod requirements, config.go fields, .env.example sections, Makefile
+targets and docker-compose services. Files edited since the addon
+generated them are kept unless --force is set.
+
+Routers cannot be removed, a project needs one: switch to another with
+'loom add router <name> --force', which removes the current router the
+same way.
+
+Examples:
+  loom remove orm gorm            # Remove GORM
+  loom remove database postgres   # Remove the PostgreSQL driver
+  loom remove auth jwt            # Remove JWT auth
+  loom remove docker              # Remove the Docker files
+  loom remove docker --dry-run    # Preview as a unified diff`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+
+	removeCmd.Flags().BoolVar(&removeForce, "force", false, "Delete files edited since the addon generated them")
+	removeCmd.Flags().BoolVar(&removeDryRun, "dry-run", false, "Show the changes as a diff without writing them")
+	addVerifyFlags(removeCmd.Flags())
+}
+
+func runRemove(cmd *cobra.Command, args []string) error {
+	category := args[0]
+	name := ""
+	if len(args) > 1 {
+		name = args[1]
+	}
+
+	if category == "router" {
+		return fmt.Errorf("a router cannot be removed; switch to another with 'loom add router <name> --force'")
+	}
+	if category != "docker" && name == "" {
+		return fmt.Errorf("usage: loom remove [type] [name]\nExample: loom remove orm gorm")
+	}
+
+	// Detect project
+	projectInfo, err := generator.DetectProject()
+	if err != nil {
+		return fmt.Errorf("error: no valid Loom project detected. %w", err)
+	}
+
+	fmt.Printf("🔍 Project: %s (%s)\n", projectInfo.Name, projectInfo.Architecture)
+
+	manager := addon.NewAddonManager(projectInfo.RootPath, projectInfo.Architecture)
+
+	addonName := mapCategoryToAddon(category, name)
+	if addonName == "" {
+		return fmt.Errorf("unrecognized addon: %s %s", category, name)
+	}
+
+	fmt.Printf("🗑️  Removing %s...\n\n", strings.TrimSpace(category+" "+name))
+
+	if err := manager.UninstallAddon(addonName, removeForce); err != nil {
+		return err
+	}
+	if err := applyChanges(manager, removeDryRun); err != nil {
+		return err
+	}
+	if removeDryRun {
+		fmt.Println("\n💡 Run without --dry-run to apply the changes")
+		return nil
+	}
+
+	if _, err := verifyProject(cmd, projectInfo.RootPath, written); err != nil {
+		return err
+	}
+
+	fmt.Println("\n📝 Next steps:")
+	fmt.Println("   1. Run: go mod tidy")
+	fmt.Println("   2. Remove the code that still uses it, if any")
+	fmt.Println("\n✨ Done! Your project has been updated")
+
+	return nil
+}
@@ -18,6 +18,8 @@ and project architecture orchestrator.`,
 
 // Execute executes the root command
 func Execute() error {
+	// Pack kinds are registered once every built-in command exists
+	registerPackKinds()
 	return rootCmd.Execute()
 }
 
@@ -114,7 +114,7 @@ func runSelfUpdate(cmd *cobra.Command, args []string) error {
 
 	if err := installCmd.Run(); err != nil {
 		return fmt.Errorf("❌ Update failed: %w\n\nTry manually: go install github.com/%s/%s/cmd/loom@%s",
-			repoOwner, repoName, targetVersion)
+			err, repoOwner, repoName, targetVersion)
 	}
 
 	fmt.Printf("\n✅ Successfully updated to %s!\n", targetVersion)
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List and customize the templates used by Loom",
+	Long: `List and customize the templates Loom renders code from.
+
+A template placed in an override directory replaces the embedded
+default of the same name. Directories are searched in this order:
+  1. .loom/templates in the current project
+  2. ~/.config/loom/templates ($XDG_CONFIG_HOME/loom/templates)
+  3. the templates embedded in Loom
+
+Examples:
+  loom templates list
+  loom templates eject components/layered/service.go.tmpl
+  loom templates eject components/modular --user`,
+	Aliases: []string{"tpl"},
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the templates and show which ones are overridden",
+	Args:  cobra.NoArgs,
+	RunE:  runTemplatesList,
+}
+
+var templatesEjectCmd = &cobra.Command{
+	Use:   "eject [name]",
+	Short: "Copy a default template into the override directory",
+	Long: `Copies the embedded default of a template into .loom/templates (or
+~/.config/loom/templates with --user) so it can be customized.
+
+The name is the one shown by 'loom templates list'; the .tmpl suffix may be
+omitted. Passing a directory ejects every template below it.
+
+Examples:
+  loom templates eject components/layered/service.go
+  loom templates eject components/middleware.go.tmpl --user
+  loom templates eject components/modular`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplatesEject,
+}
+
+var (
+	templatesOverridden bool
+	templatesEjectUser  bool
+	templatesEjectForce bool
+)
+
+func init() {
+	rootCmd.AddCommand(templatesCmd)
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesEjectCmd)
+
+	templatesListCmd.Flags().BoolVar(&templatesOverridden, "overridden", false, "Only show overridden templates")
+	templatesEjectCmd.Flags().BoolVar(&templatesEjectUser, "user", false, "Eject into the per-user directory instead of the project")
+	templatesEjectCmd.Flags().BoolVar(&templatesEjectForce, "force", false, "Overwrite existing overrides")
+}
+
+func runTemplatesList(cmd *cobra.Command, args []string) error {
+	infos, err := generator.ListTemplates()
+	if err != nil {
+		return fmt.Errorf("error listing templates: %w", err)
+	}
+
+	overridden := 0
+	for _, info := range infos {
+		if info.Source == generator.TemplateSourceEmbedded {
+			if !templatesOverridden {
+				fmt.Printf("   %s\n", info.Name)
+			}
+			continue
+		}
+		overridden++
+		fmt.Printf("✏️  %s (%s: %s)\n", info.Name, info.Source, info.Path)
+	}
+
+	fmt.Printf("\n📋 %d templates, %d overridden\n", len(infos), overridden)
+	fmt.Printf("   Project overrides: %s\n", generator.ProjectTemplatesDir())
+	if dir := generator.UserTemplatesDir(); dir != "" {
+		fmt.Printf("   User overrides:    %s\n", dir)
+	}
+
+	return nil
+}
+
+func runTemplatesEject(cmd *cobra.Command, args []string) error {
+	dir := generator.ProjectTemplatesDir()
+	if templatesEjectUser {
+		dir = generator.UserTemplatesDir()
+		if dir == "" {
+			return fmt.Errorf("could not determine the user config directory")
+		}
+	}
+
+	files, err := generator.EjectTemplates(args[0], dir, templatesEjectForce)
+	for _, file := range files {
+		fmt.Printf("   ✨ %s\n", file)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ %d template(s) ejected to %s\n", len(files), dir)
+	fmt.Println("💡 Edit them to customize the generated code; delete them to restore the defaults")
+
+	return nil
+}
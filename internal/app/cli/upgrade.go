@@ -23,7 +23,10 @@ The command:
   1. Detects the current project version
   2. Creates an automatic backup (optional)
   3. Applies necessary migrations
-  4. Updates the .loom file with the new version
+  4. Updates the project manifest (.loom/loom.yaml) with the new version
+
+Projects still using the key=value .loom file of older versions get it
+migrated into the manifest, even when they are already up to date.
 
 Examples:
   loom upgrade                    # Upgrade with backup
@@ -72,6 +75,14 @@ func runUpgrade(cmd *cobra.Command, args []string) error {
 	canUpgrade, reason := upg.CanUpgrade()
 	if !canUpgrade {
 		fmt.Println("ℹ️ ", reason)
+
+		migrated, err := upg.MigrateManifest()
+		if err != nil {
+			return fmt.Errorf("error migrating .loom: %w", err)
+		}
+		if migrated {
+			fmt.Println("✅ Project manifest written to .loom/loom.yaml")
+		}
 		return nil
 	}
 
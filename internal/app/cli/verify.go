@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/state"
+	"github.com/geomark27/loom-go/internal/verify"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// addVerifyFlags registers the flags of the verification phase that runs
+// after files are generated
+func addVerifyFlags(flags *pflag.FlagSet) {
+	flags.Bool("verify", true, "Check the project after generating (gofmt, go mod tidy, go build, go vet)")
+	flags.Bool("offline", false, "Verify without the network: parse and type-check instead of tidy/build/vet")
+}
+
+// verifyProject runs the verification phase on the project at root,
+// unless --verify=false. Only the files in generated (paths relative to
+// the working directory) are reformatted. Problems are printed with the
+// template that produced the file and turned into an error. Returns nil
+// when the phase is disabled.
+func verifyProject(cmd *cobra.Command, root string, generated []string) (*verify.Report, error) {
+	enabled, _ := cmd.Flags().GetBool("verify")
+	if !enabled {
+		return nil, nil
+	}
+	offline, _ := cmd.Flags().GetBool("offline")
+
+	opts := verify.Options{Offline: offline, Generated: []string{}}
+	for _, path := range generated {
+		if rel, ok := projectRelative(root, path); ok {
+			opts.Generated = append(opts.Generated, rel)
+		}
+	}
+	if st, err := state.Load(root); err == nil {
+		opts.Templates = st.Templates()
+	}
+
+	fmt.Println("\n🔎 Verifying the project...")
+	report, err := verify.Run(root, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying the project: %w", err)
+	}
+
+	for _, note := range report.Notes {
+		fmt.Printf("   ⚠️  %s\n", note)
+	}
+	for _, file := range report.Formatted {
+		fmt.Printf("   🎨 gofmt: %s\n", file)
+	}
+
+	if !report.OK() {
+		fmt.Printf("❌ %s reported %d problem(s):\n", strings.Join(report.Steps, ", "), len(report.Problems))
+		for _, problem := range report.Problems {
+			fmt.Printf("   %s\n", problem)
+		}
+		cmd.SilenceUsage = true
+		return report, fmt.Errorf("verification failed (the files were written; fix them or rerun with --verify=false)")
+	}
+
+	fmt.Printf("✅ Verified: %s\n", strings.Join(report.Steps, ", "))
+	return report, nil
+}
+
+// projectRelative returns path relative to the project root, and false
+// when it is outside the project
+func projectRelative(root, path string) (string, bool) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// trackedFiles returns the files of the generation state of the project
+// at root, relative to the working directory
+func trackedFiles(root string) []string {
+	st, err := state.Load(root)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for path := range st.Files {
+		files = append(files, filepath.Join(root, filepath.FromSlash(path)))
+	}
+	return files
+}
+
+// tidied reports whether the verification ran go mod tidy
+func tidied(report *verify.Report) bool {
+	return report != nil && slices.Contains(report.Steps, "go mod tidy")
+}
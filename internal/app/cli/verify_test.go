@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// TestVerifyProjectFormatsWrittenFiles checks that only the files written
+// by the command are reformatted, not every file Loom ever generated
+func TestVerifyProjectFormatsWrittenFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                               "module example.com/shop\n\ngo 1.23\n",
+		"internal/app/models/product.go":       "package models\n\ntype Product struct {\nID int\n}\n",
+		"internal/app/services/tag_service.go": "package services\n\ntype TagService struct {\nID int\n}\n",
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := &cobra.Command{}
+	addVerifyFlags(cmd.Flags())
+	if err := cmd.Flags().Set("offline", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := verifyProject(cmd, root, []string{filepath.Join(root, "internal/app/models/product.go")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(report.Formatted, []string{"internal/app/models/product.go"}) {
+		t.Errorf("Formatted = %v, want only the written file", report.Formatted)
+	}
+	content, err := os.ReadFile(filepath.Join(root, "internal/app/services/tag_service.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != files["internal/app/services/tag_service.go"] {
+		t.Errorf("untouched file reformatted:\n%s", content)
+	}
+}
+
+func TestProjectRelative(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{path: filepath.Join(root, "internal", "app", "models", "product.go"), want: "internal/app/models/product.go", ok: true},
+		{path: filepath.Join(root, "go.mod"), want: "go.mod", ok: true},
+		{path: filepath.Join(filepath.Dir(root), "other", "main.go")},
+		{path: filepath.Join(root, "..", "..x", "main.go")},
+	}
+	for _, tt := range tests {
+		got, ok := projectRelative(root, tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("projectRelative(%s) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
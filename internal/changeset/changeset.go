@@ -0,0 +1,509 @@
+// Package changeset stages file writes in memory and applies them as a
+// single unit.
+//
+// Generators and addons read, write and delete project files through a
+// Set instead of the disk. Nothing touches the project until Commit: every
+// staged Go file is parsed first, then the files are written to
+// temporary files next to their targets and renamed into place, and the
+// deleted files are moved aside. When any step fails, the files already
+// replaced or deleted are restored and the new files and directories
+// removed, so the project is never left half-modified.
+// A dry run prints the staged set as a unified diff instead.
+package changeset
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kind tells whether a change creates or updates a file
+type Kind string
+
+const (
+	Create Kind = "create"
+	Update Kind = "update"
+	Delete Kind = "delete"
+)
+
+// Change is a staged write of a single file
+type Change struct {
+	Path string
+	Kind Kind
+	Old  []byte // content on disk (nil for created files)
+	New  []byte // staged content (nil for deleted files)
+}
+
+// entry is the staged state of a path
+type entry struct {
+	path    string
+	existed bool
+	old     []byte
+	new     []byte
+	mode    fs.FileMode
+	// generated is the content a generator produced for the file, before
+	// any merge with the file on disk
+	generated []byte
+	// conflicted files hold merge conflict markers and are not parsed
+	conflicted bool
+}
+
+// Set is a set of staged file writes. Paths are used as given (relative
+// to the working directory or absolute); the same file reached through
+// different spellings of its path is tracked once.
+type Set struct {
+	entries map[string]*entry
+	bases   Bases
+}
+
+// Bases gives the content generated last time for a file, as opposed to
+// what the file holds now. The generation state of a project is one.
+type Bases interface {
+	Base(path string) ([]byte, bool)
+}
+
+// New creates an empty change set
+func New() *Set {
+	return &Set{entries: make(map[string]*entry)}
+}
+
+// UseBases sets where WriteGenerated and Merge find the previously
+// generated content of files
+func (s *Set) UseBases(bases Bases) {
+	s.bases = bases
+}
+
+// key normalizes a path
+func key(path string) string {
+	return filepath.Clean(path)
+}
+
+// load returns the entry of a path, reading the file on first access
+func (s *Set) load(path string) (*entry, error) {
+	if e, ok := s.entries[key(path)]; ok {
+		return e, nil
+	}
+
+	e := &entry{path: path, mode: 0644}
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s is a directory", path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		e.existed = true
+		e.old = content
+		e.new = content
+		e.mode = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	s.entries[key(path)] = e
+	return e, nil
+}
+
+// ReadFile returns the staged content of a file, or its content on disk
+func (s *Set) ReadFile(path string) ([]byte, error) {
+	e, err := s.load(path)
+	if err != nil {
+		return nil, err
+	}
+	if e.new == nil {
+		return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+	}
+	return e.new, nil
+}
+
+// WriteFile stages the content of a file
+func (s *Set) WriteFile(path string, content []byte) error {
+	e, err := s.load(path)
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	e.new = content
+	e.conflicted = false
+	return nil
+}
+
+// WriteConflict stages content holding merge conflict markers. It is
+// written as is: Validate does not parse it.
+func (s *Set) WriteConflict(path string, content []byte) error {
+	if err := s.WriteFile(path, content); err != nil {
+		return err
+	}
+	s.entries[key(path)].conflicted = true
+	return nil
+}
+
+// WriteGenerated stages generated content. When the file was generated
+// before and edited since, the edits are kept: the previous generated
+// content, the file and the new content are merged three ways (see
+// Merge). Reports whether the merge left conflicts.
+func (s *Set) WriteGenerated(path string, content []byte) (bool, error) {
+	e, err := s.load(path)
+	if err != nil {
+		return false, err
+	}
+	e.generated = content
+
+	if base, ok := s.base(e); ok && e.existed && string(base) != string(e.old) {
+		return s.Merge(path)
+	}
+	return false, s.WriteFile(path, content)
+}
+
+// Merge stages the file on disk merged with the content generated for
+// it. When the previous generated content is known the merge is three
+// way, so only regions both sides changed conflict; otherwise every
+// region where they differ is a conflict. Reports whether the merge left
+// conflicts.
+func (s *Set) Merge(path string) (bool, error) {
+	e, ok := s.entries[key(path)]
+	if !ok || e.generated == nil {
+		return false, fmt.Errorf("%s: no generated content to merge", path)
+	}
+	if !e.existed {
+		return false, s.WriteFile(path, e.generated)
+	}
+
+	var merged []byte
+	var conflicts bool
+	if base, ok := s.base(e); ok {
+		merged, conflicts = Merge3("current", "generated", base, e.old, e.generated)
+	} else {
+		merged, conflicts = MergeMarkers("current", "generated", e.old, e.generated)
+	}
+	if conflicts {
+		return true, s.WriteConflict(path, merged)
+	}
+	return false, s.WriteFile(path, merged)
+}
+
+// base returns the previous generated content of an entry
+func (s *Set) base(e *entry) ([]byte, bool) {
+	if s.bases == nil {
+		return nil, false
+	}
+	return s.bases.Base(e.path)
+}
+
+// Generated returns the content staged with WriteGenerated, by path
+func (s *Set) Generated() map[string][]byte {
+	generated := make(map[string][]byte)
+	for _, e := range s.entries {
+		if e.generated != nil && e.new != nil {
+			generated[e.path] = e.generated
+		}
+	}
+	return generated
+}
+
+// Conflicts returns the files staged (or committed) with conflict
+// markers, sorted
+func (s *Set) Conflicts() []string {
+	var paths []string
+	for _, e := range s.entries {
+		if e.conflicted && e.new != nil {
+			paths = append(paths, e.path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Remove stages the deletion of a file. Removing a missing file is a
+// no-op.
+func (s *Set) Remove(path string) error {
+	e, err := s.load(path)
+	if err != nil {
+		return err
+	}
+	e.new = nil
+	e.generated = nil
+	e.conflicted = false
+	return nil
+}
+
+// Exists reports whether a file exists, on disk or staged
+func (s *Set) Exists(path string) bool {
+	e, err := s.load(path)
+	return err == nil && e.new != nil
+}
+
+// IsDir reports whether a directory exists on disk or will be created for
+// a staged file
+func (s *Set) IsDir(path string) bool {
+	if info, err := os.Stat(path); err == nil {
+		return info.IsDir()
+	}
+	prefix := key(path) + string(filepath.Separator)
+	for k, e := range s.entries {
+		if e.new != nil && strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Changes returns the staged writes that change a file, sorted by path
+func (s *Set) Changes() []Change {
+	var changes []Change
+	for _, e := range s.entries {
+		switch {
+		case e.new == nil && e.existed:
+			changes = append(changes, Change{Path: e.path, Kind: Delete, Old: e.old})
+		case e.new == nil, e.existed && string(e.old) == string(e.new):
+			continue
+		case e.existed:
+			changes = append(changes, Change{Path: e.path, Kind: Update, Old: e.old, New: e.new})
+		default:
+			changes = append(changes, Change{Path: e.path, Kind: Create, New: e.new})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// Empty reports whether nothing would be written
+func (s *Set) Empty() bool {
+	return len(s.Changes()) == 0
+}
+
+// Discard drops the staged write of a file, keeping what is on disk
+func (s *Set) Discard(path string) {
+	if e, ok := s.entries[key(path)]; ok {
+		if e.existed {
+			e.new = e.old
+		} else {
+			e.new = nil
+		}
+		e.generated = nil
+		e.conflicted = false
+	}
+}
+
+// Validate checks the staged Go files parse (except conflicted ones)
+func (s *Set) Validate() error {
+	fset := token.NewFileSet()
+	var errs []error
+	for _, change := range s.Changes() {
+		if change.Kind == Delete || !strings.HasSuffix(change.Path, ".go") || s.entries[key(change.Path)].conflicted {
+			continue
+		}
+		if _, err := parser.ParseFile(fset, change.Path, change.New, parser.AllErrors); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Commit validates the staged writes and applies them. On error the
+// project is restored to its previous state.
+func (s *Set) Commit() error {
+	if err := s.Validate(); err != nil {
+		return fmt.Errorf("invalid generated code, nothing was written:\n%w", err)
+	}
+
+	tx := &transaction{}
+	if err := tx.apply(s.Changes(), s.entries); err != nil {
+		if rollbackErr := tx.rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return fmt.Errorf("%w (all changes were rolled back)", err)
+	}
+
+	tx.cleanup()
+
+	// The disk now holds the staged content
+	for _, e := range s.entries {
+		e.existed = e.new != nil
+		e.old = e.new
+	}
+	return nil
+}
+
+// transaction tracks what a commit did so it can be undone
+type transaction struct {
+	dirs     []string    // directories created, parents first
+	temps    []string    // temporary files not renamed yet
+	replaced []*entry    // files written in place
+	removed  []movedFile // deleted files, moved aside until the end
+}
+
+// movedFile is a deleted file and where it was moved to
+type movedFile struct {
+	path, backup string
+}
+
+// apply writes every change to a temporary file, then renames them over
+// their targets
+func (tx *transaction) apply(changes []Change, entries map[string]*entry) error {
+	staged := make([]*entry, 0, len(changes))
+	temps := make(map[*entry]string, len(changes))
+
+	var deleted []*entry
+
+	for _, change := range changes {
+		e := entries[key(change.Path)]
+		if change.Kind == Delete {
+			deleted = append(deleted, e)
+			continue
+		}
+		if err := tx.mkdirAll(filepath.Dir(e.path)); err != nil {
+			return err
+		}
+
+		tmp, err := writeTemp(e)
+		if err != nil {
+			return fmt.Errorf("error writing %s: %w", e.path, err)
+		}
+		tx.temps = append(tx.temps, tmp)
+		temps[e] = tmp
+		staged = append(staged, e)
+	}
+
+	for _, e := range staged {
+		if err := os.Rename(temps[e], e.path); err != nil {
+			return fmt.Errorf("error writing %s: %w", e.path, err)
+		}
+		tx.replaced = append(tx.replaced, e)
+		tx.temps = removeString(tx.temps, temps[e])
+	}
+
+	for _, e := range deleted {
+		backup, err := moveAside(e.path)
+		if err != nil {
+			return fmt.Errorf("error removing %s: %w", e.path, err)
+		}
+		tx.removed = append(tx.removed, movedFile{e.path, backup})
+	}
+
+	return nil
+}
+
+// cleanup drops the deleted files once the commit succeeded
+func (tx *transaction) cleanup() {
+	for _, moved := range tx.removed {
+		os.Remove(moved.backup)
+	}
+}
+
+// rollback restores the replaced files and removes what the commit created
+func (tx *transaction) rollback() error {
+	var errs []error
+	for i := len(tx.removed) - 1; i >= 0; i-- {
+		if err := os.Rename(tx.removed[i].backup, tx.removed[i].path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, tmp := range tx.temps {
+		if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	for i := len(tx.replaced) - 1; i >= 0; i-- {
+		e := tx.replaced[i]
+		var err error
+		if e.existed {
+			err = os.WriteFile(e.path, e.old, e.mode)
+		} else {
+			err = os.Remove(e.path)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	for i := len(tx.dirs) - 1; i >= 0; i-- {
+		if err := os.Remove(tx.dirs[i]); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mkdirAll creates a directory and its missing parents, remembering the
+// ones it created
+func (tx *transaction) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append(missing, d)
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], 0755); err != nil && !os.IsExist(err) {
+			return fmt.Errorf("error creating directory %s: %w", missing[i], err)
+		}
+		tx.dirs = append(tx.dirs, missing[i])
+	}
+	return nil
+}
+
+// writeTemp writes the staged content of an entry to a temporary file in
+// the directory of its target, so the rename does not cross filesystems
+func writeTemp(e *entry) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(e.path), "."+filepath.Base(e.path)+".loom-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(e.new)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), e.mode)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// moveAside renames a file to a hidden temporary name in its directory,
+// so a failed commit can put it back
+func moveAside(path string) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".loom-*")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	if err := os.Rename(path, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// removeString removes the first occurrence of s from list
+func removeString(list []string, s string) []string {
+	for i, item := range list {
+		if item == s {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
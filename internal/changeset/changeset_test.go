@@ -0,0 +1,279 @@
+package changeset
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitWritesStagedFiles(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(existing, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set := New()
+	created := filepath.Join(dir, "internal", "app", "app.go")
+	if err := set.WriteFile(created, []byte("package app\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.WriteFile(existing, []byte("package main\n\nfunc main() {}\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is written before the commit
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Fatalf("staged file written before commit: %v", err)
+	}
+	if content, _ := set.ReadFile(existing); !strings.Contains(string(content), "func main") {
+		t.Fatalf("ReadFile does not see the staged content: %q", content)
+	}
+
+	if err := set.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		created:  "package app\n",
+		existing: "package main\n\nfunc main() {}\n",
+	} {
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if !set.Empty() {
+		t.Errorf("changes left after commit: %v", set.Changes())
+	}
+}
+
+func TestCommitRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "go.mod")
+	if err := os.WriteFile(existing, []byte("module shop\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set := New()
+	if err := set.WriteFile(existing, []byte("module shop\n\nrequire gorm.io/gorm v1.25.5\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.WriteFile(filepath.Join(dir, "internal", "database", "database.go"), []byte("package database\n")); err != nil {
+		t.Fatal(err)
+	}
+	// The target of this write becomes a directory, so its rename fails
+	blocked := filepath.Join(dir, "zz", "file.txt")
+	if err := set.WriteFile(blocked, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(blocked, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := set.Commit(); err == nil {
+		t.Fatal("commit succeeded, want an error")
+	}
+
+	if got, _ := os.ReadFile(existing); string(got) != "module shop\n" {
+		t.Errorf("go.mod not restored: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "internal")); !os.IsNotExist(err) {
+		t.Errorf("created directory not removed: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "zz"))
+	for _, entry := range entries {
+		if entry.Name() != "file.txt" {
+			t.Errorf("temporary file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestCommitRejectsInvalidGo(t *testing.T) {
+	dir := t.TempDir()
+	set := New()
+	if err := set.WriteFile(filepath.Join(dir, "ok.txt"), []byte("ok\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.WriteFile(filepath.Join(dir, "broken.go"), []byte("package broken\nfunc {\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := set.Commit(); err == nil {
+		t.Fatal("commit succeeded, want a parse error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ok.txt")); !os.IsNotExist(err) {
+		t.Errorf("file written despite the invalid change: %v", err)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	new := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	want := `--- a/x.txt
++++ b/x.txt
+@@ -1,7 +1,7 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	if got := Unified("a/x.txt", "b/x.txt", []byte(old), []byte(new)); got != want {
+		t.Errorf("Unified =\n%s\nwant\n%s", got, want)
+	}
+
+	created := Unified("/dev/null", "b/y.txt", nil, []byte("one\ntwo"))
+	wantCreated := "--- /dev/null\n+++ b/y.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n\\ No newline at end of file\n"
+	if created != wantCreated {
+		t.Errorf("Unified (create) =\n%s\nwant\n%s", created, wantCreated)
+	}
+
+	if got := Unified("a", "b", []byte("same\n"), []byte("same\n")); got != "" {
+		t.Errorf("Unified of equal texts = %q", got)
+	}
+}
+
+func TestColorize(t *testing.T) {
+	diff := Unified("a/q.sql", "b/q.sql", []byte("-- old\nselect 1;\n"), []byte("select 1;\n"))
+	want := "\x1b[1m--- a/q.sql\x1b[0m\n" +
+		"\x1b[1m+++ b/q.sql\x1b[0m\n" +
+		"\x1b[36m@@ -1,2 +1 @@\x1b[0m\n" +
+		"\x1b[31m--- old\x1b[0m\n" +
+		" select 1;\n"
+	if got := Colorize(diff); got != want {
+		t.Errorf("Colorize =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestMergeMarkers(t *testing.T) {
+	ours := "package a\n\n// edited by hand\nfunc A() {}\n"
+	theirs := "package a\n\nfunc A() {}\n\nfunc B() {}\n"
+
+	want := "package a\n\n" +
+		"<<<<<<< current\n// edited by hand\n=======\n>>>>>>> generated\n" +
+		"func A() {}\n" +
+		"<<<<<<< current\n=======\n\nfunc B() {}\n>>>>>>> generated\n"
+	got, conflicts := MergeMarkers("current", "generated", []byte(ours), []byte(theirs))
+	if !conflicts || string(got) != want {
+		t.Errorf("MergeMarkers = %v\n%s\nwant\n%s", conflicts, got, want)
+	}
+
+	if _, conflicts := MergeMarkers("a", "b", []byte(ours), []byte(ours)); conflicts {
+		t.Error("MergeMarkers of equal texts reports conflicts")
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := "package a\n\nfunc A() {}\n\nfunc B() {}\n\nfunc C() {}\n"
+	// The user changed A and B; the generator changed B and C
+	ours := "package a\n\nfunc A() { mine() }\n\nfunc B() { mine() }\n\nfunc C() {}\n"
+	theirs := "package a\n\nfunc A() {}\n\nfunc B() { new() }\n\nfunc C() { new() }\n"
+
+	want := "package a\n\nfunc A() { mine() }\n\n" +
+		"<<<<<<< current\nfunc B() { mine() }\n=======\nfunc B() { new() }\n>>>>>>> generated\n" +
+		"\nfunc C() { new() }\n"
+	got, conflicts := Merge3("current", "generated", []byte(base), []byte(ours), []byte(theirs))
+	if !conflicts || string(got) != want {
+		t.Errorf("Merge3 = %v\n%s\nwant\n%s", conflicts, got, want)
+	}
+
+	// Edits to different regions merge cleanly
+	theirs = "package a\n\nfunc A() {}\n\nfunc B() {}\n\nfunc C() { new() }\n"
+	want = "package a\n\nfunc A() { mine() }\n\nfunc B() { mine() }\n\nfunc C() { new() }\n"
+	got, conflicts = Merge3("current", "generated", []byte(base), []byte(ours), []byte(theirs))
+	if conflicts || string(got) != want {
+		t.Errorf("Merge3 = %v\n%s\nwant\n%s", conflicts, got, want)
+	}
+}
+
+// bases is a fixed set of previously generated contents
+type bases map[string]string
+
+func (b bases) Base(path string) ([]byte, bool) {
+	content, ok := b[path]
+	return []byte(content), ok
+}
+
+func TestWriteGeneratedKeepsEdits(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("one\nedited\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set := New()
+	set.UseBases(bases{path: "one\ntwo\nthree\n"})
+	conflicts, err := set.WriteGenerated(path, []byte("one\ntwo\nthree\nfour\n"))
+	if err != nil || conflicts {
+		t.Fatalf("WriteGenerated = %v, %v", conflicts, err)
+	}
+	if got, _ := set.ReadFile(path); string(got) != "one\nedited\nthree\nfour\n" {
+		t.Errorf("staged %q", got)
+	}
+
+	// A file without a known base is overwritten
+	other := filepath.Join(dir, "other.txt")
+	if err := os.WriteFile(other, []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.WriteGenerated(other, []byte("generated\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := set.ReadFile(other); string(got) != "generated\n" {
+		t.Errorf("staged %q", got)
+	}
+}
+
+func TestCommitDeletes(t *testing.T) {
+	dir := t.TempDir()
+	doomed := filepath.Join(dir, "doomed.go")
+	if err := os.WriteFile(doomed, []byte("package doomed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed commit puts the deleted file back
+	set := New()
+	if err := set.Remove(doomed); err != nil {
+		t.Fatal(err)
+	}
+	blocked := filepath.Join(dir, "blocked.txt")
+	if err := set.WriteFile(blocked, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(blocked, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if changes := set.Changes(); len(changes) != 2 || changes[1].Kind != Delete {
+		t.Fatalf("changes = %v", changes)
+	}
+	if err := set.Commit(); err == nil {
+		t.Fatal("commit succeeded, want an error")
+	}
+	if _, err := os.Stat(doomed); err != nil {
+		t.Fatalf("deleted file not restored: %v", err)
+	}
+
+	set = New()
+	if err := set.Remove(doomed); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.Name() != "blocked.txt" {
+			t.Errorf("left behind: %s", entry.Name())
+		}
+	}
+}
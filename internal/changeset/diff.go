@@ -0,0 +1,264 @@
+package changeset
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines around each hunk
+const contextLines = 3
+
+// Diff writes the staged changes as a unified diff, colored with
+// Colorize when color is set
+func (s *Set) Diff(w io.Writer, color bool) error {
+	for _, change := range s.Changes() {
+		diff := change.Unified()
+		if color {
+			diff = Colorize(diff)
+		}
+		if _, err := io.WriteString(w, diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unified returns the change as a unified diff
+func (c Change) Unified() string {
+	name := filepath.ToSlash(c.Path)
+	from, to := "a/"+name, "b/"+name
+	switch c.Kind {
+	case Create:
+		from = "/dev/null"
+	case Delete:
+		to = "/dev/null"
+	}
+	return Unified(from, to, c.Old, c.New)
+}
+
+// op is a line of an edit script
+type op struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Unified returns the unified diff between two texts ("" when equal)
+func Unified(fromName, toName string, old, new []byte) string {
+	a, b := splitLines(string(old)), splitLines(string(new))
+	ops := editScript(a, b)
+
+	var out strings.Builder
+	for _, h := range hunks(ops) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		out.WriteString(h)
+	}
+	return out.String()
+}
+
+// splitLines splits text into lines, each one with its newline (except a
+// last line without one)
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript computes the shortest edit script turning a into b, from the
+// longest common subsequence of their lines
+func editScript(a, b []string) []op {
+	// Common prefix and suffix are kept out of the quadratic table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the LCS of ma[i:] and mb[j:]
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{' ', line})
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			ops = append(ops, op{' ', ma[i]})
+			i++
+			j++
+		case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+			// Deletions come before insertions, like diff prints them
+			ops = append(ops, op{'-', ma[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', mb[j]})
+			j++
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{' ', line})
+	}
+	return ops
+}
+
+// hunks groups an edit script into unified diff hunks
+func hunks(ops []op) []string {
+	var result []string
+
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+				continue
+			}
+			if i-end >= 2*contextLines {
+				break
+			}
+		}
+
+		from := max(start-contextLines, 0)
+		to := min(end+contextLines, len(ops))
+
+		// Line numbers of the hunk in the old and new text
+		oldLine, newLine := 1, 1
+		for _, o := range ops[:from] {
+			if o.kind != '+' {
+				oldLine++
+			}
+			if o.kind != '-' {
+				newLine++
+			}
+		}
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for _, o := range ops[from:to] {
+			body.WriteByte(o.kind)
+			body.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+			if o.kind != '+' {
+				oldCount++
+			}
+			if o.kind != '-' {
+				newCount++
+			}
+		}
+
+		result = append(result, fmt.Sprintf("@@ -%s +%s @@\n%s",
+			hunkRange(oldLine, oldCount), hunkRange(newLine, newCount), body.String()))
+		start = to
+	}
+
+	return result
+}
+
+// hunkRange formats the start,count of a hunk header
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range names the line before it
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// ANSI colors of the diff lines
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// Colorize colors a unified diff for a terminal: file headers in bold,
+// hunk headers in cyan, removed lines in red and added lines in green
+func Colorize(diff string) string {
+	var out strings.Builder
+	oldLeft, newLeft := 0, 0 // lines left in the current hunk
+
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		text := strings.TrimSuffix(line, "\n")
+		color := ""
+		switch {
+		case oldLeft > 0 || newLeft > 0:
+			// Inside a hunk "--- x" is a removed line, not a header
+			switch {
+			case strings.HasPrefix(text, "-"):
+				color = colorRed
+				oldLeft--
+			case strings.HasPrefix(text, "+"):
+				color = colorGreen
+				newLeft--
+			case strings.HasPrefix(text, " "):
+				oldLeft--
+				newLeft--
+			}
+		case strings.HasPrefix(text, "@@"):
+			color = colorCyan
+			oldLeft, newLeft = hunkCounts(text)
+		case strings.HasPrefix(text, "--- "), strings.HasPrefix(text, "+++ "):
+			color = colorBold
+		}
+		if color == "" {
+			out.WriteString(line)
+			continue
+		}
+		out.WriteString(color + text + colorReset + line[len(text):])
+	}
+	return out.String()
+}
+
+// hunkCounts returns the old and new line counts of a hunk header
+// ("@@ -1,7 +1,8 @@")
+func hunkCounts(header string) (int, int) {
+	var counts [2]int
+	fields := strings.Fields(header)
+	for i, field := range fields[1:min(3, len(fields))] {
+		counts[i] = 1
+		if _, count, ok := strings.Cut(field, ","); ok {
+			counts[i], _ = strconv.Atoi(count)
+		}
+	}
+	return counts[0], counts[1]
+}
@@ -0,0 +1,144 @@
+package changeset
+
+import "strings"
+
+// Conflict markers written around the regions two versions disagree on
+const (
+	markerStart = "<<<<<<< "
+	markerSep   = "=======\n"
+	markerEnd   = ">>>>>>> "
+)
+
+// MergeMarkers combines two versions of a file: the lines they share are
+// kept, and every region where they differ is wrapped in conflict markers
+// labelled with the given names, for the user to resolve. Returns the
+// merged content and whether it has conflicts.
+func MergeMarkers(oursName, theirsName string, ours, theirs []byte) ([]byte, bool) {
+	ops := editScript(splitLines(string(ours)), splitLines(string(theirs)))
+
+	var out strings.Builder
+	conflicts := false
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			out.WriteString(ops[i].line)
+			i++
+			continue
+		}
+
+		// A run of removed and added lines is one conflict
+		var removed, added []string
+		for ; i < len(ops) && ops[i].kind != ' '; i++ {
+			if ops[i].kind == '-' {
+				removed = append(removed, ops[i].line)
+			} else {
+				added = append(added, ops[i].line)
+			}
+		}
+		writeConflict(&out, oursName, theirsName, removed, added)
+		conflicts = true
+	}
+
+	return []byte(out.String()), conflicts
+}
+
+// writeConflict writes a conflict between two sets of lines
+func writeConflict(out *strings.Builder, oursName, theirsName string, ours, theirs []string) {
+	out.WriteString(markerStart + oursName + "\n")
+	writeLines(out, ours)
+	out.WriteString(markerSep)
+	writeLines(out, theirs)
+	out.WriteString(markerEnd + theirsName + "\n")
+}
+
+// writeLines writes lines, ending the last one with a newline so the
+// marker after it starts on its own line
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n")
+		}
+	}
+}
+
+// Merge3 merges the changes two versions made to a common base, like
+// diff3: a region changed on one side only takes that side, a region both
+// sides changed the same way is kept once, and a region they changed
+// differently is a conflict. Returns the merged content and whether it
+// has conflicts.
+func Merge3(oursName, theirsName string, base, ours, theirs []byte) ([]byte, bool) {
+	o, a, b := splitLines(string(base)), splitLines(string(ours)), splitLines(string(theirs))
+	matchA, matchB := matches(o, a), matches(o, b)
+
+	var out strings.Builder
+	conflicts := false
+	i, ia, ib := 0, 0, 0
+	for {
+		// Lines unchanged on both sides
+		for i < len(o) && matchA[i] == ia && matchB[i] == ib {
+			out.WriteString(o[i])
+			i, ia, ib = i+1, ia+1, ib+1
+		}
+		if i == len(o) && ia == len(a) && ib == len(b) {
+			break
+		}
+
+		// The changed region ends at the next base line both sides kept
+		j := i
+		for j < len(o) && (matchA[j] < 0 || matchB[j] < 0) {
+			j++
+		}
+		ja, jb := len(a), len(b)
+		if j < len(o) {
+			ja, jb = matchA[j], matchB[j]
+		}
+
+		region, regionA, regionB := o[i:j], a[ia:ja], b[ib:jb]
+		switch {
+		case equalLines(regionA, region):
+			out.WriteString(strings.Join(regionB, ""))
+		case equalLines(regionB, region), equalLines(regionA, regionB):
+			out.WriteString(strings.Join(regionA, ""))
+		default:
+			writeConflict(&out, oursName, theirsName, regionA, regionB)
+			conflicts = true
+		}
+		i, ia, ib = j, ja, jb
+	}
+
+	return []byte(out.String()), conflicts
+}
+
+// matches maps every line of base to the line of other it is kept as, or
+// to -1 when other removed it
+func matches(base, other []string) []int {
+	match := make([]int, len(base))
+	i, j := 0, 0
+	for _, o := range editScript(base, other) {
+		switch o.kind {
+		case ' ':
+			match[i] = j
+			i++
+			j++
+		case '-':
+			match[i] = -1
+			i++
+		default:
+			j++
+		}
+	}
+	return match
+}
+
+// equalLines reports whether two runs of lines are the same
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,420 @@
+package autodiff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// SchemaChanges are the statements of a migration bringing the database
+// schema in line with the models, and the changes that need a hand
+type SchemaChanges struct {
+	Up       []string
+	Down     []string // reverts Up, in the order it runs
+	Warnings []string
+}
+
+// Empty reports whether the schema already matches the models
+func (c *SchemaChanges) Empty() bool {
+	return len(c.Up) == 0 && len(c.Warnings) == 0
+}
+
+// DiffSchema compares the tables of models (parsed from their GORM tags)
+// with the schema of the database: missing tables, added and dropped
+// columns, type changes, indexes and foreign keys. Only the tables of
+// models are looked at, and only indexes named like GORM names them
+// (idx_*) are dropped.
+func DiffSchema(db *gorm.DB, models []interface{}) (*SchemaChanges, error) {
+	d := &differ{db: db, dialect: db.Dialector.Name()}
+	for _, model := range models {
+		if err := d.diffModel(model); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := &SchemaChanges{Up: d.up, Warnings: d.warnings}
+	for i := len(d.down) - 1; i >= 0; i-- {
+		changes.Down = append(changes.Down, d.down[i]...)
+	}
+	return changes, nil
+}
+
+// differ accumulates the statements of a diff
+type differ struct {
+	db       *gorm.DB
+	dialect  string
+	up       []string
+	down     [][]string // reverts of each change, reversed at the end
+	warnings []string
+}
+
+// change records statements and the ones reverting them
+func (d *differ) change(up, down []string) {
+	d.up = append(d.up, up...)
+	d.down = append(d.down, down)
+}
+
+func (d *differ) warn(format string, args ...interface{}) {
+	d.warnings = append(d.warnings, fmt.Sprintf(format, args...))
+}
+
+func (d *differ) diffModel(model interface{}) error {
+	stmt := &gorm.Statement{DB: d.db}
+	if err := stmt.Parse(model); err != nil {
+		return fmt.Errorf("failed to parse %T: %w", model, err)
+	}
+	table := stmt.Schema.Table
+	migrator := d.db.Migrator()
+
+	if !migrator.HasTable(model) {
+		up, err := d.capture(func(tx *gorm.DB) error { return tx.Migrator().CreateTable(model) })
+		if err != nil {
+			return err
+		}
+		down, err := d.sql("DROP TABLE ?", clause.Table{Name: table})
+		if err != nil {
+			return err
+		}
+		d.change(up, down)
+		return nil
+	}
+
+	columnTypes, err := migrator.ColumnTypes(model)
+	if err != nil {
+		return fmt.Errorf("failed to read the columns of %s: %w", table, err)
+	}
+	live := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, column := range columnTypes {
+		live[strings.ToLower(column.Name())] = column
+	}
+
+	// Added columns and type changes
+	for _, dbName := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[dbName]
+		if field.IgnoreMigration {
+			continue
+		}
+
+		column, exists := live[strings.ToLower(dbName)]
+		if !exists {
+			up, err := d.capture(func(tx *gorm.DB) error { return tx.Migrator().AddColumn(model, field.Name) })
+			if err != nil {
+				return err
+			}
+			down, err := d.sql("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: dbName})
+			if err != nil {
+				return err
+			}
+			d.change(up, down)
+			continue
+		}
+
+		if from, to, changed := d.typeChange(field, column); changed {
+			if err := d.alterColumn(table, dbName, from, to); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Indexes of the models missing from the database, and GORM indexes
+	// the models no longer declare
+	modelIndexes := make(map[string]bool)
+	var indexNames []string
+	for _, idx := range stmt.Schema.ParseIndexes() {
+		modelIndexes[idx.Name] = true
+		indexNames = append(indexNames, idx.Name)
+	}
+	sort.Strings(indexNames)
+
+	liveIndexes, err := d.indexes(model, table)
+	if err != nil {
+		return err
+	}
+	for _, idx := range liveIndexes {
+		if modelIndexes[idx.name] || !strings.HasPrefix(idx.name, "idx_") {
+			continue
+		}
+		up, err := d.capture(func(tx *gorm.DB) error { return tx.Migrator().DropIndex(model, idx.name) })
+		if err != nil {
+			return err
+		}
+		d.change(up, []string{idx.definition})
+	}
+
+	for _, name := range indexNames {
+		if migrator.HasIndex(model, name) {
+			continue
+		}
+		up, err := d.capture(func(tx *gorm.DB) error { return tx.Migrator().CreateIndex(model, name) })
+		if err != nil {
+			return err
+		}
+		down, err := d.capture(func(tx *gorm.DB) error { return tx.Migrator().DropIndex(model, name) })
+		if err != nil {
+			return err
+		}
+		d.change(up, down)
+	}
+
+	// Foreign keys of the model's relations
+	if err := d.foreignKeys(model, stmt); err != nil {
+		return err
+	}
+
+	// Dropped columns go last, once their indexes are gone
+	for _, column := range columnTypes {
+		if _, ok := stmt.Schema.FieldsByDBName[column.Name()]; ok {
+			continue
+		}
+		up, err := d.sql("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: column.Name()})
+		if err != nil {
+			return err
+		}
+		down, err := d.sql("ALTER TABLE ? ADD ? ?", clause.Table{Name: table}, clause.Column{Name: column.Name()}, clause.Expr{SQL: columnType(column)})
+		if err != nil {
+			return err
+		}
+		d.change(up, down)
+	}
+	return nil
+}
+
+// typeChange reports whether the type of a column differs from the one
+// of its field, the way GORM's AutoMigrate compares them
+func (d *differ) typeChange(field *schema.Field, column gorm.ColumnType) (from, to string, changed bool) {
+	if field.PrimaryKey {
+		return "", "", false
+	}
+
+	from = columnType(column)
+	to = strings.TrimSpace(d.db.Dialector.DataTypeOf(field))
+	want := strings.ToLower(to)
+	got := strings.ToLower(column.DatabaseTypeName())
+
+	if !strings.HasPrefix(want, got) {
+		for _, alias := range d.db.Migrator().GetTypeAliases(got) {
+			if strings.HasPrefix(want, alias) {
+				return from, to, sizeChanged(field, column)
+			}
+		}
+		return from, to, true
+	}
+	return from, to, sizeChanged(field, column)
+}
+
+// sizeChanged reports whether both the field and the column have a size,
+// and they differ
+func sizeChanged(field *schema.Field, column gorm.ColumnType) bool {
+	length, ok := column.Length()
+	return ok && length > 0 && field.Size > 0 && length != int64(field.Size)
+}
+
+// columnType returns the full type of a database column ("varchar(64)")
+func columnType(column gorm.ColumnType) string {
+	if full, ok := column.ColumnType(); ok && full != "" {
+		return full
+	}
+	return column.DatabaseTypeName()
+}
+
+// alterColumn changes the type of a column. SQLite cannot alter columns:
+// the table has to be rebuilt by hand.
+func (d *differ) alterColumn(table, column, from, to string) error {
+	var statement string
+	switch d.dialect {
+	case "postgres":
+		statement = "ALTER TABLE ? ALTER COLUMN ? TYPE ?"
+	case "mysql":
+		statement = "ALTER TABLE ? MODIFY COLUMN ? ?"
+	case "sqlserver":
+		statement = "ALTER TABLE ? ALTER COLUMN ? ?"
+	default:
+		d.warn("%s.%s changed from %s to %s: %s cannot alter columns, rebuild the table by hand", table, column, from, to, d.db.Dialector.Name())
+		return nil
+	}
+
+	up, err := d.sql(statement, clause.Table{Name: table}, clause.Column{Name: column}, clause.Expr{SQL: to})
+	if err != nil {
+		return err
+	}
+	down, err := d.sql(statement, clause.Table{Name: table}, clause.Column{Name: column}, clause.Expr{SQL: from})
+	if err != nil {
+		return err
+	}
+	d.change(up, down)
+	return nil
+}
+
+// liveIndex is an index of the database
+type liveIndex struct {
+	name       string
+	definition string // statement creating it again
+}
+
+// indexes returns the indexes of a table, sorted by name
+func (d *differ) indexes(model interface{}, table string) ([]liveIndex, error) {
+	var indexes []liveIndex
+
+	if d.dialect == "sqlite" {
+		// The SQLite migrator does not list indexes, sqlite_master does
+		var rows []struct {
+			Name string
+			SQL  string
+		}
+		err := d.db.Raw("SELECT name, sql FROM sqlite_master WHERE type = ? AND tbl_name = ? AND sql IS NOT NULL", "index", table).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the indexes of %s: %w", table, err)
+		}
+		for _, row := range rows {
+			indexes = append(indexes, liveIndex{name: row.Name, definition: row.SQL})
+		}
+	} else {
+		found, err := d.db.Migrator().GetIndexes(model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the indexes of %s: %w", table, err)
+		}
+		for _, idx := range found {
+			create := "CREATE INDEX ? ON ? ?"
+			if unique, _ := idx.Unique(); unique {
+				create = "CREATE UNIQUE INDEX ? ON ? ?"
+			}
+			var columns []string
+			for _, column := range idx.Columns() {
+				columns = append(columns, d.quote(clause.Column{Name: column}))
+			}
+			definition, err := d.sql(create, clause.Column{Name: idx.Name()}, clause.Table{Name: table},
+				clause.Expr{SQL: "(" + strings.Join(columns, ",") + ")"})
+			if err != nil {
+				return nil, err
+			}
+			indexes = append(indexes, liveIndex{name: idx.Name(), definition: definition[0]})
+		}
+	}
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].name < indexes[j].name })
+	return indexes, nil
+}
+
+// foreignKeys adds the foreign keys of the model's relations missing from
+// the database. SQLite only creates them with the table.
+func (d *differ) foreignKeys(model interface{}, stmt *gorm.Statement) error {
+	constraints := make(map[string]bool)
+	var names []string
+	for _, rel := range stmt.Schema.Relationships.Relations {
+		if rel.Field.IgnoreMigration {
+			continue
+		}
+		if constraint := rel.ParseConstraint(); constraint != nil && constraint.Schema == stmt.Schema && !constraints[constraint.Name] {
+			constraints[constraint.Name] = true
+			names = append(names, constraint.Name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if d.db.Migrator().HasConstraint(model, name) {
+			continue
+		}
+		if d.dialect == "sqlite" {
+			d.warn("foreign key %s of %s: SQLite cannot add it to an existing table, rebuild the table by hand", name, stmt.Schema.Table)
+			continue
+		}
+
+		up, err := d.capture(func(tx *gorm.DB) error { return tx.Migrator().CreateConstraint(model, name) })
+		if err != nil {
+			return err
+		}
+		drop := "ALTER TABLE ? DROP CONSTRAINT ?"
+		if d.dialect == "mysql" {
+			drop = "ALTER TABLE ? DROP FOREIGN KEY ?"
+		}
+		down, err := d.sql(drop, clause.Table{Name: stmt.Schema.Table}, clause.Column{Name: name})
+		if err != nil {
+			return err
+		}
+		d.change(up, down)
+	}
+	return nil
+}
+
+// quote quotes a table or column name for the database
+func (d *differ) quote(name interface{}) string {
+	return (&gorm.Statement{DB: d.db}).Quote(name)
+}
+
+// sql renders a statement for the database
+func (d *differ) sql(statement string, vars ...interface{}) ([]string, error) {
+	return d.capture(func(tx *gorm.DB) error { return tx.Exec(statement, vars...).Error })
+}
+
+// capture returns the statements fn runs, without running them
+func (d *differ) capture(fn func(tx *gorm.DB) error) ([]string, error) {
+	rec := &recorder{Interface: logger.Discard}
+	if err := fn(d.db.Session(&gorm.Session{DryRun: true, Logger: rec})); err != nil {
+		return nil, err
+	}
+	return rec.statements, nil
+}
+
+// recorder is a GORM logger collecting the statements of a dry run
+type recorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *recorder) LogMode(logger.LogLevel) logger.Interface {
+	return r
+}
+
+func (r *recorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	statement, _ := fc()
+	r.statements = append(r.statements, statement)
+}
+
+// GoSource returns a Go migration of the migrations package applying the
+// changes (statements are run by SQL, warnings are left as TODOs)
+func (c *SchemaChanges) GoSource(version, name string) []byte {
+	var b strings.Builder
+	b.WriteString("package migrations\n\n")
+	b.WriteString("// Generated by \"loom make migration --auto\" from the models of\n")
+	b.WriteString("// models_all.go and the schema of the database. Review it before running it.\n")
+	for _, warning := range c.Warnings {
+		fmt.Fprintf(&b, "//\n// TODO: %s\n", warning)
+	}
+	b.WriteString("\nfunc init() {\n\tRegister(Migration{\n")
+	fmt.Fprintf(&b, "\t\tVersion: %q,\n\t\tName:    %q,\n", version, name)
+	writeStatements(&b, "Up", c.Up)
+	writeStatements(&b, "Down", c.Down)
+	b.WriteString("\t})\n}\n")
+	return []byte(b.String())
+}
+
+// writeStatements writes the Up or Down step of a migration
+func writeStatements(b *strings.Builder, step string, statements []string) {
+	if len(statements) == 0 {
+		fmt.Fprintf(b, "\t\t%s: SQL(),\n", step)
+		return
+	}
+	fmt.Fprintf(b, "\t\t%s: SQL(\n", step)
+	for _, statement := range statements {
+		fmt.Fprintf(b, "\t\t\t%s,\n", goString(statement))
+	}
+	b.WriteString("\t\t),\n")
+}
+
+// goString quotes a statement as a Go string, raw when possible
+func goString(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
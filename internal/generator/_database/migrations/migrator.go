@@ -0,0 +1,287 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned change of the database schema. Go migrations
+// register themselves from init(); SQL migrations (sql/*.up.sql and
+// sql/*.down.sql) are registered by RegisterFS.
+type Migration struct {
+	Version string // timestamp (YYYYMMDDHHMMSS), orders the migrations
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is a row of the schema_migrations table: a migration
+// that has been applied, and the batch it was applied in
+type SchemaMigration struct {
+	Version   string `gorm:"primaryKey;size:14"`
+	Name      string `gorm:"size:255;not null"`
+	Batch     int    `gorm:"not null;index"`
+	AppliedAt time.Time
+}
+
+// TableName keeps the tracking table name independent of GORM's naming
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status is the state of a registered migration
+type Status struct {
+	Migration
+	Applied   bool
+	Batch     int
+	AppliedAt time.Time
+}
+
+var registry = map[string]Migration{}
+
+// Register adds a migration. Versions must be unique.
+func Register(m Migration) {
+	if _, exists := registry[m.Version]; exists {
+		panic(fmt.Sprintf("migration %s registered twice", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// RegisterFS registers the SQL migrations of a directory of fsys: each
+// <version>_<name>.up.sql may have a matching .down.sql
+func RegisterFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		base, ok := strings.CutSuffix(entry.Name(), ".up.sql")
+		if !ok {
+			continue
+		}
+		version, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return fmt.Errorf("invalid migration file name %s (want <version>_<name>.up.sql)", entry.Name())
+		}
+
+		up, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		m := Migration{Version: version, Name: name, Up: SQL(string(up))}
+
+		down, err := fs.ReadFile(fsys, path.Join(dir, base+".down.sql"))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err == nil {
+			m.Down = SQL(string(down))
+		}
+		Register(m)
+	}
+	return nil
+}
+
+// SQL returns a migration step running statements one after the other
+// (a SQL migration file is a single statement, run as is)
+func SQL(statements ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// All returns the registered migrations, oldest first
+func All() []Migration {
+	migrations := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+// Migrate applies the pending migrations, all in one new batch, and
+// returns them
+func Migrate(db *gorm.DB) ([]Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := 1
+	for _, record := range applied {
+		if record.Batch >= batch {
+			batch = record.Batch + 1
+		}
+	}
+
+	var ran []Migration
+	for _, m := range All() {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		log.Printf("⬆️  Migrating: %s_%s", m.Version, m.Name)
+		err := run(db, m.Up, func(tx *gorm.DB) error {
+			return tx.Create(&SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				Batch:     batch,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %s_%s failed: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Rollback reverts the migrations of the last batch or, when steps is
+// positive, the last steps migrations, and returns them
+func Rollback(db *gorm.DB, steps int) ([]Migration, error) {
+	records, err := appliedInReverse(db)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+
+	if steps > 0 {
+		if steps < len(records) {
+			records = records[:steps]
+		}
+	} else {
+		last := records[0].Batch
+		n := 0
+		for n < len(records) && records[n].Batch == last {
+			n++
+		}
+		records = records[:n]
+	}
+	return rollback(db, records)
+}
+
+// Reset reverts every applied migration
+func Reset(db *gorm.DB) ([]Migration, error) {
+	records, err := appliedInReverse(db)
+	if err != nil {
+		return nil, err
+	}
+	return rollback(db, records)
+}
+
+// MigrationStatus lists the registered migrations and whether they have
+// been applied
+func MigrationStatus(db *gorm.DB) ([]Status, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range All() {
+		status := Status{Migration: m}
+		if record, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.Batch = record.Batch
+			status.AppliedAt = record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// rollback reverts the applied migrations of records, in that order
+func rollback(db *gorm.DB, records []SchemaMigration) ([]Migration, error) {
+	var reverted []Migration
+	for _, record := range records {
+		m, ok := registry[record.Version]
+		if !ok {
+			return reverted, fmt.Errorf("migration %s_%s is applied but not registered", record.Version, record.Name)
+		}
+		if m.Down == nil {
+			return reverted, fmt.Errorf("migration %s_%s cannot be rolled back (no down migration)", m.Version, m.Name)
+		}
+
+		log.Printf("⬇️  Rolling back: %s_%s", m.Version, m.Name)
+		err := run(db, m.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("rollback of %s_%s failed: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// run applies a migration step and records it. Both run in a transaction
+// where the database supports transactional DDL; MySQL commits every
+// schema change implicitly, so there they run one after the other.
+func run(db *gorm.DB, step, record func(tx *gorm.DB) error) error {
+	apply := func(tx *gorm.DB) error {
+		if step != nil {
+			if err := step(tx); err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	}
+
+	if !transactional(db) {
+		return apply(db)
+	}
+	return db.Transaction(apply)
+}
+
+// transactional reports whether schema changes can be rolled back
+func transactional(db *gorm.DB) bool {
+	return db.Dialector.Name() != "mysql"
+}
+
+// appliedMigrations returns the applied migrations by version, creating
+// the schema_migrations table when needed
+func appliedMigrations(db *gorm.DB) (map[string]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// appliedInReverse returns the applied migrations, newest first
+func appliedInReverse(db *gorm.DB) ([]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var records []SchemaMigration
+	err := db.Order("batch DESC").Order("version DESC").Find(&records).Error
+	return records, err
+}
@@ -0,0 +1,263 @@
+package seeders
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dependent is implemented by seeders that need the data of other seeders:
+// those run first
+type Dependent interface {
+	Dependencies() []Seeder
+}
+
+// Tagged is implemented by seeders belonging to groups ("demo"), run
+// together with --tag
+type Tagged interface {
+	Tags() []string
+}
+
+// Restricted is implemented by seeders that only run in some environments
+// (e.g. demo data: "development" and "testing", never "production")
+type Restricted interface {
+	Environments() []string
+}
+
+// SeederRun is a row of the seeder_runs table: a seeder that has run
+type SeederRun struct {
+	Name  string `gorm:"primaryKey;size:255"`
+	RanAt time.Time
+}
+
+// TableName keeps the tracking table name independent of GORM's naming
+func (SeederRun) TableName() string {
+	return "seeder_runs"
+}
+
+// DatabaseSeeder orchestrates all seeders. Without options it runs every
+// seeder of AllSeeders.
+type DatabaseSeeder struct {
+	Classes     []string // only these seeders ("ProductSeeder" or "Product")
+	Tags        []string // only the seeders with one of these tags
+	Environment string   // skips the seeders restricted to other environments
+	Once        bool     // skips the seeders that have already run
+}
+
+// Run executes the selected seeders, each after its dependencies and in a
+// transaction, and records them in seeder_runs
+func (s *DatabaseSeeder) Run(db *gorm.DB) error {
+	log.Println("🌱 Running seeders...")
+
+	ordered, err := Ordered(AllSeeders)
+	if err != nil {
+		return err
+	}
+	selected, err := s.selection(ordered)
+	if err != nil {
+		return err
+	}
+
+	ran, err := ranSeeders(db)
+	if err != nil {
+		return err
+	}
+
+	skipped := map[string]bool{}
+	for _, seeder := range ordered {
+		name := Name(seeder)
+		if !selected[name] {
+			continue
+		}
+		if !s.allowed(seeder) {
+			log.Printf("⏭️  %s: not run in %s, skipping", name, s.Environment)
+			skipped[name] = true
+			continue
+		}
+		if dependency := skippedDependency(seeder, skipped); dependency != "" {
+			log.Printf("⏭️  %s: depends on %s, which is not run, skipping", name, dependency)
+			skipped[name] = true
+			continue
+		}
+		if s.Once && ran[name] {
+			log.Printf("⏭️  %s: already run, skipping", name)
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := seeder.Run(tx); err != nil {
+				return err
+			}
+			return tx.Save(&SeederRun{Name: name, RanAt: time.Now()}).Error
+		})
+		if err != nil {
+			log.Printf("❌ Seeder failed: %v", err)
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	log.Println("✅ All seeders executed successfully")
+	return nil
+}
+
+// selection returns the names of the seeders chosen by Classes and Tags,
+// with their dependencies. The dependencies of a seeder that does not run
+// in the environment are left out: they are not needed.
+func (s *DatabaseSeeder) selection(seeders []Seeder) (map[string]bool, error) {
+	byName := make(map[string]Seeder, len(seeders))
+	for _, seeder := range seeders {
+		byName[Name(seeder)] = seeder
+	}
+
+	var roots []Seeder
+	for _, class := range s.Classes {
+		if !strings.HasSuffix(class, "Seeder") {
+			class += "Seeder"
+		}
+		seeder, ok := byName[class]
+		if !ok {
+			return nil, fmt.Errorf("seeder %s is not registered in AllSeeders", class)
+		}
+		roots = append(roots, seeder)
+	}
+	for _, tag := range s.Tags {
+		found := false
+		for _, seeder := range seeders {
+			if tagged, ok := seeder.(Tagged); ok && slices.Contains(tagged.Tags(), tag) {
+				roots = append(roots, seeder)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no seeder is tagged %q", tag)
+		}
+	}
+	if len(s.Classes) == 0 && len(s.Tags) == 0 {
+		roots = seeders
+	}
+
+	selected := map[string]bool{}
+	var add func(seeder Seeder)
+	add = func(seeder Seeder) {
+		name := Name(seeder)
+		if selected[name] {
+			return
+		}
+		selected[name] = true
+		if !s.allowed(seeder) {
+			return
+		}
+		if dependent, ok := seeder.(Dependent); ok {
+			for _, dependency := range dependent.Dependencies() {
+				add(byName[Name(dependency)])
+			}
+		}
+	}
+	for _, seeder := range roots {
+		add(seeder)
+	}
+	return selected, nil
+}
+
+// allowed reports whether a seeder runs in the environment
+func (s *DatabaseSeeder) allowed(seeder Seeder) bool {
+	restricted, ok := seeder.(Restricted)
+	return !ok || s.Environment == "" || slices.Contains(restricted.Environments(), s.Environment)
+}
+
+// skippedDependency returns the first dependency of a seeder that has
+// been skipped, or "" when they all ran
+func skippedDependency(seeder Seeder, skipped map[string]bool) string {
+	if dependent, ok := seeder.(Dependent); ok {
+		for _, dependency := range dependent.Dependencies() {
+			if skipped[Name(dependency)] {
+				return Name(dependency)
+			}
+		}
+	}
+	return ""
+}
+
+// ranSeeders returns the names of the seeders that have run, creating the
+// seeder_runs table when needed
+func ranSeeders(db *gorm.DB) (map[string]bool, error) {
+	if err := db.AutoMigrate(&SeederRun{}); err != nil {
+		return nil, fmt.Errorf("failed to create seeder_runs: %w", err)
+	}
+
+	var runs []SeederRun
+	if err := db.Find(&runs).Error; err != nil {
+		return nil, err
+	}
+
+	ran := make(map[string]bool, len(runs))
+	for _, run := range runs {
+		ran[run.Name] = true
+	}
+	return ran, nil
+}
+
+// Ordered sorts seeders so that each one comes after its dependencies,
+// keeping their order otherwise. Dependencies must be registered too.
+func Ordered(seeders []Seeder) ([]Seeder, error) {
+	registered := make(map[string]Seeder, len(seeders))
+	for _, seeder := range seeders {
+		registered[Name(seeder)] = seeder
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var ordered []Seeder
+	var visit func(seeder Seeder, path []string) error
+	visit = func(seeder Seeder, path []string) error {
+		name := Name(seeder)
+		path = append(path, name)
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("seeders depend on each other: %s", strings.Join(path, " -> "))
+		case done:
+			return nil
+		}
+
+		state[name] = visiting
+		if dependent, ok := seeder.(Dependent); ok {
+			for _, dependency := range dependent.Dependencies() {
+				// Run the registered seeder, not the value naming it
+				next, ok := registered[Name(dependency)]
+				if !ok {
+					return fmt.Errorf("%s depends on %s, which is not registered in AllSeeders", name, Name(dependency))
+				}
+				if err := visit(next, path); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = done
+		ordered = append(ordered, seeder)
+		return nil
+	}
+
+	for _, seeder := range seeders {
+		if err := visit(seeder, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Name returns the name of a seeder: its type name ("UserSeeder")
+func Name(seeder Seeder) string {
+	t := reflect.TypeOf(seeder)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
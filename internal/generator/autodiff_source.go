@@ -0,0 +1,18 @@
+package generator
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// autodiffSource is the schema diff run by "loom make migration --auto"
+//
+//go:embed _database/autodiff/autodiff.go
+var autodiffSource string
+
+// AutodiffSource returns the code of package autodiff declared in package
+// pkg. The GORM addon writes it into the migrations package of projects,
+// where the console runs it against the project database.
+func AutodiffSource(pkg string) string {
+	return strings.Replace(autodiffSource, "package autodiff", "package "+pkg, 1)
+}
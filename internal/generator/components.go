@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// componentsDir holds the templates of "loom generate" components, one
+// directory per architecture plus the shared ones (middleware, docs)
+const componentsDir = "components"
+
+// ComponentData is the data passed to the component templates
+type ComponentData struct {
+	Name       string // name as given on the command line
+	Lower      string // package and file name ("products")
+	Pascal     string // PascalCase name ("OrderItems", "RateLimit")
+	Type       string // model type of a module, singular ("OrderItem")
+	Camel      string // variable name ("products")
+	Snake      string // snake_case name ("order_items")
+	Plural     string // snake_case plural ("products"), also the route segment
+	Singular   string // snake_case singular ("product")
+	ModulePath string // Go module of the project
+	Router     HTTPRouter
+	ORM        string // as reported by addon.ProjectDetector.DetectORM; "gorm" backs repositories with GORM
+	UseHelpers bool   // validate DTOs with the Loom helpers
+	Fields     []Field
+	Relations  []Relation
+	Flags      map[string]string // flag values of pack kinds
+}
+
+// componentData returns the template data of a component
+func (g *ModuleGenerator) componentData(name string, fields []Field, relations []Relation) ComponentData {
+	nameLower := strings.ToLower(name)
+
+	return ComponentData{
+		Name:       name,
+		Lower:      nameLower,
+		Pascal:     toPascalCase(name),
+		Type:       moduleTypeName(name),
+		Camel:      toCamelCase(nameLower),
+		Snake:      toSnakeCase(name),
+		Plural:     resourcePath(name),
+		Singular:   toSingular(toSnakeCase(name)),
+		ModulePath: g.project.ModuleName,
+		Router:     g.router(),
+		ORM:        g.project.ORM,
+		UseHelpers: g.project.HasHelpers,
+		Fields:     fields,
+		Relations:  relations,
+	}
+}
+
+// BelongsTo returns the belongs_to relations
+func (d ComponentData) BelongsTo() []Relation {
+	return filterRelations(d.Relations, RelationBelongsTo)
+}
+
+// ManyToMany returns the many_to_many relations
+func (d ComponentData) ManyToMany() []Relation {
+	return filterRelations(d.Relations, RelationManyToMany)
+}
+
+// UniqueFields returns the fields with a unique constraint
+func (d ComponentData) UniqueFields() []Field {
+	var unique []Field
+	for _, f := range d.Fields {
+		if f.Unique {
+			unique = append(unique, f)
+		}
+	}
+	return unique
+}
+
+// NeedsTime reports whether a field uses time.Time
+func (d ComponentData) NeedsTime() bool {
+	return needsTimeImport(d.Fields)
+}
+
+// NestedRoutes returns the routes listing the module items by parent
+func (d ComponentData) NestedRoutes() []NestedRoute {
+	return NestedRoutes(d.Name, d.Relations)
+}
+
+// componentFuncs are the helpers available to component templates
+var componentFuncs = template.FuncMap{
+	"pascal":   toPascalCase,
+	"camel":    toCamelCase,
+	"snake":    toSnakeCase,
+	"plural":   toPlural,
+	"singular": toSingular,
+	"lower":    strings.ToLower,
+	"humanize": func(s string) string { return strings.ReplaceAll(s, "_", " ") },
+	"last":     func(i, n int) bool { return i == n-1 },
+	"associationFields": func(owner string, relations []Relation) string {
+		return associationLines(owner, relations)
+	},
+	"joinModels": func(owner string, relations []Relation) string {
+		return joinModels(owner, relations, "int")
+	},
+}
+
+// renderComponent executes the component template at name (relative to
+// the components directory, e.g. "layered/handler.go.tmpl")
+func renderComponent(name string, data ComponentData) (string, error) {
+	content, err := readTemplate(path.Join(componentsDir, name))
+	if err != nil {
+		return "", err
+	}
+	return renderString(name, content, data)
+}
+
+// renderString executes a component template given by its content
+func renderString(name, content string, data ComponentData) (string, error) {
+	tmpl, err := template.New(name).Funcs(componentFuncs).Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template %s: %w", name, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("error executing template %s: %w", name, err)
+	}
+
+	return out.String(), nil
+}
+
+// renderComponents renders a set of files, keyed by output path
+func renderComponents(templates map[string]string, data ComponentData) (map[string]string, error) {
+	files := make(map[string]string, len(templates))
+	for filePath, name := range templates {
+		content, err := renderComponent(name, data)
+		if err != nil {
+			return nil, err
+		}
+		files[filePath] = content
+	}
+	return files, nil
+}
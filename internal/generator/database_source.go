@@ -0,0 +1,30 @@
+package generator
+
+import _ "embed"
+
+// The database sources written into projects live in _database, which the
+// go tool skips: they import GORM, and only the databasetest module, where
+// they are compiled and tested against SQLite, requires it.
+
+// migratorSource runs the versioned migrations of generated projects
+//
+//go:embed _database/migrations/migrator.go
+var migratorSource string
+
+// MigratorSource returns the code of the migrator. The GORM addon writes
+// it into the migrations package of projects, next to the migrations.
+func MigratorSource() string {
+	return migratorSource
+}
+
+// databaseSeederSource orders and selects the seeders of generated projects
+//
+//go:embed _database/seeders/database_seeder.go
+var databaseSeederSource string
+
+// DatabaseSeederSource returns the code of the DatabaseSeeder. The GORM
+// addon writes it into the seeders package of projects, where
+// seeders_all.go declares Seeder and AllSeeders.
+func DatabaseSeederSource() string {
+	return databaseSeederSource
+}
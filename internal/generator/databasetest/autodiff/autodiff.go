@@ -0,0 +1 @@
+../../_database/autodiff/autodiff.go
\ No newline at end of file
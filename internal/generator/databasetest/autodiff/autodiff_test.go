@@ -0,0 +1,178 @@
+package autodiff
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Two versions of the same tables: the database is created from the
+// first, the models are the second
+
+type categoryV1 struct {
+	ID   uint
+	Name string
+}
+
+func (categoryV1) TableName() string { return "categories" }
+
+type productV1 struct {
+	ID     uint
+	Name   string
+	Price  int
+	Legacy string `gorm:"index"`
+}
+
+func (productV1) TableName() string { return "products" }
+
+type category struct {
+	ID   uint
+	Name string
+}
+
+func (category) TableName() string { return "categories" }
+
+type product struct {
+	ID         uint
+	Name       string
+	Price      string // type change
+	Stock      int    `gorm:"index"`
+	CategoryID *uint
+	Category   *category
+}
+
+func (product) TableName() string { return "products" }
+
+type tag struct {
+	ID   uint
+	Name string `gorm:"uniqueIndex"`
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection: every connection to :memory: is a new database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+func exec(t *testing.T, db *gorm.DB, statements []string) {
+	t.Helper()
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+}
+
+// TestDiffSchemaSQLite diffs models against a SQLite database, applies
+// the migration, checks the schema then matches, and reverts it
+func TestDiffSchemaSQLite(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&categoryV1{}, &productV1{}); err != nil {
+		t.Fatal(err)
+	}
+
+	models := []interface{}{&category{}, &product{}, &tag{}}
+	changes, err := DiffSchema(db, models)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up := strings.Join(changes.Up, "\n")
+	for _, want := range []string{
+		"ALTER TABLE `products` ADD `stock` integer",
+		"ALTER TABLE `products` ADD `category_id` integer",
+		"CREATE INDEX `idx_products_stock` ON `products`(`stock`)",
+		"DROP INDEX `idx_products_legacy`",
+		"ALTER TABLE `products` DROP COLUMN `legacy`",
+		"CREATE TABLE `tags`",
+		"CREATE UNIQUE INDEX `idx_tags_name` ON `tags`(`name`)",
+	} {
+		if !strings.Contains(up, want) {
+			t.Errorf("up migration misses %q:\n%s", want, up)
+		}
+	}
+	if strings.Contains(up, "categories") {
+		t.Errorf("unchanged table categories in the up migration:\n%s", up)
+	}
+
+	// SQLite can neither alter columns nor add foreign keys to a table
+	warnings := strings.Join(changes.Warnings, "\n")
+	for _, want := range []string{"products.price changed from integer to text", "foreign key fk_products_category"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings miss %q:\n%s", want, warnings)
+		}
+	}
+
+	exec(t, db, changes.Up)
+	again, err := DiffSchema(db, models)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Up) != 0 {
+		t.Errorf("schema still differs after the up migration:\n%s", strings.Join(again.Up, "\n"))
+	}
+
+	exec(t, db, changes.Down)
+	reverted, err := DiffSchema(db, []interface{}{&categoryV1{}, &productV1{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reverted.Empty() {
+		t.Errorf("schema differs from the original one after the down migration:\n%s\n%s",
+			strings.Join(reverted.Up, "\n"), strings.Join(reverted.Warnings, "\n"))
+	}
+	if db.Migrator().HasTable("tags") {
+		t.Error("down migration did not drop tags")
+	}
+}
+
+// TestDiffSchemaUpToDate checks that a database migrated from the models
+// yields no changes
+func TestDiffSchemaUpToDate(t *testing.T) {
+	db := openSQLite(t)
+	models := []interface{}{&category{}, &product{}, &tag{}}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := DiffSchema(db, models)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.Empty() {
+		t.Errorf("changes for an up to date schema:\n%s\n%s", strings.Join(changes.Up, "\n"), strings.Join(changes.Warnings, "\n"))
+	}
+}
+
+// TestGoSource checks the generated migration is valid Go
+func TestGoSource(t *testing.T) {
+	changes := &SchemaChanges{
+		Up:       []string{"ALTER TABLE `products` ADD `stock` integer", `CREATE INDEX "idx" ON "products"("stock")`},
+		Down:     []string{"ALTER TABLE `products` DROP COLUMN `stock`"},
+		Warnings: []string{"products.price changed"},
+	}
+	source := string(changes.GoSource("20260101120000", "add_stock"))
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "migration.go", source, 0); err != nil {
+		t.Fatalf("invalid Go: %v\n%s", err, source)
+	}
+	for _, want := range []string{`Version: "20260101120000"`, "// TODO: products.price changed", `Up: SQL(`, "`CREATE INDEX \"idx\" ON \"products\"(\"stock\")`"} {
+		if !strings.Contains(source, want) {
+			t.Errorf("source misses %q:\n%s", want, source)
+		}
+	}
+}
@@ -0,0 +1 @@
+../../_database/migrations/migrator.go
\ No newline at end of file
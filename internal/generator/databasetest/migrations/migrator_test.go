@@ -0,0 +1,244 @@
+package migrations
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection: every connection to :memory: is a new database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+// migrationLog records the steps run by the test migrations
+type migrationLog []string
+
+// register replaces the registry with table-creating migrations, one per
+// version, logging their steps
+func (l *migrationLog) register(t *testing.T, versions ...string) {
+	t.Helper()
+	registry = map[string]Migration{}
+	for _, version := range versions {
+		l.add(version)
+	}
+}
+
+func (l *migrationLog) add(version string) {
+	table := "t" + version
+	Register(Migration{
+		Version: version,
+		Name:    "create_" + table,
+		Up: func(tx *gorm.DB) error {
+			*l = append(*l, "up "+version)
+			return tx.Exec("CREATE TABLE " + table + " (id integer)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			*l = append(*l, "down "+version)
+			return tx.Exec("DROP TABLE " + table).Error
+		},
+	})
+}
+
+func versions(migrations []Migration) []string {
+	var list []string
+	for _, m := range migrations {
+		list = append(list, m.Version)
+	}
+	return list
+}
+
+func batches(t *testing.T, db *gorm.DB) map[string]int {
+	t.Helper()
+	statuses, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := map[string]int{}
+	for _, status := range statuses {
+		if status.Applied {
+			applied[status.Version] = status.Batch
+		}
+	}
+	return applied
+}
+
+// TestMigrateBatches checks that the migrations applied together share a
+// batch, the next run starting a new one
+func TestMigrateBatches(t *testing.T) {
+	db := openSQLite(t)
+	var log migrationLog
+	log.register(t, "20240102000000", "20240101000000")
+
+	ran, err := Migrate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(ran); !slices.Equal(got, []string{"20240101000000", "20240102000000"}) {
+		t.Errorf("first run applied %v, want them oldest first", got)
+	}
+
+	ran, err = Migrate(db)
+	if err != nil || len(ran) != 0 {
+		t.Fatalf("second run applied %v, %v; want nothing", versions(ran), err)
+	}
+
+	log.add("20240103000000")
+	if _, err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"20240101000000": 1, "20240102000000": 1, "20240103000000": 2}
+	if got := batches(t, db); !maps.Equal(got, want) {
+		t.Errorf("batches = %v, want %v", got, want)
+	}
+}
+
+// TestRollback checks that a rollback reverts the last batch, newest
+// first, and that --step counts migrations across batches
+func TestRollback(t *testing.T) {
+	db := openSQLite(t)
+	var log migrationLog
+	log.register(t, "20240101000000", "20240102000000")
+	if _, err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	log.add("20240103000000")
+	log.add("20240104000000")
+	if _, err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	log = nil
+	reverted, err := Rollback(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(reverted); !slices.Equal(got, []string{"20240104000000", "20240103000000"}) {
+		t.Errorf("rollback reverted %v, want the second batch newest first", got)
+	}
+	if !slices.Equal(log, []string{"down 20240104000000", "down 20240103000000"}) {
+		t.Errorf("steps run = %v", log)
+	}
+
+	// Applied again, they form batch 2 again
+	if _, err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	reverted, err = Rollback(db, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(reverted); !slices.Equal(got, []string{"20240104000000", "20240103000000", "20240102000000"}) {
+		t.Errorf("rollback --step=3 reverted %v", got)
+	}
+	if got := batches(t, db); !maps.Equal(got, map[string]int{"20240101000000": 1}) {
+		t.Errorf("batches after rollback = %v", got)
+	}
+
+	reverted, err = Reset(db)
+	if err != nil || !slices.Equal(versions(reverted), []string{"20240101000000"}) {
+		t.Errorf("reset reverted %v, %v", versions(reverted), err)
+	}
+	if reverted, err := Rollback(db, 0); err != nil || len(reverted) != 0 {
+		t.Errorf("rollback of an empty database reverted %v, %v", versions(reverted), err)
+	}
+}
+
+// TestMigrateFailure checks that a failed migration leaves neither its
+// changes nor its record, and stops the run
+func TestMigrateFailure(t *testing.T) {
+	db := openSQLite(t)
+	var log migrationLog
+	log.register(t, "20240101000000")
+	Register(Migration{
+		Version: "20240102000000",
+		Name:    "broken",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE TABLE broken (id integer)").Error; err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+	})
+	log.add("20240103000000")
+
+	ran, err := Migrate(db)
+	if err == nil {
+		t.Fatal("Migrate succeeded, want an error")
+	}
+	if got := versions(ran); !slices.Equal(got, []string{"20240101000000"}) {
+		t.Errorf("applied %v before the failure", got)
+	}
+	if db.Migrator().HasTable("broken") {
+		t.Error("the failed migration was not rolled back")
+	}
+	if got := batches(t, db); !maps.Equal(got, map[string]int{"20240101000000": 1}) {
+		t.Errorf("batches = %v", got)
+	}
+}
+
+func TestRollbackWithoutDown(t *testing.T) {
+	db := openSQLite(t)
+	registry = map[string]Migration{}
+	Register(Migration{Version: "20240101000000", Name: "irreversible", Up: SQL("CREATE TABLE a (id integer)")})
+	if _, err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Rollback(db, 0); err == nil {
+		t.Error("rollback of a migration without down succeeded")
+	}
+	if got := batches(t, db); len(got) != 1 {
+		t.Errorf("migration no longer applied: %v", got)
+	}
+}
+
+func TestRegisterFS(t *testing.T) {
+	db := openSQLite(t)
+	registry = map[string]Migration{}
+	fsys := fstest.MapFS{
+		"sql/20240101000000_create_products.up.sql":   {Data: []byte("CREATE TABLE products (id integer)")},
+		"sql/20240101000000_create_products.down.sql": {Data: []byte("DROP TABLE products")},
+		"sql/20240102000000_seed_products.up.sql":     {Data: []byte("INSERT INTO products (id) VALUES (1)")},
+	}
+	if err := RegisterFS(fsys, "sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	all := All()
+	if got := versions(all); !slices.Equal(got, []string{"20240101000000", "20240102000000"}) {
+		t.Fatalf("registered %v", got)
+	}
+	if all[0].Name != "create_products" || all[0].Down == nil || all[1].Down != nil {
+		t.Errorf("migrations = %+v", all)
+	}
+
+	if _, err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := db.Table("products").Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("products = %d, %v", count, err)
+	}
+
+	if err := RegisterFS(fstest.MapFS{"sql/broken.up.sql": {}}, "sql"); err == nil {
+		t.Error("no error for a file name without version")
+	}
+}
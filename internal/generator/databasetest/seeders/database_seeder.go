@@ -0,0 +1 @@
+../../_database/seeders/database_seeder.go
\ No newline at end of file
@@ -0,0 +1,260 @@
+package seeders
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection: every connection to :memory: is a new database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.Exec("CREATE TABLE seeded (name text)").Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// seeded records a seeder run in the seeded table, which the transaction
+// of the run rolls back on failure
+func seeded(db *gorm.DB, name string) error {
+	return db.Exec("INSERT INTO seeded (name) VALUES (?)", name).Error
+}
+
+// UserSeeder runs everywhere
+type UserSeeder struct{}
+
+func (UserSeeder) Run(db *gorm.DB) error { return seeded(db, "UserSeeder") }
+
+// ProductSeeder is demo data, never run in production
+type ProductSeeder struct{}
+
+func (ProductSeeder) Run(db *gorm.DB) error  { return seeded(db, "ProductSeeder") }
+func (ProductSeeder) Dependencies() []Seeder { return []Seeder{&UserSeeder{}} }
+func (ProductSeeder) Tags() []string         { return []string{"demo"} }
+func (ProductSeeder) Environments() []string { return []string{"development", "testing"} }
+
+// OrderSeeder runs everywhere, but needs the products
+type OrderSeeder struct{}
+
+func (OrderSeeder) Run(db *gorm.DB) error  { return seeded(db, "OrderSeeder") }
+func (OrderSeeder) Dependencies() []Seeder { return []Seeder{&ProductSeeder{}} }
+func (OrderSeeder) Tags() []string         { return []string{"demo"} }
+
+// ReportSeeder runs everywhere, after the users
+type ReportSeeder struct{}
+
+func (ReportSeeder) Run(db *gorm.DB) error  { return seeded(db, "ReportSeeder") }
+func (ReportSeeder) Dependencies() []Seeder { return []Seeder{&UserSeeder{}} }
+func (ReportSeeder) Tags() []string         { return []string{"reports"} }
+
+type CycleASeeder struct{}
+
+func (CycleASeeder) Run(*gorm.DB) error     { return nil }
+func (CycleASeeder) Dependencies() []Seeder { return []Seeder{&CycleBSeeder{}} }
+
+type CycleBSeeder struct{}
+
+func (CycleBSeeder) Run(*gorm.DB) error     { return nil }
+func (CycleBSeeder) Dependencies() []Seeder { return []Seeder{&CycleASeeder{}} }
+
+type FailingSeeder struct{}
+
+func (FailingSeeder) Run(db *gorm.DB) error {
+	if err := seeded(db, "FailingSeeder"); err != nil {
+		return err
+	}
+	return errors.New("boom")
+}
+
+func names(seeders []Seeder) []string {
+	var list []string
+	for _, seeder := range seeders {
+		list = append(list, Name(seeder))
+	}
+	return list
+}
+
+// seededNames returns the seeders that have written to the seeded table,
+// in the order they ran
+func seededNames(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var list []string
+	if err := db.Raw("SELECT name FROM seeded ORDER BY rowid").Scan(&list).Error; err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func register(t *testing.T, seeders ...Seeder) {
+	t.Helper()
+	previous := AllSeeders
+	AllSeeders = seeders
+	t.Cleanup(func() { AllSeeders = previous })
+}
+
+func TestOrdered(t *testing.T) {
+	ordered, err := Ordered([]Seeder{&OrderSeeder{}, &ReportSeeder{}, &ProductSeeder{}, &UserSeeder{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"UserSeeder", "ProductSeeder", "OrderSeeder", "ReportSeeder"}
+	if got := names(ordered); !slices.Equal(got, want) {
+		t.Errorf("Ordered = %v, want %v", got, want)
+	}
+
+	// The registered seeder runs, not the value naming the dependency
+	user := &UserSeeder{}
+	ordered, err = Ordered([]Seeder{&ProductSeeder{}, user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ordered[0] != Seeder(user) {
+		t.Errorf("Ordered put %p first, want the registered %p", ordered[0], user)
+	}
+}
+
+func TestOrderedErrors(t *testing.T) {
+	_, err := Ordered([]Seeder{&CycleASeeder{}, &CycleBSeeder{}})
+	if err == nil || !strings.Contains(err.Error(), "CycleASeeder -> CycleBSeeder -> CycleASeeder") {
+		t.Errorf("cycle error = %v", err)
+	}
+
+	_, err = Ordered([]Seeder{&ProductSeeder{}})
+	if err == nil || !strings.Contains(err.Error(), "ProductSeeder depends on UserSeeder") {
+		t.Errorf("unregistered dependency error = %v", err)
+	}
+}
+
+// TestRunSelection checks which seeders --class, --tag and the environment
+// run, and in which order
+func TestRunSelection(t *testing.T) {
+	tests := []struct {
+		name   string
+		seeder DatabaseSeeder
+		want   []string
+	}{
+		{
+			name: "all",
+			want: []string{"UserSeeder", "ProductSeeder", "OrderSeeder", "ReportSeeder"},
+		},
+		{
+			name:   "class with its dependencies",
+			seeder: DatabaseSeeder{Classes: []string{"Product"}},
+			want:   []string{"UserSeeder", "ProductSeeder"},
+		},
+		{
+			name:   "full class name",
+			seeder: DatabaseSeeder{Classes: []string{"ReportSeeder"}},
+			want:   []string{"UserSeeder", "ReportSeeder"},
+		},
+		{
+			name:   "tag",
+			seeder: DatabaseSeeder{Tags: []string{"demo"}, Environment: "development"},
+			want:   []string{"UserSeeder", "ProductSeeder", "OrderSeeder"},
+		},
+		{
+			// Nothing tagged demo runs in production, so neither do the users
+			name:   "tag in production",
+			seeder: DatabaseSeeder{Tags: []string{"demo"}, Environment: "production"},
+		},
+		{
+			name:   "all in production",
+			seeder: DatabaseSeeder{Environment: "production"},
+			want:   []string{"UserSeeder", "ReportSeeder"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openSQLite(t)
+			register(t, &UserSeeder{}, &ProductSeeder{}, &OrderSeeder{}, &ReportSeeder{})
+
+			if err := tt.seeder.Run(db); err != nil {
+				t.Fatal(err)
+			}
+			if got := seededNames(t, db); !slices.Equal(got, tt.want) {
+				t.Errorf("ran %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunSelectionErrors(t *testing.T) {
+	db := openSQLite(t)
+	register(t, &UserSeeder{}, &ProductSeeder{})
+
+	for _, seeder := range []DatabaseSeeder{
+		{Classes: []string{"Invoice"}},
+		{Tags: []string{"missing"}},
+	} {
+		if err := seeder.Run(db); err == nil {
+			t.Errorf("%+v ran, want an error", seeder)
+		}
+	}
+	if got := seededNames(t, db); len(got) != 0 {
+		t.Errorf("ran %v despite the error", got)
+	}
+}
+
+// TestRunOnce checks that seeder_runs records the seeders run, which
+// --once then skips
+func TestRunOnce(t *testing.T) {
+	db := openSQLite(t)
+	register(t, &UserSeeder{})
+
+	seeder := DatabaseSeeder{Once: true}
+	for range 2 {
+		if err := seeder.Run(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := seededNames(t, db); !slices.Equal(got, []string{"UserSeeder"}) {
+		t.Errorf("--once ran %v", got)
+	}
+
+	seeder.Once = false
+	if err := seeder.Run(db); err != nil {
+		t.Fatal(err)
+	}
+	if got := seededNames(t, db); len(got) != 2 {
+		t.Errorf("without --once ran %v, want UserSeeder twice", got)
+	}
+
+	ran, err := ranSeeders(db)
+	if err != nil || len(ran) != 1 || !ran["UserSeeder"] {
+		t.Errorf("seeder_runs = %v, %v", ran, err)
+	}
+}
+
+// TestRunFailure checks that a failed seeder leaves neither its data nor
+// its record, and stops the run
+func TestRunFailure(t *testing.T) {
+	db := openSQLite(t)
+	register(t, &UserSeeder{}, &FailingSeeder{}, &ReportSeeder{})
+
+	err := (&DatabaseSeeder{}).Run(db)
+	if err == nil || !strings.Contains(err.Error(), "FailingSeeder") {
+		t.Fatalf("Run error = %v", err)
+	}
+	if got := seededNames(t, db); !slices.Equal(got, []string{"UserSeeder"}) {
+		t.Errorf("ran %v, want only the seeders before the failure", got)
+	}
+	ran, err := ranSeeders(db)
+	if err != nil || ran["FailingSeeder"] {
+		t.Errorf("seeder_runs = %v, %v", ran, err)
+	}
+}
@@ -0,0 +1,14 @@
+package seeders
+
+import "gorm.io/gorm"
+
+// Seeder and AllSeeders stand in for seeders_all.go, generated in projects
+// with their own seeders. Only database_seeder.go is written to projects.
+
+// Seeder interface for all seeders
+type Seeder interface {
+	Run(db *gorm.DB) error
+}
+
+// AllSeeders contains all seeders for execution
+var AllSeeders []Seeder
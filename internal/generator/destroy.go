@@ -0,0 +1,193 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/source"
+)
+
+// Destroy removes what a generator created for a component: the files it
+// tracked for kind:name (module, model, seeder...), their registration in
+// models_all.go and seeders_all.go and, for a module, its wiring in the
+// server and its API docs. Files edited since they were generated are
+// kept unless force is set. Everything Destroy could not undo is reported
+// as skipped. The changes are staged like every other generator write.
+func (g *ModuleGenerator) Destroy(kind, name string, force bool) ([]FileChange, error) {
+	if g.state == nil {
+		return nil, fmt.Errorf("the generation state could not be loaded")
+	}
+
+	nameLower := strings.ToLower(name)
+	generatorName := kind + ":" + nameLower
+	paths := g.state.Paths(generatorName)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files generated for %s %s are tracked", kind, name)
+	}
+
+	var changes []FileChange
+	var models, seeders []string
+	packageModels := make(map[string][]string) // import path -> types
+	for _, p := range paths {
+		if !g.changes.Exists(p) {
+			g.state.Forget(p)
+			continue
+		}
+
+		if !force {
+			pristine, err := g.state.IsPristine(p)
+			if err != nil {
+				return changes, fmt.Errorf("%s: %w", p, err)
+			}
+			if !pristine {
+				changes = append(changes, FileChange{Path: p, Kind: ChangeSkipped, Reason: "modified since generation, use --force to delete"})
+				continue
+			}
+		}
+
+		// The types declared in the file may be registered
+		if strings.HasSuffix(p, ".go") {
+			if file, err := source.LoadFrom(g.changes, p); err == nil {
+				switch path.Base(path.Dir(p)) {
+				case "models":
+					models = append(models, file.TypeNames()...)
+				case "seeders":
+					seeders = append(seeders, file.TypeNames()...)
+				default:
+					// The model of a modular module, registered from its package
+					if strings.HasPrefix(p, "internal/modules/") && path.Base(p) == "model.go" {
+						importPath := g.project.ModuleName + "/" + path.Dir(p)
+						packageModels[importPath] = append(packageModels[importPath], file.TypeNames()...)
+					}
+				}
+			}
+		}
+
+		if err := g.changes.Remove(p); err != nil {
+			return changes, fmt.Errorf("%s: %w", p, err)
+		}
+		g.state.Forget(p)
+		changes = append(changes, FileChange{Path: p, Kind: ChangeDelete})
+	}
+
+	changes = append(changes, g.unregister(models, seeders)...)
+	changes = append(changes, g.unregisterPackageModels(packageModels)...)
+
+	if kind == "module" {
+		changes = append(changes, g.unwire(name)...)
+		if g.manifest != nil {
+			g.manifest.RemoveModule(nameLower)
+		}
+		if g.project.Architecture != "layered" {
+			g.prune = append(g.prune, path.Join("internal/modules", nameLower))
+		}
+	}
+
+	return changes, nil
+}
+
+// unregister removes models and seeders from their registries
+func (g *ModuleGenerator) unregister(models, seeders []string) []FileChange {
+	var changes []FileChange
+
+	registries := []struct {
+		path       string
+		names      []string
+		unregister func(source.FS, string, string) (bool, error)
+	}{
+		{ModelsRegistryPath, models, UnregisterModel},
+		{SeedersRegistryPath, seedersOnly(seeders), UnregisterSeeder},
+	}
+	for _, registry := range registries {
+		if len(registry.names) == 0 || !g.changes.Exists(registry.path) {
+			continue
+		}
+
+		var removed []string
+		for _, name := range registry.names {
+			ok, err := registry.unregister(g.changes, registry.path, name)
+			if err != nil {
+				changes = append(changes, FileChange{Path: registry.path, Kind: ChangeSkipped, Reason: fmt.Sprintf("could not unregister %s: %v", name, err)})
+			} else if ok {
+				removed = append(removed, name)
+			}
+		}
+		if len(removed) > 0 {
+			changes = append(changes, FileChange{Path: registry.path, Kind: ChangeUpdate, Reason: "unregister " + strings.Join(removed, ", ")})
+		}
+	}
+
+	return changes
+}
+
+// unregisterPackageModels removes the models of modular modules from
+// models_all.go
+func (g *ModuleGenerator) unregisterPackageModels(packageModels map[string][]string) []FileChange {
+	if len(packageModels) == 0 || !g.changes.Exists(ModelsRegistryPath) {
+		return nil
+	}
+
+	var changes []FileChange
+	var removed []string
+	for importPath, types := range packageModels {
+		for _, name := range types {
+			ok, err := UnregisterPackageModel(g.changes, ModelsRegistryPath, importPath, name)
+			if err != nil {
+				changes = append(changes, FileChange{Path: ModelsRegistryPath, Kind: ChangeSkipped, Reason: fmt.Sprintf("could not unregister %s: %v", name, err)})
+			} else if ok {
+				removed = append(removed, name)
+			}
+		}
+	}
+	if len(removed) > 0 {
+		changes = append(changes, FileChange{Path: ModelsRegistryPath, Kind: ChangeUpdate, Reason: "unregister " + strings.Join(removed, ", ")})
+	}
+	return changes
+}
+
+// seedersOnly returns the names of the seeder types without their Seeder
+// suffix, the name UnregisterSeeder expects
+func seedersOnly(types []string) []string {
+	var names []string
+	for _, t := range types {
+		if name, ok := strings.CutSuffix(t, "Seeder"); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// unwire removes the wiring and the docs of a module
+func (g *ModuleGenerator) unwire(name string) []FileChange {
+	var changes []FileChange
+
+	changed, leftover, err := g.UnwireModule(name)
+	for _, p := range changed {
+		changes = append(changes, FileChange{Path: p, Kind: ChangeUpdate, Reason: "unwire " + strings.ToLower(name)})
+	}
+	if err != nil {
+		changes = append(changes, FileChange{Path: path.Join(serverDir, "server.go"), Kind: ChangeSkipped, Reason: fmt.Sprintf("could not unwire the module: %v", err)})
+	}
+	for _, p := range leftover {
+		changes = append(changes, FileChange{Path: p, Kind: ChangeSkipped, Reason: "still refers to the module, remove it by hand"})
+	}
+
+	docsPath, err := g.removeModuleDocs(name)
+	if err != nil {
+		changes = append(changes, FileChange{Path: "docs/API.md", Kind: ChangeSkipped, Reason: fmt.Sprintf("could not remove the endpoints: %v", err)})
+	} else if docsPath != "" {
+		changes = append(changes, FileChange{Path: docsPath, Kind: ChangeUpdate, Reason: "remove the endpoints"})
+	}
+
+	return changes
+}
+
+// removeEmptyDirs removes the directories left empty by a commit
+func removeEmptyDirs(dirs []string) {
+	for _, dir := range dirs {
+		// Fails (and is ignored) when the directory still holds files
+		_ = os.Remove(dir)
+	}
+}
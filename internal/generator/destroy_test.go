@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"os"
+	"testing"
+
+	"github.com/geomark27/loom-go/internal/golden"
+)
+
+// TestDestroyModule checks that destroying a module restores the files
+// generating and wiring it changed, and keeps files edited since
+func TestDestroyModule(t *testing.T) {
+	for _, architecture := range []string{"layered", "modular"} {
+		for _, router := range []string{RouterGin, RouterChi} {
+			t.Run(goldenName(architecture, router), func(t *testing.T) {
+				root := newGoldenProject(t, architecture, router, true)
+				golden.Chdir(t, root)
+
+				project := &ProjectInfo{
+					Name:         "shop",
+					Architecture: architecture,
+					HasHelpers:   true,
+					RootPath:     ".",
+					ModuleName:   "example.com/shop",
+					Router:       detectedRouter(router),
+				}
+
+				wiring := []string{"internal/platform/server/server.go", "docs/API.md"}
+				if architecture == "layered" {
+					wiring = append(wiring, "internal/platform/server/routes.go")
+				}
+				before := make(map[string]string)
+				for _, p := range wiring {
+					content, err := os.ReadFile(p)
+					if err != nil {
+						t.Fatal(err)
+					}
+					before[p] = string(content)
+				}
+
+				gen := NewModuleGenerator(project)
+				files, err := gen.GenerateModule("products", nil, nil, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := gen.WireModule("products", nil); err != nil {
+					t.Fatal(err)
+				}
+				if err := gen.Commit(); err != nil {
+					t.Fatal(err)
+				}
+
+				// An edited file is left behind
+				edited := files[0]
+				if err := os.WriteFile(edited, []byte("package edited\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				gen = NewModuleGenerator(project)
+				changes, err := gen.Destroy("module", "products", false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := gen.Commit(); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, change := range changes {
+					if change.Kind == ChangeSkipped && change.Path != edited {
+						t.Errorf("%s left behind: %s", change.Path, change.Reason)
+					}
+				}
+				for _, file := range files {
+					if file == "docs/API.md" {
+						continue
+					}
+					_, err := os.Stat(file)
+					if file == edited && err != nil {
+						t.Errorf("edited file %s was deleted", file)
+					}
+					if file != edited && !os.IsNotExist(err) {
+						t.Errorf("%s was not deleted", file)
+					}
+				}
+				for _, p := range wiring {
+					content, err := os.ReadFile(p)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if string(content) != before[p] {
+						t.Errorf("%s not restored:\n%s", p, content)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Field describes a typed attribute of a generated entity.
+// Fields are declared on the command line as name:type[:modifier...], e.g.
+//
+//	name:string price:float64:default=0 email:string:unique bio:text:nullable
+type Field struct {
+	Name     string // snake_case name used for JSON and database columns
+	Type     string // type as written by the user (string, text, int, ...)
+	GoType   string // Go type without pointer (string, int, float64, time.Time...)
+	Unique   bool
+	Nullable bool
+	Index    bool
+	Default  string
+	Size     int
+
+	// Composite indexes this field belongs to (declared in schema files)
+	IndexNames       []string
+	UniqueIndexNames []string
+}
+
+// fieldTypes maps the accepted field types to their Go type
+var fieldTypes = map[string]string{
+	"string":    "string",
+	"text":      "string",
+	"int":       "int",
+	"integer":   "int",
+	"int64":     "int64",
+	"bigint":    "int64",
+	"uint":      "uint",
+	"float":     "float64",
+	"float32":   "float32",
+	"float64":   "float64",
+	"decimal":   "float64",
+	"bool":      "bool",
+	"boolean":   "bool",
+	"time":      "time.Time",
+	"datetime":  "time.Time",
+	"timestamp": "time.Time",
+	"date":      "time.Time",
+}
+
+// reservedFields are always generated and cannot be declared explicitly
+var reservedFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+}
+
+// fieldNamePattern accepts the field names that give Go identifiers
+// ("price", "unit_price", "unitPrice")
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// DefaultFields is used when no fields are given on the command line
+func DefaultFields() []Field {
+	return []Field{{Name: "name", Type: "string", GoType: "string"}}
+}
+
+// ParseFields parses a list of field specifications
+func ParseFields(specs []string) ([]Field, error) {
+	fields := make([]Field, 0, len(specs))
+	seen := make(map[string]bool)
+
+	for _, spec := range specs {
+		field, err := ParseField(spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[field.Name] {
+			return nil, fmt.Errorf("field %q declared more than once", field.Name)
+		}
+		seen[field.Name] = true
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// ParseField parses a single name:type[:modifier...] specification
+func ParseField(spec string) (Field, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return Field{}, fmt.Errorf("invalid field %q (expected name:type)", spec)
+	}
+	if !fieldNamePattern.MatchString(parts[0]) {
+		return Field{}, fmt.Errorf("invalid field name %q: use letters, digits and underscores, starting with a letter", parts[0])
+	}
+
+	field := Field{
+		Name: toSnakeCase(parts[0]),
+		Type: strings.ToLower(parts[1]),
+	}
+
+	if reservedFields[field.Name] {
+		return Field{}, fmt.Errorf("field %q is generated automatically", field.Name)
+	}
+
+	goType, ok := fieldTypes[field.Type]
+	if !ok {
+		return Field{}, fmt.Errorf("unknown type %q for field %q", parts[1], field.Name)
+	}
+	field.GoType = goType
+
+	for _, modifier := range parts[2:] {
+		key, value, hasValue := strings.Cut(modifier, "=")
+		switch strings.ToLower(key) {
+		case "unique":
+			field.Unique = true
+		case "nullable", "null", "optional":
+			field.Nullable = true
+		case "index":
+			field.Index = true
+		case "default":
+			if !hasValue {
+				return Field{}, fmt.Errorf("modifier default for field %q needs a value (default=...)", field.Name)
+			}
+			if err := validateDefault(field.GoType, value); err != nil {
+				return Field{}, fmt.Errorf("invalid default for field %q: %w", field.Name, err)
+			}
+			field.Default = value
+		case "size":
+			size, err := strconv.Atoi(value)
+			if err != nil || size <= 0 {
+				return Field{}, fmt.Errorf("invalid size for field %q: %q", field.Name, value)
+			}
+			field.Size = size
+		default:
+			return Field{}, fmt.Errorf("unknown modifier %q for field %q", modifier, field.Name)
+		}
+	}
+
+	return field, nil
+}
+
+// validateDefault checks that a default value matches the Go type
+func validateDefault(goType, value string) error {
+	var err error
+	switch goType {
+	case "int", "int64":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "uint":
+		_, err = strconv.ParseUint(value, 10, 64)
+	case "float32", "float64":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	}
+	return err
+}
+
+// GoName returns the exported struct field name
+func (f Field) GoName() string {
+	return toPascalCase(f.Name)
+}
+
+// FieldType returns the Go type used in the model (pointer when nullable)
+func (f Field) FieldType() string {
+	if f.Nullable {
+		return "*" + f.GoType
+	}
+	return f.GoType
+}
+
+// IsTime reports whether the field needs the time package
+func (f Field) IsTime() bool {
+	return f.GoType == "time.Time"
+}
+
+// Required reports whether the field must be present on creation
+func (f Field) Required() bool {
+	// A required bool would reject false, so booleans are never required
+	return !f.Nullable && f.Default == "" && f.GoType != "bool"
+}
+
+// GORMTag returns the content of the gorm struct tag
+func (f Field) GORMTag() string {
+	var parts []string
+
+	switch {
+	case f.Type == "text":
+		parts = append(parts, "type:text")
+	case f.Type == "date":
+		parts = append(parts, "type:date")
+	case f.Size > 0:
+		parts = append(parts, fmt.Sprintf("size:%d", f.Size))
+	case f.GoType == "string":
+		parts = append(parts, "size:255")
+	}
+
+	if !f.Nullable {
+		parts = append(parts, "not null")
+	}
+	if f.Unique {
+		parts = append(parts, "uniqueIndex")
+	} else if f.Index {
+		parts = append(parts, "index")
+	}
+	for _, name := range f.IndexNames {
+		parts = append(parts, "index:"+name)
+	}
+	for _, name := range f.UniqueIndexNames {
+		parts = append(parts, "uniqueIndex:"+name)
+	}
+	if f.Default != "" {
+		parts = append(parts, "default:"+f.Default)
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// StructTag returns the struct tag of the model field: json, and gorm
+// unless the field has no column constraint (a nullable time)
+func (f Field) StructTag() string {
+	tag := fmt.Sprintf(`json:"%s"`, f.Name)
+	if gorm := f.GORMTag(); gorm != "" {
+		tag += fmt.Sprintf(` gorm:"%s"`, gorm)
+	}
+	return tag
+}
+
+// ValidateTag returns the content of the validate struct tag understood by
+// helpers.ValidateStruct ("" when there is nothing to validate)
+func (f Field) ValidateTag() string {
+	var rules []string
+	if f.Required() {
+		rules = append(rules, "required")
+	}
+	if f.GoType == "string" && strings.Contains(f.Name, "email") {
+		rules = append(rules, "email")
+	}
+	return strings.Join(rules, ",")
+}
+
+// CreateTag returns the struct tag of the field in Create DTOs
+func (f Field) CreateTag() string {
+	tag := fmt.Sprintf("json:\"%s\"", f.Name)
+	if f.Nullable {
+		tag = fmt.Sprintf("json:\"%s,omitempty\"", f.Name)
+	}
+	if rules := f.ValidateTag(); rules != "" {
+		tag += fmt.Sprintf(" validate:\"%s\"", rules)
+	}
+	return tag
+}
+
+// UpdateTag returns the struct tag of the field in Update DTOs, where
+// every field is optional
+func (f Field) UpdateTag() string {
+	tag := fmt.Sprintf("json:\"%s,omitempty\"", f.Name)
+	if f.GoType == "string" && strings.Contains(f.Name, "email") {
+		tag += ` validate:"email"`
+	}
+	return tag
+}
+
+// DocNotes returns the constraints listed in the API documentation
+func (f Field) DocNotes() string {
+	var notes []string
+	if f.Unique {
+		notes = append(notes, "unique")
+	}
+	if f.Nullable {
+		notes = append(notes, "nullable")
+	}
+	if f.Default != "" {
+		notes = append(notes, "default: "+f.Default)
+	}
+	return strings.Join(notes, ", ")
+}
+
+// ExampleValue returns a JSON example value used in the API documentation
+func (f Field) ExampleValue() string {
+	if f.Default != "" {
+		if f.GoType == "string" {
+			return strconv.Quote(f.Default)
+		}
+		return f.Default
+	}
+
+	switch f.GoType {
+	case "string":
+		if strings.Contains(f.Name, "email") {
+			return `"user@example.com"`
+		}
+		return fmt.Sprintf("%q", "example "+strings.ReplaceAll(f.Name, "_", " "))
+	case "int", "int64", "uint":
+		return "1"
+	case "float32", "float64":
+		return "9.99"
+	case "bool":
+		return "true"
+	case "time.Time":
+		return `"2025-01-01T00:00:00Z"`
+	}
+	return "null"
+}
+
+// needsTimeImport reports whether any field uses time.Time
+func needsTimeImport(fields []Field) bool {
+	for _, f := range fields {
+		if f.IsTime() {
+			return true
+		}
+	}
+	return false
+}
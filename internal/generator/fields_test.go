@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		spec    string
+		name    string
+		goType  string
+		wantErr string
+	}{
+		{spec: "price:float64", name: "price", goType: "float64"},
+		{spec: "unitPrice:decimal", name: "unit_price", goType: "float64"},
+		{spec: "sku_code:string:unique", name: "sku_code", goType: "string"},
+		{spec: "na$me:string", wantErr: "invalid field name"},
+		{spec: "1st:string", wantErr: "invalid field name"},
+		{spec: "full-name:string", wantErr: "invalid field name"},
+		{spec: "name", wantErr: "expected name:type"},
+		{spec: "id:uint", wantErr: "generated automatically"},
+		{spec: "name:blob", wantErr: "unknown type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			field, err := ParseField(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseField(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if field.Name != tt.name || field.GoType != tt.goType {
+				t.Errorf("ParseField(%q) = %s %s, want %s %s", tt.spec, field.Name, field.GoType, tt.name, tt.goType)
+			}
+		})
+	}
+}
+
+func TestToCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"price":       "price",
+		"order_items": "orderItems",
+		"OrderItems":  "orderItems",
+		"sku":         "sku",
+		"api_key":     "apiKey",
+		"productID":   "productID",
+		"category_id": "categoryID",
+		"HTTPServer":  "httpServer",
+	}
+	for name, want := range tests {
+		if got := toCamelCase(name); got != want {
+			t.Errorf("toCamelCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestStructTag(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "name:string", want: `json:"name" gorm:"size:255;not null"`},
+		{spec: "sku:string:unique:size=64", want: `json:"sku" gorm:"size:64;not null;uniqueIndex"`},
+		// A nullable time has no column constraint: no empty gorm tag
+		{spec: "archived_at:time:nullable", want: `json:"archived_at"`},
+	}
+	for _, tt := range tests {
+		field, err := ParseField(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := field.StructTag(); got != tt.want {
+			t.Errorf("StructTag(%s) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
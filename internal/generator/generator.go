@@ -1,10 +1,17 @@
 package generator
 
 import (
+	"bytes"
 	"fmt"
+	"go/format"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
+
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/state"
 )
 
 // ProjectConfig contains the configuration for generating a project
@@ -17,11 +24,57 @@ type ProjectConfig struct {
 	IsModular    bool   // If true, uses modular architecture instead of layered
 	Architecture string // "layered" or "modular"
 	LoomVersion  string // Loom version to use in go.mod (injected automatically)
+	Pack         *Pack  // Template pack providing the project skeleton (optional)
+	Router       string // HTTP router of the skeleton (Router* constants, "" for gin)
+	CI           bool   // If true, adds a GitHub Actions workflow
+
+	// Addons are installed into the project once its files are generated
+	// (e.g. gorm, postgres, jwt, docker), through Installer
+	Addons    []string
+	Installer AddonInstaller
+}
+
+// AddonInstaller installs addons into a generated project. It is
+// implemented by addon.AddonManager, created for the project path.
+type AddonInstaller interface {
+	InstallAddons(names []string) error
+}
+
+// HTTP returns the router the project skeleton is generated for
+func (config *ProjectConfig) HTTP() HTTPRouter {
+	if config.Router == "" {
+		return routerFor(RouterGin)
+	}
+	return routerFor(config.Router)
+}
+
+// manifest returns the manifest of the project skeleton. The addons
+// record themselves in it as they are installed.
+func (config *ProjectConfig) manifest() *state.Manifest {
+	router := config.HTTP().Name
+	if router == RouterNetHTTP {
+		router = "none" // as reported by addon.ProjectDetector
+	}
+
+	m := &state.Manifest{
+		Version:      config.LoomVersion,
+		Architecture: config.Architecture,
+		Router:       router,
+		ORM:          "none",
+		Databases:    []string{},
+		Auth:         "none",
+	}
+	if config.Pack != nil {
+		m.PinPack(config.Pack.Name, config.Pack.Version)
+	}
+	return m
 }
 
 // Generator is responsible for generating projects
 type Generator struct {
 	templates map[string]string
+	state     *state.State   // generation state of the project being created
+	changes   *changeset.Set // project files, written together once rendered
 }
 
 // New creates a new generator instance
@@ -31,13 +84,78 @@ func New() *Generator {
 	}
 }
 
-// GenerateProject generates a new project based on the configuration
+// track records a project file in the generation state, with the
+// template it was rendered from
+func (g *Generator) track(config *ProjectConfig, filePath string, content []byte, template string) {
+	if rel, err := filepath.Rel(config.Path, filePath); err == nil {
+		g.state.Record(rel, content, "project", template)
+	}
+}
+
+// GenerateProject generates a new project based on the configuration,
+// then installs its addons. When the project directory is created by this
+// call and any step fails, the directory is removed again.
 func (g *Generator) GenerateProject(config *ProjectConfig) error {
+	_, err := os.Stat(config.Path)
+	created := os.IsNotExist(err)
+
+	if err := g.generateProject(config); err != nil {
+		if created {
+			os.RemoveAll(config.Path)
+		}
+		return err
+	}
+	return nil
+}
+
+// generateProject renders the project files into a change set, commits
+// them and installs the addons
+func (g *Generator) generateProject(config *ProjectConfig) error {
+	st, err := state.Load(config.Path)
+	if err != nil {
+		return err
+	}
+	g.state = st
+	g.changes = changeset.New()
+
+	if err := g.generateProjectFiles(config); err != nil {
+		return err
+	}
+	if err := g.state.SaveTo(g.changes); err != nil {
+		return fmt.Errorf("error saving generation state: %w", err)
+	}
+	if err := config.manifest().SaveTo(config.Path, g.changes); err != nil {
+		return fmt.Errorf("error saving project manifest: %w", err)
+	}
+	if err := g.changes.Commit(); err != nil {
+		return err
+	}
+
+	if len(config.Addons) == 0 {
+		return nil
+	}
+	if config.Installer == nil {
+		return fmt.Errorf("no addon installer to install %s", strings.Join(config.Addons, ", "))
+	}
+	if err := config.Installer.InstallAddons(config.Addons); err != nil {
+		return fmt.Errorf("error installing addons: %w", err)
+	}
+
+	return nil
+}
+
+// generateProjectFiles stages the skeleton of a new project. Only the
+// (empty) directories of the architecture are created right away.
+func (g *Generator) generateProjectFiles(config *ProjectConfig) error {
 	// Create the project root directory
 	if err := os.MkdirAll(config.Path, 0755); err != nil {
 		return fmt.Errorf("error creating project directory: %w", err)
 	}
 
+	if skeleton := config.skeleton(); skeleton != nil && skeleton.Replace {
+		return g.generateSkeleton(config)
+	}
+
 	// Create directory structure based on architecture
 	dirs := g.getDirectories(config)
 
@@ -49,6 +167,9 @@ func (g *Generator) GenerateProject(config *ProjectConfig) error {
 
 	// Generate files from templates based on architecture
 	files := g.getFileMapping(config)
+	if config.CI {
+		files[filepath.Join(config.Path, ".github", "workflows", "ci.yml")] = "ci.yml.tmpl"
+	}
 
 	for filePath, templateName := range files {
 		if err := g.generateFile(filePath, templateName, config); err != nil {
@@ -56,9 +177,72 @@ func (g *Generator) GenerateProject(config *ProjectConfig) error {
 		}
 	}
 
+	if config.skeleton() != nil {
+		return g.generateSkeleton(config)
+	}
+
 	return nil
 }
 
+// skeleton returns the pack skeleton of the project, if any
+func (config *ProjectConfig) skeleton() *PackSkeleton {
+	if config.Pack == nil {
+		return nil
+	}
+	return config.Pack.Skeleton
+}
+
+// generateSkeleton stages the files of the pack skeleton
+func (g *Generator) generateSkeleton(config *ProjectConfig) error {
+	root := filepath.Join(config.Pack.Dir, config.Pack.Skeleton.Dir)
+
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		source, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel, err := executeString(source, filepath.ToSlash(source), config)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(rel, ".tmpl") {
+			rel = strings.TrimSuffix(rel, ".tmpl")
+			rendered, err := executeString(rel, string(content), config)
+			if err != nil {
+				return err
+			}
+			content = []byte(rendered)
+		}
+
+		target := filepath.Join(config.Path, filepath.FromSlash(rel))
+		g.track(config, target, content, packTemplate(config.Pack, filepath.Join(config.Pack.Skeleton.Dir, source)))
+		return g.changes.WriteFile(target, content)
+	})
+}
+
+// executeString executes a template given by its content
+func executeString(name, content string, config *ProjectConfig) (string, error) {
+	tmpl, err := template.New(name).Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template %s: %w", name, err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, config); err != nil {
+		return "", fmt.Errorf("error executing template %s: %w", name, err)
+	}
+	return out.String(), nil
+}
+
 // getDirectories returns the directories to create based on the architecture
 func (g *Generator) getDirectories(config *ProjectConfig) []string {
 	if config.IsModular {
@@ -203,25 +387,43 @@ func (g *Generator) getModularFiles(config *ProjectConfig) map[string]string {
 
 // generateFile generates a specific file using a template
 func (g *Generator) generateFile(filePath, templateName string, config *ProjectConfig) error {
+	content, err := g.render(filePath, templateName, config)
+	if err != nil {
+		return err
+	}
+
+	if err := g.changes.WriteFile(filePath, content); err != nil {
+		return fmt.Errorf("error creating file %s: %w", filePath, err)
+	}
+	g.track(config, filePath, content, templatePath(templateName))
+
+	return nil
+}
+
+// render renders the template of a project file
+func (g *Generator) render(filePath, templateName string, config *ProjectConfig) ([]byte, error) {
 	templateContent, exists := g.templates[templateName]
 	if !exists {
-		return fmt.Errorf("template %s not found", templateName)
+		return nil, fmt.Errorf("template %s not found", templateName)
 	}
 
 	tmpl, err := template.New(templateName).Parse(templateContent)
 	if err != nil {
-		return fmt.Errorf("error parsing template %s: %w", templateName, err)
+		return nil, fmt.Errorf("error parsing template %s: %w", templateName, err)
 	}
 
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("error creating file %s: %w", filePath, err)
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, config); err != nil {
+		return nil, fmt.Errorf("error executing template %s: %w", templateName, err)
 	}
-	defer file.Close()
 
-	if err := tmpl.Execute(file, config); err != nil {
-		return fmt.Errorf("error executing template %s: %w", templateName, err)
+	// Router-specific sections leave uneven indentation behind; an
+	// unparsable file is staged as is and rejected by the commit
+	content := out.Bytes()
+	if strings.HasSuffix(filePath, ".go") {
+		if formatted, err := format.Source(content); err == nil {
+			content = formatted
+		}
 	}
-
-	return nil
+	return content, nil
 }
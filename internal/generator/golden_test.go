@@ -0,0 +1,244 @@
+package generator
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/geomark27/loom-go/internal/golden"
+	"github.com/geomark27/loom-go/internal/source"
+	"github.com/geomark27/loom-go/internal/state"
+)
+
+// newGoldenProject generates a project in a temporary directory
+func newGoldenProject(t *testing.T, architecture, router string, helpers bool) string {
+	t.Helper()
+
+	// Template overrides of the user must not leak into the output
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	config := &ProjectConfig{
+		Name:         "shop",
+		Path:         filepath.Join(t.TempDir(), "shop"),
+		ModuleName:   "example.com/shop",
+		Description:  "shop project generated with Loom",
+		UseHelpers:   helpers,
+		IsModular:    architecture == "modular",
+		Architecture: architecture,
+		LoomVersion:  "1.0.0",
+		Router:       router,
+		CI:           true,
+	}
+	if err := New().GenerateProject(config); err != nil {
+		t.Fatalf("GenerateProject: %v", err)
+	}
+	return config.Path
+}
+
+// goldenName names the golden file of a combination ("net/http" becomes
+// "nethttp")
+func goldenName(parts ...string) string {
+	return strings.NewReplacer("/", "", " ", "-").Replace(strings.Join(parts, " "))
+}
+
+// assertTree compares the given files of a generated project with a
+// golden file, after checking that the Go sources parse
+func assertTree(t *testing.T, root, name string, files []string) {
+	t.Helper()
+	golden.ParseGo(t, root, files)
+
+	archive, err := golden.Archive(root, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, name, archive)
+}
+
+// TestProjectGolden renders the "loom new" skeleton for every
+// architecture and router, and checks every template of getTemplates()
+// is covered (here or by the ORM addon tests)
+func TestProjectGolden(t *testing.T) {
+	rendered := make(map[string]bool)
+
+	for _, architecture := range []string{"layered", "modular"} {
+		for _, router := range PresetRouters {
+			combos := []bool{true}
+			if router == RouterGin {
+				combos = append(combos, false)
+			}
+			for _, helpers := range combos {
+				name := goldenName("project", architecture, router)
+				if !helpers {
+					name += "-standalone"
+				}
+
+				t.Run(name, func(t *testing.T) {
+					root := newGoldenProject(t, architecture, router, helpers)
+
+					files, err := golden.Files(root)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assertTree(t, root, name, files)
+
+					st, err := state.Load(root)
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, template := range st.Templates() {
+						rendered[template] = true
+					}
+				})
+			}
+		}
+	}
+
+	// The database and console templates are rendered by the ORM addon
+	// with its own data (see the addon tests)
+	for key := range templateFiles {
+		if strings.HasPrefix(key, "database/") || strings.HasPrefix(key, "console/") {
+			continue
+		}
+		if !rendered[templatePath(key)] {
+			t.Errorf("template %s is not rendered by any project", key)
+		}
+	}
+}
+
+// TestModuleGolden runs every ModuleGenerator generator for both
+// architectures, every router and with and without GORM
+func TestModuleGolden(t *testing.T) {
+	fields, err := ParseFields([]string{
+		"name:string",
+		"price:float64",
+		"sku:string:unique:size=64",
+		"bio:text:nullable",
+		"published_at:time",
+		"active:bool:default=true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, architecture := range []string{"layered", "modular"} {
+		relations := []Relation{{Kind: RelationBelongsTo, Entity: "category"}}
+		if architecture == "layered" {
+			relations = append(relations,
+				Relation{Kind: RelationHasMany, Entity: "reviews"},
+				Relation{Kind: RelationManyToMany, Entity: "tags"})
+		}
+
+		for _, router := range []string{RouterGin, RouterChi, RouterEcho, RouterGorillaMux, RouterNetHTTP} {
+			for _, orm := range []string{"", "gorm"} {
+				name := goldenName("module", architecture, router)
+				if orm != "" {
+					name += "-" + orm
+				}
+
+				t.Run(name, func(t *testing.T) {
+					root := newGoldenProject(t, architecture, router, true)
+					golden.Chdir(t, root)
+					if orm == "gorm" {
+						installGORM(t, architecture)
+					}
+
+					gen := NewModuleGenerator(&ProjectInfo{
+						Name:         "shop",
+						Architecture: architecture,
+						HasHelpers:   true,
+						RootPath:     ".",
+						ModuleName:   "example.com/shop",
+						Router:       detectedRouter(router),
+						ORM:          orm,
+					})
+
+					steps := []struct {
+						name string
+						run  func() error
+					}{
+						{"GenerateModule", func() error {
+							_, err := gen.GenerateModule("products", fields, relations, false)
+							return err
+						}},
+						{"WireModule", func() error {
+							_, err := gen.WireModule("products", relations)
+							return err
+						}},
+						{"GenerateHandler", func() error {
+							_, err := gen.GenerateHandler("widget", false)
+							return err
+						}},
+						{"GenerateService", func() error {
+							_, err := gen.GenerateService("widget", false)
+							return err
+						}},
+						{"GenerateModel", func() error {
+							_, err := gen.GenerateModel("gadget", fields[:2], false)
+							return err
+						}},
+						{"GenerateMiddleware", func() error {
+							_, err := gen.GenerateMiddleware("audit", false)
+							return err
+						}},
+						{"Commit", gen.Commit},
+					}
+					for _, step := range steps {
+						if err := step.run(); err != nil {
+							t.Fatalf("%s: %v", step.name, err)
+						}
+					}
+
+					files, err := golden.Changed(".")
+					if err != nil {
+						t.Fatal(err)
+					}
+					assertTree(t, ".", name, files)
+				})
+			}
+		}
+	}
+}
+
+// installGORM adds what "loom add orm gorm" gives the generators to work
+// with: the GORM requirements and the model registry
+func installGORM(t *testing.T, architecture string) {
+	t.Helper()
+
+	for _, require := range [][2]string{{"gorm.io/gorm", "v1.25.5"}, {"gorm.io/driver/postgres", "v1.5.4"}} {
+		if _, err := source.AddRequire(source.Disk, "go.mod", require[0], require[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modelsImport := "example.com/shop/internal/app/models"
+	if architecture == "modular" {
+		modelsImport = "example.com/shop/internal/modules/users"
+	}
+	registry := `package database
+
+import (
+	models "` + modelsImport + `"
+)
+
+var AllModels = []interface{}{
+	&models.User{},
+}
+`
+	if err := os.MkdirAll(path.Dir(ModelsRegistryPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ModelsRegistryPath, []byte(registry), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// detectedRouter returns the router name addon.ProjectDetector reports
+// for a project generated with router
+func detectedRouter(router string) string {
+	if router == RouterNetHTTP {
+		return "none"
+	}
+	return router
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Code fragments shared by the module templates that depend on the
+// declared fields. Each fragment ends with a newline so it can be spliced
+// directly into a struct or function body.
+
+// modelFieldLines returns the model struct fields with json and gorm tags
+func modelFieldLines(fields []Field) string {
+	var b strings.Builder
+	for _, f := range fields {
+		fmt.Fprintf(&b, "\t%s %s `%s`\n", f.GoName(), f.FieldType(), f.StructTag())
+	}
+	return b.String()
+}
+
+// appendModuleDocs appends the endpoints of a module to docs/API.md.
+// Returns the documentation path when it was updated.
+func (g *ModuleGenerator) appendModuleDocs(name string, fields []Field, relations []Relation) (string, error) {
+	docsPath := "docs/API.md"
+
+	content, err := g.changes.ReadFile(docsPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	nameLower := strings.ToLower(name)
+	marker := fmt.Sprintf("<!-- loom:module %s -->", nameLower)
+	if strings.Contains(string(content), marker) {
+		return "", nil
+	}
+
+	section, err := renderComponent("docs.md.tmpl", g.componentData(name, fields, relations))
+	if err != nil {
+		return "", err
+	}
+	section = marker + "\n" + section
+
+	docs := string(content)
+	if !strings.HasSuffix(docs, "\n") {
+		docs += "\n"
+	}
+
+	return docsPath, g.changes.WriteFile(docsPath, []byte(docs+"\n"+section))
+}
+
+// removeModuleDocs removes the section appendModuleDocs added for a
+// module, up to the next module section. Returns the docs path when it
+// was updated.
+func (g *ModuleGenerator) removeModuleDocs(name string) (string, error) {
+	docsPath := "docs/API.md"
+
+	content, err := g.changes.ReadFile(docsPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	docs := string(content)
+	start := strings.Index(docs, fmt.Sprintf("<!-- loom:module %s -->", strings.ToLower(name)))
+	if start < 0 {
+		return "", nil
+	}
+	end := len(docs)
+	if next := strings.Index(docs[start+1:], "<!-- loom:module "); next >= 0 {
+		end = start + 1 + next
+	}
+	// The blank line added before the last section goes too
+	if end == len(docs) && strings.HasSuffix(docs[:start], "\n\n") {
+		start--
+	}
+
+	return docsPath, g.changes.WriteFile(docsPath, []byte(docs[:start]+docs[end:]))
+}
@@ -2,880 +2,390 @@ package generator
 
 import (
 	"fmt"
-	"os"
+	"go/format"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
-)
-
-// ModuleGenerator generates complete modules or individual components
-type ModuleGenerator struct {
-	project *ProjectInfo
-}
-
-// NewModuleGenerator creates a new instance of the module generator
-func NewModuleGenerator(project *ProjectInfo) *ModuleGenerator {
-	return &ModuleGenerator{
-		project: project,
-	}
-}
-
-// GenerateModule generates a complete module
-func (g *ModuleGenerator) GenerateModule(name string, force bool, dryRun bool) ([]string, error) {
-	var files []string
-
-	if g.project.Architecture == "layered" {
-		files = g.generateLayeredModule(name, force, dryRun)
-	} else {
-		files = g.generateModularModule(name, force, dryRun)
-	}
-
-	return files, nil
-}
-
-// generateLayeredModule generates module in layered architecture
-func (g *ModuleGenerator) generateLayeredModule(name string, force bool, dryRun bool) []string {
-	files := []string{}
-
-	nameLower := strings.ToLower(name)
-	nameTitle := strings.Title(nameLower)
-
-	// Define files to create
-	filesToCreate := map[string]string{
-		fmt.Sprintf("internal/app/handlers/%s_handler.go", nameLower):        g.getHandlerTemplate(nameTitle, nameLower),
-		fmt.Sprintf("internal/app/services/%s_service.go", nameLower):        g.getServiceTemplate(nameTitle, nameLower),
-		fmt.Sprintf("internal/app/repositories/%s_repository.go", nameLower): g.getRepositoryTemplate(nameTitle, nameLower),
-		fmt.Sprintf("internal/app/models/%s.go", nameLower):                  g.getModelTemplate(nameTitle, nameLower),
-		fmt.Sprintf("internal/app/dtos/%s_dto.go", nameLower):                g.getDTOTemplate(nameTitle, nameLower),
-	}
-
-	// Generate each file
-	for filePath, content := range filesToCreate {
-		if err := g.createFile(filePath, content, force, dryRun); err != nil {
-			fmt.Printf("⚠️  %s: %v\n", filePath, err)
-			continue
-		}
-		files = append(files, filePath)
-	}
-
-	return files
-}
-
-// generateModularModule generates module in modular architecture
-func (g *ModuleGenerator) generateModularModule(name string, force bool, dryRun bool) []string {
-	files := []string{}
-
-	nameLower := strings.ToLower(name)
-	nameTitle := strings.Title(nameLower)
-	moduleDir := filepath.Join("internal/modules", nameLower)
-
-	// Create module directory
-	if !dryRun {
-		os.MkdirAll(moduleDir, 0755)
-	}
-
-	// Define files to create
-	filesToCreate := map[string]string{
-		filepath.Join(moduleDir, "handler.go"):    g.getModularHandlerTemplate(nameTitle, nameLower),
-		filepath.Join(moduleDir, "service.go"):    g.getModularServiceTemplate(nameTitle, nameLower),
-		filepath.Join(moduleDir, "repository.go"): g.getModularRepositoryTemplate(nameTitle, nameLower),
-		filepath.Join(moduleDir, "model.go"):      g.getModularModelTemplate(nameTitle, nameLower),
-		filepath.Join(moduleDir, "dto.go"):        g.getModularDTOTemplate(nameTitle, nameLower),
-		filepath.Join(moduleDir, "module.go"):     g.getModularModuleTemplate(nameTitle, nameLower),
-		filepath.Join(moduleDir, "ports.go"):      g.getModularPortsTemplate(nameTitle, nameLower),
-		filepath.Join(moduleDir, "errors.go"):     g.getModularErrorsTemplate(nameTitle, nameLower),
-	}
-
-	// Generate each file
-	for filePath, content := range filesToCreate {
-		if err := g.createFile(filePath, content, force, dryRun); err != nil {
-			fmt.Printf("⚠️  %s: %v\n", filePath, err)
-			continue
-		}
-		files = append(files, filePath)
-	}
-
-	return files
-}
 
-// createFile creates a file with the given content
-func (g *ModuleGenerator) createFile(filePath, content string, force bool, dryRun bool) error {
-	// Check if file already exists
-	if _, err := os.Stat(filePath); err == nil && !force {
-		return fmt.Errorf("already exists (use --force to overwrite)")
-	}
-
-	if dryRun {
-		return nil
-	}
-
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-
-	// Write file
-	return os.WriteFile(filePath, []byte(content), 0644)
-}
-
-// Templates for Layered architecture
-
-func (g *ModuleGenerator) getHandlerTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package handlers
-
-import (
-	"encoding/json"
-	"net/http"
-	"strconv"
-
-	"github.com/gorilla/mux"
-	"%s/internal/app/dtos"
-	"%s/internal/app/services"
+	"github.com/geomark27/loom-go/internal/changeset"
+	"github.com/geomark27/loom-go/internal/source"
+	"github.com/geomark27/loom-go/internal/state"
 )
 
-type %sHandler struct {
-	service *services.%sService
+// ModuleGenerator generates complete modules or individual components.
+//
+// Generators only stage their writes in a change set: nothing reaches the
+// disk until Commit, and a dry run prints Changes() instead.
+type ModuleGenerator struct {
+	project   *ProjectInfo
+	state     *state.State
+	manifest  *state.Manifest // nil for projects without a manifest
+	changes   *changeset.Set
+	templates map[string]string // rendered path -> template
+	previous  map[string]previousRecord
+	prune     []string // directories to remove when a commit leaves them empty
 }
 
-func New%sHandler(service *services.%sService) *%sHandler {
-	return &%sHandler{
-		service: service,
-	}
+// previousRecord is the state record of a file before this run tracked it
+type previousRecord struct {
+	file state.GeneratedFile
+	ok   bool
 }
 
-// List gets all %s
-func (h *%sHandler) List(w http.ResponseWriter, r *http.Request) {
-	items, err := h.service.GetAll()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(items)
+// PlannedFile is a file rendered by a generator before it is written
+type PlannedFile struct {
+	Path    string
+	Content string
 }
 
-// GetByID gets a %s by ID
-func (h *%sHandler) GetByID(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	item, err := h.service.GetByID(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(item)
-}
-
-// Create creates a new %s
-func (h *%sHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var dto dtos.Create%sDTO
-	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	item, err := h.service.Create(&dto)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// NewModuleGenerator creates a new instance of the module generator
+func NewModuleGenerator(project *ProjectInfo) *ModuleGenerator {
+	g := &ModuleGenerator{
+		project:   project,
+		changes:   changeset.New(),
+		templates: make(map[string]string),
+		previous:  make(map[string]previousRecord),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(item)
-}
-
-// Update updates a %s
-func (h *%sHandler) Update(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	// The generation state lets later runs detect files edited by the user
+	st, err := state.Load(project.RootPath)
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	var dto dtos.Update%sDTO
-	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		fmt.Printf("⚠️  %v (generated files will not be tracked)\n", err)
+	} else {
+		g.state = st
+		g.changes.UseBases(st)
 	}
 
-	item, err := h.service.Update(id, &dto)
+	// Generated modules are recorded in the project manifest
+	manifest, err := state.LoadManifest(project.RootPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		fmt.Printf("⚠️  %v (generated modules will not be recorded)\n", err)
 	}
+	g.manifest = manifest
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(item)
+	return g
 }
 
-// Delete deletes a %s
-func (h *%sHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	if err := h.service.Delete(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusNoContent)
+// Changes returns the writes staged by the generators
+func (g *ModuleGenerator) Changes() *changeset.Set {
+	return g.changes
 }
-`, g.project.ModuleName, g.project.ModuleName, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle,
-		nameLower, nameTitle, nameLower, nameTitle, nameLower, nameTitle, nameTitle,
-		nameLower, nameTitle, nameTitle, nameLower, nameTitle)
-}
-
-func (g *ModuleGenerator) getServiceTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package services
-
-import (
-	"fmt"
 
-	"%s/internal/app/dtos"
-	"%s/internal/app/models"
-	"%s/internal/app/repositories"
-)
-
-type %sService struct {
-	repo *repositories.%sRepository
+// Conflicts returns the staged files merged with conflicts
+func (g *ModuleGenerator) Conflicts() []string {
+	return g.changes.Conflicts()
 }
 
-func New%sService(repo *repositories.%sRepository) *%sService {
-	return &%sService{
-		repo: repo,
+// Commit writes the staged files and the generation state. When any write
+// fails, the project is left as it was.
+func (g *ModuleGenerator) Commit() error {
+	if g.state != nil {
+		if err := g.state.SaveTo(g.changes); err != nil {
+			return fmt.Errorf("error saving generation state: %w", err)
+		}
 	}
-}
-
-func (s *%sService) GetAll() ([]*models.%s, error) {
-	return s.repo.FindAll()
-}
-
-func (s *%sService) GetByID(id int) (*models.%s, error) {
-	item, err := s.repo.FindByID(id)
-	if err != nil {
-		return nil, fmt.Errorf("%s not found")
+	if g.manifest != nil {
+		if err := g.manifest.SaveTo(g.project.RootPath, g.changes); err != nil {
+			return fmt.Errorf("error saving project manifest: %w", err)
+		}
 	}
-	return item, nil
-}
-
-func (s *%sService) Create(dto *dtos.Create%sDTO) (*models.%s, error) {
-	item := &models.%s{
-		Name: dto.Name,
-		// TODO: Map more fields according to your DTO
+	if err := g.changes.Commit(); err != nil {
+		return err
 	}
-
-	return s.repo.Create(item)
+	removeEmptyDirs(g.prune)
+	return nil
 }
 
-func (s *%sService) Update(id int, dto *dtos.Update%sDTO) (*models.%s, error) {
-	item, err := s.repo.FindByID(id)
+// GenerateModule generates a complete module.
+// When fields is empty a single "name:string" field is used.
+func (g *ModuleGenerator) GenerateModule(name string, fields []Field, relations []Relation, force bool) ([]string, error) {
+	plan, err := g.PlanModule(name, fields, relations)
 	if err != nil {
-		return nil, fmt.Errorf("%s not found")
-	}
-
-	if dto.Name != nil {
-		item.Name = *dto.Name
-	}
-	// TODO: Update more fields according to your DTO
-
-	return s.repo.Update(item)
-}
-
-func (s *%sService) Delete(id int) error {
-	return s.repo.Delete(id)
-}
-`, g.project.ModuleName, g.project.ModuleName, g.project.ModuleName, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle,
-		nameTitle, nameTitle, nameTitle, nameTitle, nameLower, nameTitle, nameTitle, nameTitle, nameTitle,
-		nameTitle, nameTitle, nameTitle, nameLower, nameTitle)
-}
-
-func (g *ModuleGenerator) getRepositoryTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package repositories
-
-import (
-	"fmt"
-	"sync"
-
-	"%s/internal/app/models"
-)
-
-type %sRepository struct {
-	data   map[int]*models.%s
-	nextID int
-	mu     sync.RWMutex
-}
-
-func New%sRepository() *%sRepository {
-	return &%sRepository{
-		data:   make(map[int]*models.%s),
-		nextID: 1,
-	}
-}
-
-func (r *%sRepository) FindAll() ([]*models.%s, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	items := make([]*models.%s, 0, len(r.data))
-	for _, item := range r.data {
-		items = append(items, item)
-	}
-
-	return items, nil
-}
-
-func (r *%sRepository) FindByID(id int) (*models.%s, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	item, exists := r.data[id]
-	if !exists {
-		return nil, fmt.Errorf("%s not found")
-	}
-
-	return item, nil
-}
-
-func (r *%sRepository) Create(item *models.%s) (*models.%s, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	item.ID = r.nextID
-	r.nextID++
-
-	r.data[item.ID] = item
-
-	return item, nil
-}
-
-func (r *%sRepository) Update(item *models.%s) (*models.%s, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if _, exists := r.data[item.ID]; !exists {
-		return nil, fmt.Errorf("%s not found")
+		return nil, err
 	}
 
-	r.data[item.ID] = item
-
-	return item, nil
-}
-
-func (r *%sRepository) Delete(id int) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if _, exists := r.data[id]; !exists {
-		return fmt.Errorf("%s not found")
+	// Layered modules share the models package with the other modules
+	if g.project.Architecture == "layered" {
+		modelPath := fmt.Sprintf("internal/app/models/%s.go", strings.ToLower(name))
+		if other := g.declaringFile(path.Dir(modelPath), moduleTypeName(name), modelPath); other != "" {
+			return nil, fmt.Errorf("type %s is already declared in %s", moduleTypeName(name), other)
+		}
 	}
 
-	delete(r.data, id)
-
-	return nil
-}
-`, g.project.ModuleName, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle,
-		nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameLower,
-		nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameLower,
-		nameTitle, nameLower)
-}
-
-func (g *ModuleGenerator) getModelTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package models
-
-import "time"
-
-type %s struct {
-	ID        int       `+"`json:\"id\"`"+`
-	Name      string    `+"`json:\"name\"`"+`
-	CreatedAt time.Time `+"`json:\"created_at\"`"+`
-	UpdatedAt time.Time `+"`json:\"updated_at\"`"+`
-	// TODO: Add more fields according to your needs
-}
-`, nameTitle)
-}
-
-func (g *ModuleGenerator) getDTOTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package dtos
-
-type Create%sDTO struct {
-	Name string `+"`json:\"name\" binding:\"required\"`"+`
-	// TODO: Add more fields according to your needs
-}
-
-type Update%sDTO struct {
-	Name *string `+"`json:\"name,omitempty\"`"+`
-	// TODO: Add more fields according to your needs
-}
-`, nameTitle, nameTitle)
-}
-
-// Templates for Modular architecture
-
-func (g *ModuleGenerator) getModularHandlerTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package %s
-
-import (
-	"encoding/json"
-	"net/http"
-	"strconv"
-
-	"github.com/gorilla/mux"
-)
-
-type Handler struct {
-	service Service
-}
-
-func NewHandler(service Service) *Handler {
-	return &Handler{
-		service: service,
+	var files []string
+	for _, planned := range plan {
+		if _, err := g.createFile(planned.Path, planned.Content, force); err != nil {
+			return nil, fmt.Errorf("%s: %w", planned.Path, err)
+		}
+		g.track(planned, "module:"+strings.ToLower(name))
+		files = append(files, planned.Path)
 	}
-}
-
-// RegisterRoutes registers the module routes
-func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/%s", h.List).Methods("GET")
-	router.HandleFunc("/%s/{id}", h.GetByID).Methods("GET")
-	router.HandleFunc("/%s", h.Create).Methods("POST")
-	router.HandleFunc("/%s/{id}", h.Update).Methods("PUT")
-	router.HandleFunc("/%s/{id}", h.Delete).Methods("DELETE")
-}
-
-func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	items, err := h.service.GetAll()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if g.manifest != nil {
+		g.manifest.AddModule(strings.ToLower(name))
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(items)
-}
-
-func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	// Register the model and its many-to-many join tables with the models
+	registryPath, err := g.registerModels(name, relations)
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
+		fmt.Printf("⚠️  models_all.go: %v\n", err)
+	} else if registryPath != "" {
+		files = append(files, registryPath)
 	}
 
-	item, err := h.service.GetByID(id)
+	// Document the new endpoints in docs/API.md
+	docsPath, err := g.appendModuleDocs(name, moduleFields(fields, relations), relations)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
-		return
+		fmt.Printf("⚠️  docs/API.md: %v\n", err)
+	} else if docsPath != "" {
+		files = append(files, docsPath)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(item)
+	return files, nil
 }
 
-func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
-	var dto Create%sDTO
-	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	item, err := h.service.Create(&dto)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// declaringFile returns the Go file of dir, other than skip, declaring
+// typeName, or "" when there is none
+func (g *ModuleGenerator) declaringFile(dir, typeName, skip string) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, match := range matches {
+		match = filepath.ToSlash(match)
+		if match == skip || strings.HasSuffix(match, "_test.go") {
+			continue
+		}
+		file, err := source.LoadFrom(g.changes, match)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(file.TypeNames(), typeName) {
+			return match
+		}
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(item)
+	return ""
 }
 
-func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
+// PlanModule renders every file of a module without touching the disk
+func (g *ModuleGenerator) PlanModule(name string, fields []Field, relations []Relation) ([]PlannedFile, error) {
+	fields = moduleFields(fields, relations)
 
-	var dto Update%sDTO
-	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	var files map[string]string
+	var err error
+	if g.project.Architecture == "layered" {
+		files, err = g.layeredModuleFiles(name, fields, relations)
+	} else {
+		files, err = g.modularModuleFiles(name, fields, relations)
 	}
-
-	item, err := h.service.Update(id, &dto)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(item)
+	return planFiles(files), nil
 }
 
-func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	if err := h.service.Delete(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// UnsupportedRelations returns the relations the project architecture
+// cannot express. Modular modules do not share a models package, so they
+// only reference other modules through belongs_to foreign keys.
+func (g *ModuleGenerator) UnsupportedRelations(relations []Relation) []Relation {
+	if g.project.Architecture == "layered" {
+		return nil
 	}
-
-	w.WriteHeader(http.StatusNoContent)
-}
-`, nameLower, nameLower, nameLower, nameLower, nameLower, nameLower, nameTitle, nameTitle)
-}
-
-func (g *ModuleGenerator) getModularServiceTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package %s
-
-type ServiceImpl struct {
-	repo Repository
-}
-
-func NewService(repo Repository) Service {
-	return &ServiceImpl{
-		repo: repo,
+	var unsupported []Relation
+	for _, r := range relations {
+		if r.Kind != RelationBelongsTo {
+			unsupported = append(unsupported, r)
+		}
 	}
+	return unsupported
 }
 
-func (s *ServiceImpl) GetAll() ([]*%s, error) {
-	return s.repo.FindAll()
-}
-
-func (s *ServiceImpl) GetByID(id int) (*%s, error) {
-	return s.repo.FindByID(id)
-}
-
-func (s *ServiceImpl) Create(dto *Create%sDTO) (*%s, error) {
-	item := &%s{
-		Name: dto.Name,
-		// TODO: Map more fields
+// moduleFields returns the declared fields (or the default ones) followed
+// by the foreign keys of the belongs_to relations
+func moduleFields(fields []Field, relations []Relation) []Field {
+	if len(fields) == 0 {
+		fields = DefaultFields()
 	}
-
-	return s.repo.Create(item)
+	return mergeRelationFields(fields, relations)
 }
 
-func (s *ServiceImpl) Update(id int, dto *Update%sDTO) (*%s, error) {
-	item, err := s.repo.FindByID(id)
-	if err != nil {
-		return nil, err
-	}
+// layeredModuleFiles returns the files of a module in layered architecture
+func (g *ModuleGenerator) layeredModuleFiles(name string, fields []Field, relations []Relation) (map[string]string, error) {
+	nameLower := strings.ToLower(name)
 
-	if dto.Name != nil {
-		item.Name = *dto.Name
+	return g.renderComponents(map[string]string{
+		fmt.Sprintf("internal/app/handlers/%s_handler.go", nameLower):        "layered/handler.go.tmpl",
+		fmt.Sprintf("internal/app/services/%s_service.go", nameLower):        "layered/service.go.tmpl",
+		fmt.Sprintf("internal/app/repositories/%s_repository.go", nameLower): "layered/repository.go.tmpl",
+		fmt.Sprintf("internal/app/models/%s.go", nameLower):                  "layered/model.go.tmpl",
+		fmt.Sprintf("internal/app/dtos/%s_dto.go", nameLower):                "layered/dto.go.tmpl",
+	}, g.componentData(name, fields, relations))
+}
+
+// modularModuleFiles returns the files of a module in modular architecture.
+// Only belongs_to relations are rendered (see UnsupportedRelations).
+func (g *ModuleGenerator) modularModuleFiles(name string, fields []Field, relations []Relation) (map[string]string, error) {
+	moduleDir := path.Join("internal/modules", strings.ToLower(name))
+	relations = filterRelations(relations, RelationBelongsTo)
+
+	return g.renderComponents(map[string]string{
+		path.Join(moduleDir, "handler.go"):    "modular/handler.go.tmpl",
+		path.Join(moduleDir, "service.go"):    "modular/service.go.tmpl",
+		path.Join(moduleDir, "repository.go"): "modular/repository.go.tmpl",
+		path.Join(moduleDir, "model.go"):      "modular/model.go.tmpl",
+		path.Join(moduleDir, "dto.go"):        "modular/dto.go.tmpl",
+		path.Join(moduleDir, "module.go"):     "modular/module.go.tmpl",
+		path.Join(moduleDir, "ports.go"):      "modular/ports.go.tmpl",
+		path.Join(moduleDir, "errors.go"):     "modular/errors.go.tmpl",
+	}, g.componentData(name, fields, relations))
+}
+
+// renderComponents renders a set of component files and remembers the
+// template of each one
+func (g *ModuleGenerator) renderComponents(templates map[string]string, data ComponentData) (map[string]string, error) {
+	for filePath, name := range templates {
+		g.templates[filePath] = path.Join(componentsDir, name)
+	}
+	return renderComponents(templates, data)
+}
+
+// planFiles sorts the rendered files by path and gofmt's Go sources so
+// generated struct tags stay aligned
+func planFiles(files map[string]string) []PlannedFile {
+	planned := make([]PlannedFile, 0, len(files))
+	for filePath, content := range files {
+		if strings.HasSuffix(filePath, ".go") {
+			if formatted, err := format.Source([]byte(content)); err == nil {
+				content = string(formatted)
+			}
+		}
+		planned = append(planned, PlannedFile{Path: filePath, Content: content})
 	}
-	// TODO: Update more fields
-
-	return s.repo.Update(item)
-}
-
-func (s *ServiceImpl) Delete(id int) error {
-	return s.repo.Delete(id)
-}
-`, nameLower, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle)
-}
-
-func (g *ModuleGenerator) getModularRepositoryTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package %s
-
-import (
-	"fmt"
-	"sync"
-)
 
-type RepositoryImpl struct {
-	data   map[int]*%s
-	nextID int
-	mu     sync.RWMutex
-}
+	sort.Slice(planned, func(i, j int) bool {
+		return planned[i].Path < planned[j].Path
+	})
 
-func NewRepository() Repository {
-	return &RepositoryImpl{
-		data:   make(map[int]*%s),
-		nextID: 1,
-	}
+	return planned
 }
 
-func (r *RepositoryImpl) FindAll() ([]*%s, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	items := make([]*%s, 0, len(r.data))
-	for _, item := range r.data {
-		items = append(items, item)
+// createFile stages a file with the given content. Edits made to a file
+// generated before are merged into the new content; reports whether the
+// merge left conflicts.
+func (g *ModuleGenerator) createFile(filePath, content string, force bool) (bool, error) {
+	// Check if file already exists (on disk or staged by this run)
+	if g.changes.Exists(filePath) && !force {
+		return false, fmt.Errorf("already exists (use --force to overwrite)")
 	}
 
-	return items, nil
+	return g.changes.WriteGenerated(filePath, []byte(content))
 }
 
-func (r *RepositoryImpl) FindByID(id int) (*%s, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// writeComponent stages a single generated component file
+func (g *ModuleGenerator) writeComponent(generator, filePath, content string, force bool) ([]string, error) {
+	planned := planFiles(map[string]string{filePath: content})[0]
 
-	item, exists := r.data[id]
-	if !exists {
-		return nil, ErrNotFound
+	if _, err := g.createFile(planned.Path, planned.Content, force); err != nil {
+		return nil, err
 	}
+	g.track(planned, generator)
 
-	return item, nil
-}
-
-func (r *RepositoryImpl) Create(item *%s) (*%s, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	item.ID = r.nextID
-	r.nextID++
-
-	r.data[item.ID] = item
-
-	return item, nil
+	return []string{planned.Path}, nil
 }
 
-func (r *RepositoryImpl) Update(item *%s) (*%s, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if _, exists := r.data[item.ID]; !exists {
-		return nil, ErrNotFound
+// track records a staged file in the generation state, which is saved
+// with the staged files on Commit
+func (g *ModuleGenerator) track(file PlannedFile, generator string) {
+	if g.state == nil {
+		return
 	}
-
-	r.data[item.ID] = item
-
-	return item, nil
-}
-
-func (r *RepositoryImpl) Delete(id int) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if _, exists := r.data[id]; !exists {
-		return ErrNotFound
+	if _, seen := g.previous[file.Path]; !seen {
+		prev, ok := g.state.Lookup(file.Path)
+		g.previous[file.Path] = previousRecord{prev, ok}
 	}
-
-	delete(r.data, id)
-
-	return nil
+	g.state.Record(file.Path, []byte(file.Content), generator, g.templates[file.Path])
 }
-`, nameLower, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle)
-}
-
-func (g *ModuleGenerator) getModularModelTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package %s
-
-import "time"
-
-type %s struct {
-	ID        int       `+"`json:\"id\"`"+`
-	Name      string    `+"`json:\"name\"`"+`
-	CreatedAt time.Time `+"`json:\"created_at\"`"+`
-	UpdatedAt time.Time `+"`json:\"updated_at\"`"+`
-	// TODO: Add more fields
-}
-`, nameLower, nameTitle)
-}
-
-func (g *ModuleGenerator) getModularDTOTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package %s
-
-type Create%sDTO struct {
-	Name string `+"`json:\"name\" binding:\"required\"`"+`
-	// TODO: Add more fields
-}
-
-type Update%sDTO struct {
-	Name *string `+"`json:\"name,omitempty\"`"+`
-	// TODO: Add more fields
-}
-`, nameLower, nameTitle, nameTitle)
-}
-
-func (g *ModuleGenerator) getModularModuleTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package %s
-
-import "github.com/gorilla/mux"
-
-type Module struct {
-	handler *Handler
-}
-
-func NewModule() *Module {
-	repo := NewRepository()
-	service := NewService(repo)
-	handler := NewHandler(service)
 
-	return &Module{
-		handler: handler,
+// Skip drops the staged write of a file, keeping the file (and its state
+// record) as they were
+func (g *ModuleGenerator) Skip(filePath string) {
+	g.changes.Discard(filePath)
+	if prev, seen := g.previous[filePath]; seen && g.state != nil {
+		g.state.Restore(filePath, prev.file, prev.ok)
+		delete(g.previous, filePath)
 	}
 }
 
-func (m *Module) RegisterRoutes(router *mux.Router) {
-	m.handler.RegisterRoutes(router.PathPrefix("/api/v1").Subrouter())
-}
-`, nameLower)
-}
-
-func (g *ModuleGenerator) getModularPortsTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package %s
-
-// Service defines the business methods of the module
-type Service interface {
-	GetAll() ([]*%s, error)
-	GetByID(id int) (*%s, error)
-	Create(dto *Create%sDTO) (*%s, error)
-	Update(id int, dto *Update%sDTO) (*%s, error)
-	Delete(id int) error
-}
-
-// Repository defines the persistence methods of the module
-type Repository interface {
-	FindAll() ([]*%s, error)
-	FindByID(id int) (*%s, error)
-	Create(item *%s) (*%s, error)
-	Update(item *%s) (*%s, error)
-	Delete(id int) error
-}
-`, nameLower, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle,
-		nameTitle, nameTitle, nameTitle, nameTitle, nameTitle, nameTitle)
-}
-
-func (g *ModuleGenerator) getModularErrorsTemplate(nameTitle, nameLower string) string {
-	return fmt.Sprintf(`package %s
-
-import "errors"
-
-var (
-	ErrNotFound      = errors.New("%s not found")
-	ErrInvalidInput  = errors.New("invalid input")
-	ErrAlreadyExists = errors.New("%s already exists")
-)
-`, nameLower, nameLower, nameLower)
+// Merge replaces the staged write of an existing file with the file on
+// disk merged with the generated content (see changeset.Set.Merge).
+// Reports whether the merge has conflicts.
+func (g *ModuleGenerator) Merge(filePath string) (bool, error) {
+	return g.changes.Merge(filePath)
 }
 
 // GenerateHandler generates only the handler file
-func (g *ModuleGenerator) GenerateHandler(name string, force bool, dryRun bool) ([]string, error) {
+func (g *ModuleGenerator) GenerateHandler(name string, force bool) ([]string, error) {
 	nameLower := strings.ToLower(name)
-	nameTitle := strings.Title(nameLower)
 
-	var filePath string
-	var content string
-
-	if g.project.Architecture == "layered" {
-		filePath = fmt.Sprintf("internal/app/handlers/%s_handler.go", nameLower)
-		content = g.getHandlerTemplate(nameTitle, nameLower)
-	} else {
+	filePath := fmt.Sprintf("internal/app/handlers/%s_handler.go", nameLower)
+	templateName := "layered/handler.go.tmpl"
+	if g.project.Architecture != "layered" {
 		filePath = fmt.Sprintf("internal/modules/%s/handler.go", nameLower)
-		content = g.getModularHandlerTemplate(nameTitle, nameLower)
+		templateName = "modular/handler.go.tmpl"
 	}
 
-	if err := g.createFile(filePath, content, force, dryRun); err != nil {
-		return nil, err
-	}
-
-	return []string{filePath}, nil
+	return g.generateComponent("handler:"+nameLower, filePath, templateName, g.componentData(name, nil, nil), force)
 }
 
 // GenerateService generates only the service file
-func (g *ModuleGenerator) GenerateService(name string, force bool, dryRun bool) ([]string, error) {
+func (g *ModuleGenerator) GenerateService(name string, force bool) ([]string, error) {
 	nameLower := strings.ToLower(name)
-	nameTitle := strings.Title(nameLower)
-
-	var filePath string
-	var content string
 
-	if g.project.Architecture == "layered" {
-		filePath = fmt.Sprintf("internal/app/services/%s_service.go", nameLower)
-		content = g.getServiceTemplate(nameTitle, nameLower)
-	} else {
+	filePath := fmt.Sprintf("internal/app/services/%s_service.go", nameLower)
+	templateName := "layered/service.go.tmpl"
+	if g.project.Architecture != "layered" {
 		filePath = fmt.Sprintf("internal/modules/%s/service.go", nameLower)
-		content = g.getModularServiceTemplate(nameTitle, nameLower)
-	}
-
-	if err := g.createFile(filePath, content, force, dryRun); err != nil {
-		return nil, err
+		templateName = "modular/service.go.tmpl"
 	}
 
-	return []string{filePath}, nil
+	return g.generateComponent("service:"+nameLower, filePath, templateName, g.componentData(name, DefaultFields(), nil), force)
 }
 
 // GenerateModel generates only the model file
-func (g *ModuleGenerator) GenerateModel(name string, force bool, dryRun bool) ([]string, error) {
-	nameLower := strings.ToLower(name)
-	nameTitle := strings.Title(nameLower)
+func (g *ModuleGenerator) GenerateModel(name string, fields []Field, force bool) ([]string, error) {
+	if len(fields) == 0 {
+		fields = DefaultFields()
+	}
 
-	var filePath string
-	var content string
+	nameLower := strings.ToLower(name)
 
-	if g.project.Architecture == "layered" {
-		filePath = fmt.Sprintf("internal/app/models/%s.go", nameLower)
-		content = g.getModelTemplate(nameTitle, nameLower)
-	} else {
+	filePath := fmt.Sprintf("internal/app/models/%s.go", nameLower)
+	templateName := "layered/model.go.tmpl"
+	if g.project.Architecture != "layered" {
 		filePath = fmt.Sprintf("internal/modules/%s/model.go", nameLower)
-		content = g.getModularModelTemplate(nameTitle, nameLower)
+		templateName = "modular/model.go.tmpl"
 	}
 
-	if err := g.createFile(filePath, content, force, dryRun); err != nil {
-		return nil, err
-	}
-
-	return []string{filePath}, nil
+	return g.generateComponent("model:"+nameLower, filePath, templateName, g.componentData(name, fields, nil), force)
 }
 
 // GenerateMiddleware generates a middleware
-func (g *ModuleGenerator) GenerateMiddleware(name string, force bool, dryRun bool) ([]string, error) {
+func (g *ModuleGenerator) GenerateMiddleware(name string, force bool) ([]string, error) {
 	nameLower := strings.ToLower(name)
-	nameTitle := strings.Title(nameLower)
 
-	var filePath string
-	var content string
-
-	if g.project.Architecture == "layered" {
-		filePath = fmt.Sprintf("internal/app/middleware/%s.go", nameLower)
-	} else {
+	filePath := fmt.Sprintf("internal/app/middleware/%s.go", nameLower)
+	if g.project.Architecture != "layered" {
 		filePath = fmt.Sprintf("internal/middleware/%s.go", nameLower)
 	}
 
-	content = fmt.Sprintf(`package middleware
-
-import (
-	"log"
-	"net/http"
-)
-
-// %s middleware
-func %s(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Middleware %s: %%s %%s", r.Method, r.URL.Path)
-
-		// Implement your logic here
-
-		next.ServeHTTP(w, r)
-	})
+	return g.generateComponent("middleware:"+nameLower, filePath, "middleware.go.tmpl", g.componentData(name, nil, nil), force)
 }
-`, nameTitle, nameTitle, nameLower)
 
-	if err := g.createFile(filePath, content, force, dryRun); err != nil {
+// generateComponent renders a single component template and writes it
+func (g *ModuleGenerator) generateComponent(generator, filePath, templateName string, data ComponentData, force bool) ([]string, error) {
+	content, err := renderComponent(templateName, data)
+	if err != nil {
 		return nil, err
 	}
-
-	return []string{filePath}, nil
+	g.templates[filePath] = path.Join(componentsDir, templateName)
+	return g.writeComponent(generator, filePath, content, force)
 }
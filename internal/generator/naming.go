@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// toPascalCase converts snake_case, kebab-case or camelCase to PascalCase
+// e.g. "order_items" -> "OrderItems", "productID" -> "ProductID"
+func toPascalCase(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		words[i] = capitalize(w)
+	}
+	return strings.Join(words, "")
+}
+
+// toCamelCase converts a name to camelCase. The first word is lower-cased
+// entirely, initialisms included: "sku" -> "sku", "api_key" -> "apiKey"
+func toCamelCase(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(w)
+		} else {
+			words[i] = capitalize(w)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// toSnakeCase converts a name to snake_case
+func toSnakeCase(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return strings.Join(words, "_")
+}
+
+// splitWords splits a name on separators and lower-to-upper case transitions
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' || r == '.' {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
+
+// capitalize upper-cases the first letter and keeps common initialisms upper-case
+func capitalize(w string) string {
+	if w == "" {
+		return w
+	}
+	switch strings.ToLower(w) {
+	case "id", "url", "uuid", "api", "http", "json", "sku", "ip":
+		return strings.ToUpper(w)
+	}
+	runes := []rune(w)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// toPlural returns a naive English plural ("category" -> "categories")
+func toPlural(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case lower == "":
+		return s
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		if strings.HasSuffix(lower, "ss") || !strings.HasSuffix(lower, "s") {
+			return s + "es"
+		}
+		return s
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return s[:len(s)-1] + "ies"
+	}
+	return s + "s"
+}
+
+// toSingular returns a naive English singular ("categories" -> "category")
+func toSingular(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"):
+		return s
+	case strings.HasSuffix(lower, "s") && len(lower) > 1:
+		return s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,177 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/state"
+)
+
+// Templates are looked up by name, their path below the embedded
+// templates directory ("components/layered/handler.go.tmpl"). A file with
+// the same name in an override directory replaces the embedded default:
+//
+//  1. .loom/templates in the current project
+//  2. $XDG_CONFIG_HOME/loom/templates (~/.config/loom/templates)
+//  3. the templates embedded in the binary
+
+// Template sources reported by ListTemplates
+const (
+	TemplateSourceProject  = "project"
+	TemplateSourceUser     = "user"
+	TemplateSourceEmbedded = "embedded"
+)
+
+// embeddedRoot is the directory of templatesFS holding the templates
+const embeddedRoot = "templates"
+
+// TemplateInfo describes a template and where it is currently loaded from
+type TemplateInfo struct {
+	Name   string
+	Source string // one of the TemplateSource* constants
+	Path   string // override file ("" for embedded templates)
+}
+
+// ProjectTemplatesDir returns the per-project override directory
+func ProjectTemplatesDir() string {
+	return filepath.Join(state.Dir, "templates")
+}
+
+// UserTemplatesDir returns the per-user override directory
+// ("" when the home directory is unknown)
+func UserTemplatesDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "loom", "templates")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "loom", "templates")
+}
+
+// templateOverride returns the override file of a template, if any
+func templateOverride(name string) (source, file string) {
+	dirs := []struct{ source, dir string }{
+		{TemplateSourceProject, ProjectTemplatesDir()},
+		{TemplateSourceUser, UserTemplatesDir()},
+	}
+	for _, d := range dirs {
+		if d.dir == "" {
+			continue
+		}
+		file := filepath.Join(d.dir, filepath.FromSlash(name))
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return d.source, file
+		}
+	}
+	return TemplateSourceEmbedded, ""
+}
+
+// readTemplate returns the content of a template, honoring overrides
+func readTemplate(name string) (string, error) {
+	if _, file := templateOverride(name); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("error reading template override %s: %w", file, err)
+		}
+		return string(content), nil
+	}
+
+	content, err := templatesFS.ReadFile(path.Join(embeddedRoot, name))
+	if err != nil {
+		return "", fmt.Errorf("template %s not found", name)
+	}
+	return string(content), nil
+}
+
+// embeddedTemplates returns the names of the embedded templates below dir
+// ("" for all of them), sorted
+func embeddedTemplates(dir string) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(templatesFS, path.Join(embeddedRoot, dir), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, strings.TrimPrefix(p, embeddedRoot+"/"))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListTemplates returns every embedded template and where it is loaded from
+func ListTemplates() ([]TemplateInfo, error) {
+	names, err := embeddedTemplates("")
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]TemplateInfo, 0, len(names))
+	for _, name := range names {
+		source, file := templateOverride(name)
+		infos = append(infos, TemplateInfo{Name: name, Source: source, Path: file})
+	}
+	return infos, nil
+}
+
+// EjectTemplates copies the embedded default of a template into an
+// override directory so it can be customized. name is a template name
+// (the .tmpl suffix may be omitted) or a directory such as
+// "components/layered", which ejects every template below it. Existing
+// overrides are kept unless force is set. Returns the written files.
+func EjectTemplates(name, dir string, force bool) ([]string, error) {
+	name = strings.Trim(path.Clean(filepath.ToSlash(name)), "/")
+
+	names, err := resolveTemplates(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, n := range names {
+		target := filepath.Join(dir, filepath.FromSlash(n))
+		if _, err := os.Stat(target); err == nil && !force {
+			return written, fmt.Errorf("%s already exists (use --force to overwrite)", target)
+		}
+
+		content, err := templatesFS.ReadFile(path.Join(embeddedRoot, n))
+		if err != nil {
+			return written, err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return written, err
+		}
+		written = append(written, target)
+	}
+
+	return written, nil
+}
+
+// resolveTemplates returns the embedded templates matching an eject name
+func resolveTemplates(name string) ([]string, error) {
+	for _, candidate := range []string{name, name + ".tmpl"} {
+		if _, err := templatesFS.ReadFile(path.Join(embeddedRoot, candidate)); err == nil {
+			return []string{candidate}, nil
+		}
+	}
+
+	if names, err := embeddedTemplates(name); err == nil && len(names) > 0 {
+		return names, nil
+	}
+
+	return nil, fmt.Errorf("template %s not found (see 'loom templates list')", name)
+}
@@ -0,0 +1,350 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/geomark27/loom-go/internal/state"
+)
+
+// packLockFile records the installed packs, next to the packs directory
+// (.loom/packs.lock for project packs)
+const packLockFile = "packs.lock"
+
+// PackLock pins the installed packs to a version and a checksum
+type PackLock struct {
+	Packs map[string]LockedPack `json:"packs"`
+}
+
+// LockedPack records where a pack was installed from
+type LockedPack struct {
+	Version     string    `json:"version"`
+	Source      string    `json:"source"`
+	Ref         string    `json:"ref,omitempty"`
+	Commit      string    `json:"commit,omitempty"`
+	Checksum    string    `json:"checksum"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// packLockPath returns the lockfile of a packs directory
+func packLockPath(packsDir string) string {
+	return filepath.Join(filepath.Dir(packsDir), packLockFile)
+}
+
+// LoadPackLock reads the lockfile of a packs directory.
+// A missing lockfile yields an empty lock.
+func LoadPackLock(packsDir string) (*PackLock, error) {
+	lock := &PackLock{Packs: make(map[string]LockedPack)}
+
+	// A legacy .loom file standing for the directory means no packs
+	data, err := os.ReadFile(packLockPath(packsDir))
+	if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading pack lockfile: %w", err)
+	}
+
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", packLockPath(packsDir), err)
+	}
+	if lock.Packs == nil {
+		lock.Packs = make(map[string]LockedPack)
+	}
+
+	return lock, nil
+}
+
+// save writes the lockfile of a packs directory
+func (l *PackLock) save(packsDir string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(packLockPath(packsDir), append(data, '\n'), 0644)
+}
+
+// ParsePackSource splits a pack source into a location and a git ref:
+// "https://host/org/pack.git@v1.2.0" pins the tag v1.2.0. An "@" before
+// the last path separator belongs to the location (git@host:org/pack.git).
+func ParsePackSource(source string) (location, ref string) {
+	at := strings.LastIndex(source, "@")
+	if at <= strings.LastIndexAny(source, "/:") {
+		return source, ""
+	}
+	return source[:at], source[at+1:]
+}
+
+// isLocalPack reports whether location is a pack directory on disk (as
+// opposed to a git repository, which may be a bare repository on disk)
+func isLocalPack(location string) bool {
+	_, err := os.Stat(filepath.Join(location, PackManifestFile))
+	return err == nil
+}
+
+// fetchPack makes the content of a pack source available in a directory.
+// Git sources are cloned into a temporary directory removed by cleanup.
+func fetchPack(source string) (dir string, locked LockedPack, cleanup func(), err error) {
+	location, ref := ParsePackSource(source)
+	cleanup = func() {}
+
+	if ref == "" && isLocalPack(location) {
+		abs, err := filepath.Abs(location)
+		if err != nil {
+			return "", locked, cleanup, err
+		}
+		return abs, LockedPack{Source: abs}, cleanup, nil
+	}
+
+	tmp, err := os.MkdirTemp("", "loom-pack-")
+	if err != nil {
+		return "", locked, cleanup, err
+	}
+	cleanup = func() { os.RemoveAll(tmp) }
+
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, location, tmp)
+
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		cleanup()
+		return "", locked, func() {}, fmt.Errorf("error cloning %s: %s", source, strings.TrimSpace(string(output)))
+	}
+
+	commit, err := exec.Command("git", "-C", tmp, "rev-parse", "HEAD").Output()
+	if err != nil {
+		cleanup()
+		return "", locked, func() {}, fmt.Errorf("error reading the commit of %s: %w", source, err)
+	}
+
+	locked = LockedPack{Source: location, Ref: ref, Commit: strings.TrimSpace(string(commit))}
+	return tmp, locked, cleanup, nil
+}
+
+// InstallPack installs a pack from a local directory or a git repository
+// (optionally pinned with @<tag>) into packsDir and records it in the
+// lockfile. An installed pack with the same name is replaced only when
+// force is set.
+func InstallPack(source, packsDir string, force bool) (*Pack, error) {
+	dir, locked, cleanup, err := fetchPack(source)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	pack, err := LoadPack(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := installPackDir(pack, packsDir, locked, force); err != nil {
+		return nil, err
+	}
+
+	return LoadPack(filepath.Join(packsDir, pack.Name))
+}
+
+// CopyPack installs an already installed pack into another packs
+// directory (used to give new projects the pack they were created from)
+func CopyPack(pack *Pack, packsDir string) error {
+	locked := LockedPack{Source: pack.Dir}
+
+	lock, err := LoadPackLock(filepath.Dir(pack.Dir))
+	if err == nil {
+		if entry, ok := lock.Packs[pack.Name]; ok {
+			locked = entry
+		}
+	}
+
+	return installPackDir(pack, packsDir, locked, true)
+}
+
+// installPackDir copies a pack into packsDir and updates the lockfile
+func installPackDir(pack *Pack, packsDir string, locked LockedPack, force bool) error {
+	target := filepath.Join(packsDir, pack.Name)
+	if same, _ := samePath(pack.Dir, target); same {
+		return fmt.Errorf("pack %s is already installed in %s", pack.Name, target)
+	}
+	if _, err := os.Stat(target); err == nil {
+		if !force {
+			return fmt.Errorf("pack %s is already installed in %s (use --force to replace it)", pack.Name, target)
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+	}
+
+	// Project packs live in .loom, which may still be a legacy file
+	if loomDir := filepath.Dir(packsDir); filepath.Base(loomDir) == state.Dir {
+		if err := state.EnsureDir(filepath.Dir(loomDir)); err != nil {
+			return err
+		}
+	}
+
+	if err := copyPackFiles(pack.Dir, target); err != nil {
+		return fmt.Errorf("error installing pack %s: %w", pack.Name, err)
+	}
+
+	checksum, err := PackChecksum(target)
+	if err != nil {
+		return err
+	}
+
+	lock, err := LoadPackLock(packsDir)
+	if err != nil {
+		return err
+	}
+
+	locked.Version = pack.Version
+	locked.Checksum = checksum
+	locked.InstalledAt = time.Now().UTC().Truncate(time.Second)
+	lock.Packs[pack.Name] = locked
+
+	if err := lock.save(packsDir); err != nil {
+		return err
+	}
+	return pinProjectPack(packsDir, pack.Name, pack.Version)
+}
+
+// pinProjectPack records the version of a project pack in the project
+// manifest ("" forgets the pack). User packs are not recorded.
+func pinProjectPack(packsDir, name, version string) error {
+	loomDir := filepath.Dir(packsDir)
+	if filepath.Base(loomDir) != state.Dir {
+		return nil
+	}
+
+	root := filepath.Dir(loomDir)
+	manifest, err := state.LoadManifest(root)
+	if err != nil || manifest == nil {
+		return err
+	}
+	manifest.PinPack(name, version)
+	return manifest.Save(root)
+}
+
+// samePath reports whether two paths point to the same location
+func samePath(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return absA == absB, nil
+}
+
+// copyPackFiles copies the files of a pack, leaving out VCS metadata
+func copyPackFiles(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+// PackChecksum returns a checksum of the files of a pack directory
+func PackChecksum(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", file, len(content))
+		hash.Write(content)
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// RemovePack uninstalls a pack from packsDir and the lockfile
+func RemovePack(name, packsDir string) error {
+	if err := validatePackName(name); err != nil {
+		return fmt.Errorf("invalid pack name %q: %w", name, err)
+	}
+
+	target := filepath.Join(packsDir, name)
+	if _, err := os.Stat(target); err != nil {
+		return fmt.Errorf("pack %s is not installed in %s", name, packsDir)
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+
+	lock, err := LoadPackLock(packsDir)
+	if err != nil {
+		return err
+	}
+	delete(lock.Packs, name)
+	if err := lock.save(packsDir); err != nil {
+		return err
+	}
+	return pinProjectPack(packsDir, name, "")
+}
+
+// FindPack returns the installed pack with the given name
+func FindPack(name string) (*Pack, error) {
+	packs, _ := LoadPacks()
+	for _, pack := range packs {
+		if pack.Name == name {
+			return pack, nil
+		}
+	}
+	return nil, fmt.Errorf("pack %s is not installed (see 'loom pack list')", name)
+}
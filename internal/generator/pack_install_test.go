@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePack writes a pack directory with a single kind
+func writePack(t *testing.T, name, version string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "name: " + name + "\nversion: " + version + `
+kinds:
+  - name: job
+    files:
+      - template: job.go.tmpl
+        path: internal/app/jobs/{{.Snake}}_job.go
+`
+	files := map[string]string{
+		PackManifestFile: manifest,
+		"job.go.tmpl":    "package jobs\n",
+		".git/HEAD":      "ref: refs/heads/main\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestInstallPack(t *testing.T) {
+	packsDir := filepath.Join(t.TempDir(), "packs")
+
+	pack, err := InstallPack(writePack(t, "acme", "1.0.0"), packsDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack.Dir != filepath.Join(packsDir, "acme") || pack.Version != "1.0.0" {
+		t.Errorf("installed %s %s in %s", pack.Name, pack.Version, pack.Dir)
+	}
+	if _, err := os.Stat(filepath.Join(pack.Dir, ".git")); !os.IsNotExist(err) {
+		t.Errorf(".git was copied with the pack (%v)", err)
+	}
+
+	// The lockfile pins the version and the checksum of the installed files
+	lock, err := LoadPackLock(packsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked, ok := lock.Packs["acme"]
+	if !ok {
+		t.Fatalf("acme missing from the lockfile: %+v", lock.Packs)
+	}
+	checksum, err := PackChecksum(pack.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked.Version != "1.0.0" || locked.Checksum != checksum || !strings.HasPrefix(checksum, "sha256:") {
+		t.Errorf("locked %+v, want version 1.0.0 and checksum %s", locked, checksum)
+	}
+
+	// Editing an installed file changes the checksum
+	if err := os.WriteFile(filepath.Join(pack.Dir, "job.go.tmpl"), []byte("package job\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if modified, _ := PackChecksum(pack.Dir); modified == checksum {
+		t.Error("checksum unchanged after editing a template")
+	}
+
+	// Replacing an installed pack needs force
+	if _, err := InstallPack(writePack(t, "acme", "2.0.0"), packsDir, false); err == nil {
+		t.Error("installed over acme without force")
+	}
+	if pack, err = InstallPack(writePack(t, "acme", "2.0.0"), packsDir, true); err != nil || pack.Version != "2.0.0" {
+		t.Errorf("forced install = %v, %v", pack, err)
+	}
+}
+
+func TestInstallPackRejectsPathNames(t *testing.T) {
+	root := t.TempDir()
+	packsDir := filepath.Join(root, ".loom", "packs")
+	if err := os.MkdirAll(packsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"..", ".", `"../x"`} {
+		if _, err := InstallPack(writePack(t, name, "1.0.0"), packsDir, true); err == nil {
+			t.Errorf("installed a pack named %s", name)
+		}
+	}
+	if _, err := os.Stat(packsDir); err != nil {
+		t.Errorf("packs directory lost: %v", err)
+	}
+}
+
+func TestRemovePack(t *testing.T) {
+	packsDir := filepath.Join(t.TempDir(), "packs")
+	if _, err := InstallPack(writePack(t, "acme", "1.0.0"), packsDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemovePack("acme", packsDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(packsDir, "acme")); !os.IsNotExist(err) {
+		t.Errorf("acme still installed (%v)", err)
+	}
+	lock, err := LoadPackLock(packsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lock.Packs["acme"]; ok {
+		t.Error("acme still in the lockfile")
+	}
+
+	if err := RemovePack("acme", packsDir); err == nil {
+		t.Error("removed a pack that is not installed")
+	}
+}
+
+func TestRemovePackRejectsPathNames(t *testing.T) {
+	root := t.TempDir()
+	packsDir := filepath.Join(root, ".loom", "packs")
+	internal := filepath.Join(root, "internal")
+	for _, dir := range []string{packsDir, internal} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"..", ".", "../../internal", ""} {
+		if err := RemovePack(name, packsDir); err == nil {
+			t.Errorf("RemovePack(%q) succeeded", name)
+		}
+	}
+	for _, dir := range []string{packsDir, internal} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s lost: %v", dir, err)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/geomark27/loom-go/internal/source"
+	"github.com/geomark27/loom-go/internal/state"
+)
+
+// PackManifestFile is the manifest at the root of a template pack
+const PackManifestFile = "pack.yaml"
+
+// Pack is a template pack: a directory with a pack.yaml manifest and the
+// templates it references. Packs define new "loom generate" kinds, e.g.
+//
+//	name: acme
+//	version: 1.0.0
+//	kinds:
+//	  - name: job
+//	    short: Generate a background job
+//	    flags:
+//	      - { name: schedule, description: cron schedule, required: true }
+//	    files:
+//	      - template: job.go.tmpl
+//	        layered: internal/app/jobs/{{.Snake}}_job.go
+//	        modular: internal/modules/{{.Lower}}/job.go
+//	    registry:
+//	      - file: internal/app/jobs/jobs_all.go
+//	        slice: AllJobs
+//	        entry: "&{{.Pascal}}Job{}"
+//
+// Paths, registry files and entries are templates executed with the same
+// ComponentData as the files; flag values are available as .Flags.<name>.
+//
+// A pack may also provide a project skeleton for "loom new --pack":
+//
+//	skeleton:
+//	  dir: skeleton
+//	  replace: false
+type Pack struct {
+	Name        string        `yaml:"name"`
+	Version     string        `yaml:"version"`
+	Description string        `yaml:"description"`
+	Kinds       []PackKind    `yaml:"kinds"`
+	Skeleton    *PackSkeleton `yaml:"skeleton"`
+
+	Dir string `yaml:"-"` // directory the pack was loaded from
+}
+
+// PackKind is a component kind, generated with "loom generate <name>"
+type PackKind struct {
+	Name     string         `yaml:"name"`
+	Short    string         `yaml:"short"`
+	Long     string         `yaml:"long"`
+	Aliases  []string       `yaml:"aliases"`
+	Flags    []PackFlag     `yaml:"flags"`
+	Files    []PackFile     `yaml:"files"`
+	Registry []RegistryEdit `yaml:"registry"`
+
+	Pack *Pack `yaml:"-"`
+}
+
+// PackSkeleton is the project skeleton of a pack. Every file below Dir is
+// written to the new project at the same relative path; files ending in
+// .tmpl are executed with the ProjectConfig and lose the suffix, and path
+// segments may use template actions ("cmd/{{.Name}}/main.go.tmpl"). The
+// skeleton is laid over the standard project unless Replace is set.
+type PackSkeleton struct {
+	Dir     string `yaml:"dir"`
+	Replace bool   `yaml:"replace"`
+}
+
+// PackFlag is a string flag of a kind
+type PackFlag struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Default     string `yaml:"default"`
+	Required    bool   `yaml:"required"`
+}
+
+// PackFile is a file emitted by a kind. Path applies to every
+// architecture unless Layered or Modular is set; a file without a path for
+// the project architecture is not generated.
+type PackFile struct {
+	Template string `yaml:"template"` // relative to the pack directory
+	Path     string `yaml:"path"`
+	Layered  string `yaml:"layered"`
+	Modular  string `yaml:"modular"`
+}
+
+// RegistryEdit is a Go source edit applied after generation: either an
+// entry appended to a slice variable (like AllModels) or statements
+// appended to a function. Import is added to the file first.
+type RegistryEdit struct {
+	File   string `yaml:"file"`
+	Import string `yaml:"import"`
+	Slice  string `yaml:"slice"`
+	Entry  string `yaml:"entry"`
+	Func   string `yaml:"func"`
+	Code   string `yaml:"code"`
+}
+
+// ProjectPacksDir returns the directory of the packs of the project
+func ProjectPacksDir() string {
+	return filepath.Join(state.Dir, "packs")
+}
+
+// UserPacksDir returns the directory of the packs of the user
+// ("" when the home directory is unknown)
+func UserPacksDir() string {
+	dir := UserTemplatesDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(dir), "packs")
+}
+
+// LoadPack reads and validates the pack in dir
+func LoadPack(dir string) (*Pack, error) {
+	data, err := os.ReadFile(filepath.Join(dir, PackManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading pack manifest: %w", err)
+	}
+
+	var pack Pack
+	if err := yaml.Unmarshal(data, &pack); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filepath.Join(dir, PackManifestFile), err)
+	}
+	pack.Dir = dir
+
+	if err := pack.validate(); err != nil {
+		return nil, fmt.Errorf("pack %s: %w", dir, err)
+	}
+
+	for i := range pack.Kinds {
+		pack.Kinds[i].Pack = &pack
+	}
+
+	return &pack, nil
+}
+
+// validate checks the manifest of a pack
+func (p *Pack) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if err := validatePackName(p.Name); err != nil {
+		return fmt.Errorf("invalid name %q: %w", p.Name, err)
+	}
+
+	if p.Skeleton != nil {
+		info, err := os.Stat(filepath.Join(p.Dir, p.Skeleton.Dir))
+		if p.Skeleton.Dir == "" || err != nil || !info.IsDir() {
+			return fmt.Errorf("skeleton directory %q not found", p.Skeleton.Dir)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, kind := range p.Kinds {
+		if err := ValidateComponentName(kind.Name); err != nil {
+			return fmt.Errorf("invalid kind name %q: %w", kind.Name, err)
+		}
+		if seen[kind.Name] {
+			return fmt.Errorf("kind %q declared more than once", kind.Name)
+		}
+		seen[kind.Name] = true
+
+		if len(kind.Files) == 0 {
+			return fmt.Errorf("kind %q: no files declared", kind.Name)
+		}
+		for _, file := range kind.Files {
+			if file.Template == "" {
+				return fmt.Errorf("kind %q: file without template", kind.Name)
+			}
+			if file.Path == "" && file.Layered == "" && file.Modular == "" {
+				return fmt.Errorf("kind %q: no path for template %s", kind.Name, file.Template)
+			}
+		}
+		for _, edit := range kind.Registry {
+			if edit.File == "" {
+				return fmt.Errorf("kind %q: registry edit without file", kind.Name)
+			}
+			if (edit.Slice == "") == (edit.Func == "") {
+				return fmt.Errorf("kind %q: registry edit of %s needs either slice or func", kind.Name, edit.File)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePackName checks that a pack name is a single path element, as
+// it names the directory of the pack in the packs directory
+func validatePackName(name string) error {
+	if err := ValidateComponentName(name); err != nil {
+		return err
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("name must be a single directory name")
+	}
+	return nil
+}
+
+// LoadPacks loads the packs installed in the project and user pack
+// directories. A project pack hides a user pack with the same name.
+// Packs that fail to load are reported in errs and skipped.
+func LoadPacks() (packs []*Pack, errs []error) {
+	seen := make(map[string]bool)
+
+	for _, dir := range []string{ProjectPacksDir(), UserPacksDir()} {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			pack, err := LoadPack(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if seen[pack.Name] {
+				continue
+			}
+			seen[pack.Name] = true
+			packs = append(packs, pack)
+		}
+	}
+
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].Name < packs[j].Name
+	})
+
+	return packs, errs
+}
+
+// PathFor returns the path pattern of a file for an architecture
+func (f PackFile) PathFor(architecture string) string {
+	switch {
+	case architecture == "layered" && f.Layered != "":
+		return f.Layered
+	case architecture == "modular" && f.Modular != "":
+		return f.Modular
+	}
+	return f.Path
+}
+
+// GenerateKind generates a component of a pack kind: every file of the
+// kind for the project architecture, then the registry edits. flags holds
+// the values of the kind flags. Returns the created and edited files.
+func (g *ModuleGenerator) GenerateKind(kind PackKind, name string, flags map[string]string, force bool) ([]string, error) {
+	data := g.componentData(name, DefaultFields(), nil)
+	data.Flags = flags
+
+	generatorName := kind.Name + ":" + data.Lower
+	files := make(map[string]string)
+
+	for _, file := range kind.Files {
+		pattern := file.PathFor(g.project.Architecture)
+		if pattern == "" {
+			continue
+		}
+		filePath, err := renderString("path", pattern, data)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(filepath.Join(kind.Pack.Dir, filepath.FromSlash(file.Template)))
+		if err != nil {
+			return nil, fmt.Errorf("pack %s: %w", kind.Pack.Name, err)
+		}
+		rendered, err := renderString(file.Template, string(content), data)
+		if err != nil {
+			return nil, err
+		}
+		filePath = filepath.ToSlash(filepath.Clean(filePath))
+		files[filePath] = rendered
+		g.templates[filePath] = packTemplate(kind.Pack, file.Template)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s does not support %s projects", kind.Name, g.project.Architecture)
+	}
+
+	var written []string
+	for _, planned := range planFiles(files) {
+		if _, err := g.createFile(planned.Path, planned.Content, force); err != nil {
+			return written, fmt.Errorf("%s: %w", planned.Path, err)
+		}
+		g.track(planned, generatorName)
+		written = append(written, planned.Path)
+	}
+
+	for _, edit := range kind.Registry {
+		edited, err := g.applyRegistryEdit(edit, data)
+		if err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+			continue
+		}
+		if edited != "" {
+			written = append(written, edited)
+		}
+	}
+
+	return written, nil
+}
+
+// applyRegistryEdit applies a registry edit of a kind. Returns the edited
+// file, or "" when the edit was already applied.
+func (g *ModuleGenerator) applyRegistryEdit(edit RegistryEdit, data ComponentData) (string, error) {
+	var rendered RegistryEdit
+	fields := []struct {
+		src string
+		dst *string
+	}{
+		{edit.File, &rendered.File},
+		{edit.Import, &rendered.Import},
+		{edit.Slice, &rendered.Slice},
+		{edit.Entry, &rendered.Entry},
+		{edit.Func, &rendered.Func},
+		{edit.Code, &rendered.Code},
+	}
+	for _, f := range fields {
+		value, err := renderString("registry", f.src, data)
+		if err != nil {
+			return "", err
+		}
+		*f.dst = strings.TrimSpace(value)
+	}
+
+	file, err := source.LoadFrom(g.changes, rendered.File)
+	if err != nil {
+		return "", err
+	}
+
+	if rendered.Import != "" {
+		if _, err := file.AddImport(rendered.Import); err != nil {
+			return "", err
+		}
+	}
+	if rendered.Slice != "" {
+		_, err = file.AppendToSlice(rendered.Slice, rendered.Entry)
+	} else {
+		_, err = file.AppendToFunc(rendered.Func, rendered.Code)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !file.Changed() {
+		return "", nil
+	}
+	if err := file.Save(); err != nil {
+		return "", err
+	}
+	return file.Path(), nil
+}
+
+// packTemplate names a file of a pack (relative to its directory) in
+// reports, e.g. "pack acme: kinds/job.go.tmpl"
+func packTemplate(pack *Pack, file string) string {
+	return fmt.Sprintf("pack %s: %s", pack.Name, filepath.ToSlash(file))
+}
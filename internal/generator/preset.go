@@ -0,0 +1,138 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Preset holds the answers of the "loom new" wizard so a team can create
+// projects the same way with "loom new <name> --preset team.yaml":
+//
+//	architecture: modular
+//	router: chi
+//	orm: gorm
+//	database: postgres
+//	auth: jwt
+//	docker: true
+//	ci: true
+//	modules: [products, orders]
+//	with: [redis]
+//
+// Empty values keep the defaults of "loom new".
+type Preset struct {
+	Architecture string   `yaml:"architecture,omitempty"` // "layered" or "modular"
+	Router       string   `yaml:"router,omitempty"`       // gin, chi, echo or net/http
+	ORM          string   `yaml:"orm,omitempty"`          // gorm or sqlc
+	Database     string   `yaml:"database,omitempty"`     // postgres, mysql, sqlite, sqlserver, mongodb or redis
+	Auth         string   `yaml:"auth,omitempty"`         // jwt or oauth2
+	Docker       bool     `yaml:"docker,omitempty"`
+	CI           bool     `yaml:"ci,omitempty"`
+	Modules      []string `yaml:"modules,omitempty"`
+	With         []string `yaml:"with,omitempty"` // more addons, as in "loom new --with"
+	Standalone   bool     `yaml:"standalone,omitempty"`
+	Module       string   `yaml:"module,omitempty"` // Go module name
+	Pack         string   `yaml:"pack,omitempty"`
+}
+
+// Preset choices offered by the wizard
+var (
+	PresetArchitectures = []string{"layered", "modular"}
+	PresetRouters       = []string{RouterGin, RouterChi, RouterEcho, RouterNetHTTP}
+	PresetORMs          = []string{"gorm", "sqlc"}
+	PresetDatabases     = []string{"postgres", "mysql", "sqlite", "sqlserver", "mongodb", "redis"}
+	PresetAuths         = []string{"jwt", "oauth2"}
+)
+
+// LoadPreset reads and validates a preset file
+func LoadPreset(path string) (*Preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading preset: %w", err)
+	}
+
+	var preset Preset
+	if err := yaml.Unmarshal(data, &preset); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	if err := preset.Validate(); err != nil {
+		return nil, fmt.Errorf("preset %s: %w", path, err)
+	}
+
+	return &preset, nil
+}
+
+// Save writes the preset to path
+func (p *Preset) Save(path string) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Validate checks that every choice of the preset is supported
+func (p *Preset) Validate() error {
+	choices := []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{"architecture", p.Architecture, PresetArchitectures},
+		{"router", p.Router, PresetRouters},
+		{"orm", p.ORM, PresetORMs},
+		{"database", p.Database, PresetDatabases},
+		{"auth", p.Auth, PresetAuths},
+	}
+	for _, c := range choices {
+		if c.value != "" && !slices.Contains(c.allowed, c.value) {
+			return fmt.Errorf("unsupported %s %q (choose %s)", c.name, c.value, strings.Join(c.allowed, ", "))
+		}
+	}
+
+	for _, name := range p.With {
+		if !isAddon(name) {
+			return fmt.Errorf("unknown addon %q", name)
+		}
+	}
+
+	for _, module := range p.Modules {
+		if err := ValidateComponentName(module); err != nil {
+			return fmt.Errorf("invalid module %q: %w", module, err)
+		}
+	}
+
+	return nil
+}
+
+// Addons returns the addons the preset installs
+func (p *Preset) Addons() []string {
+	var addons []string
+	add := func(name string) {
+		if name != "" && !slices.Contains(addons, name) {
+			addons = append(addons, name)
+		}
+	}
+
+	add(p.ORM)
+	add(p.Database)
+	add(p.Auth)
+	for _, name := range p.With {
+		add(name)
+	}
+	if p.Docker {
+		add("docker")
+	}
+	return addons
+}
+
+// isAddon reports whether name is an addon a preset can install
+func isAddon(name string) bool {
+	return name == "docker" ||
+		slices.Contains(PresetORMs, name) ||
+		slices.Contains(PresetDatabases, name) ||
+		slices.Contains(PresetAuths, name)
+}
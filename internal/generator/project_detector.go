@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/geomark27/loom-go/internal/state"
 )
 
 // ProjectInfo contains information about the detected project
@@ -14,6 +16,8 @@ type ProjectInfo struct {
 	HasHelpers   bool
 	RootPath     string
 	ModuleName   string
+	Router       string // as reported by addon.ProjectDetector.DetectRouter
+	ORM          string // as reported by addon.ProjectDetector.DetectORM
 }
 
 // DetectProject detects the type of Loom project in the current directory
@@ -27,8 +31,14 @@ func DetectProject() (*ProjectInfo, error) {
 		RootPath: ".",
 	}
 
-	// Detect architecture
-	if _, err := os.Stat("internal/modules"); err == nil {
+	// Detect architecture, as recorded in the project manifest first
+	manifest, err := state.LoadManifest(info.RootPath)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil && manifest.Architecture != "" {
+		info.Architecture = manifest.Architecture
+	} else if _, err := os.Stat("internal/modules"); err == nil {
 		info.Architecture = "modular"
 	} else if _, err := os.Stat("internal/app"); err == nil {
 		info.Architecture = "layered"
@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/source"
+)
+
+// ModelsRegistryPath is the GORM model registry created by "loom add orm gorm"
+const ModelsRegistryPath = "internal/database/models_all.go"
+
+// SeedersRegistryPath is the seeder registry created by "loom add orm gorm"
+const SeedersRegistryPath = "internal/database/seeders/seeders_all.go"
+
+// RegisterModel adds &models.<structName>{} to AllModels in models_all.go.
+// Returns false when the model is already registered.
+func RegisterModel(fsys source.FS, modelsAllPath, structName string) (bool, error) {
+	return appendToRegistry(fsys, modelsAllPath, "AllModels", fmt.Sprintf("&models.%s{}", structName))
+}
+
+// RegisterPackageModel adds &<pkg>.<structName>{} to AllModels in
+// models_all.go, importing the package (the model of a modular module).
+// Returns false when the model is already registered.
+func RegisterPackageModel(fsys source.FS, modelsAllPath, importPath, structName string) (bool, error) {
+	file, err := source.LoadFrom(fsys, modelsAllPath)
+	if err != nil {
+		return false, err
+	}
+
+	imported, err := file.AddImport(importPath)
+	if err != nil {
+		return false, err
+	}
+	added, err := file.AppendToSlice("AllModels", fmt.Sprintf("&%s.%s{}", path.Base(importPath), structName))
+	if err != nil || (!imported && !added) {
+		return false, err
+	}
+
+	return true, file.Save()
+}
+
+// RegisterSeeder adds &<structName>Seeder{} to AllSeeders in seeders_all.go.
+// Returns false when the seeder is already registered.
+func RegisterSeeder(fsys source.FS, seedersAllPath, structName string) (bool, error) {
+	return appendToRegistry(fsys, seedersAllPath, "AllSeeders", fmt.Sprintf("&%sSeeder{}", structName))
+}
+
+// UnregisterModel removes &models.<structName>{} from AllModels.
+// Returns false when the model is not registered.
+func UnregisterModel(fsys source.FS, modelsAllPath, structName string) (bool, error) {
+	return removeFromRegistry(fsys, modelsAllPath, "AllModels", fmt.Sprintf("&models.%s{}", structName))
+}
+
+// UnregisterPackageModel removes &<pkg>.<structName>{} from AllModels and
+// the import of the package once nothing else uses it.
+// Returns false when the model is not registered.
+func UnregisterPackageModel(fsys source.FS, modelsAllPath, importPath, structName string) (bool, error) {
+	file, err := source.LoadFrom(fsys, modelsAllPath)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := file.RemoveFromSlice("AllModels", fmt.Sprintf("&%s.%s{}", path.Base(importPath), structName))
+	if err != nil || !removed {
+		return false, err
+	}
+	if _, err := file.RemoveUnusedImport(importPath); err != nil {
+		return false, err
+	}
+
+	return true, file.Save()
+}
+
+// UnregisterSeeder removes &<structName>Seeder{} from AllSeeders.
+// Returns false when the seeder is not registered.
+func UnregisterSeeder(fsys source.FS, seedersAllPath, structName string) (bool, error) {
+	return removeFromRegistry(fsys, seedersAllPath, "AllSeeders", fmt.Sprintf("&%sSeeder{}", structName))
+}
+
+// removeFromRegistry removes an entry from a registry slice of a Go file
+func removeFromRegistry(fsys source.FS, path, varName, entry string) (bool, error) {
+	file, err := source.LoadFrom(fsys, path)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := file.RemoveFromSlice(varName, entry)
+	if err != nil || !removed {
+		return false, err
+	}
+
+	return true, file.Save()
+}
+
+// appendToRegistry appends an entry to a registry slice of a Go file
+func appendToRegistry(fsys source.FS, path, varName, entry string) (bool, error) {
+	file, err := source.LoadFrom(fsys, path)
+	if err != nil {
+		return false, err
+	}
+
+	added, err := file.AppendToSlice(varName, entry)
+	if err != nil || !added {
+		return false, err
+	}
+
+	return true, file.Save()
+}
+
+// registerModels registers the model of a module and its many-to-many
+// join models in models_all.go when the project uses GORM, so that
+// migrations see them. Returns the registry path when it was updated.
+func (g *ModuleGenerator) registerModels(name string, relations []Relation) (string, error) {
+	if !g.changes.Exists(ModelsRegistryPath) {
+		return "", nil
+	}
+
+	typeName := moduleTypeName(name)
+	if g.project.Architecture != "layered" {
+		// Modular modules declare their model in their own package
+		importPath := g.project.ModuleName + "/internal/modules/" + strings.ToLower(name)
+		added, err := RegisterPackageModel(g.changes, ModelsRegistryPath, importPath, typeName)
+		if err != nil || !added {
+			return "", err
+		}
+		return ModelsRegistryPath, nil
+	}
+
+	// The model template declares the join models in GORM projects only
+	structNames := []string{typeName}
+	if g.project.ORM == "gorm" {
+		structNames = append(structNames, JoinModelNames(strings.ToLower(name), relations)...)
+	}
+
+	updated := false
+	for _, structName := range structNames {
+		added, err := RegisterModel(g.changes, ModelsRegistryPath, structName)
+		if err != nil {
+			return "", err
+		}
+		updated = updated || added
+	}
+
+	if !updated {
+		return "", nil
+	}
+	return ModelsRegistryPath, nil
+}
@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/geomark27/loom-go/internal/golden"
+)
+
+// TestGenerateModuleRegistersModel checks that the model of a generated
+// module is registered in models_all.go, where migrations find it, and
+// unregistered when the module is destroyed
+func TestGenerateModuleRegistersModel(t *testing.T) {
+	tests := []struct {
+		architecture string
+		modelsImport string
+		entries      []string
+		imports      []string
+	}{
+		{
+			architecture: "layered",
+			modelsImport: "example.com/shop/internal/app/models",
+			entries:      []string{"&models.OrderItem{}", "&models.OrderItemTag{}"},
+		},
+		{
+			architecture: "modular",
+			modelsImport: "example.com/shop/internal/modules/users",
+			entries:      []string{"&order_items.OrderItem{}"},
+			imports:      []string{`"example.com/shop/internal/modules/order_items"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.architecture, func(t *testing.T) {
+			root := newGoldenProject(t, tt.architecture, RouterChi, true)
+			golden.Chdir(t, root)
+
+			// As written by "loom add orm gorm"
+			original := `package database
+
+import (
+	models "` + tt.modelsImport + `"
+)
+
+var AllModels = []interface{}{
+	&models.User{},
+}
+`
+			if err := os.MkdirAll(path.Dir(ModelsRegistryPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(ModelsRegistryPath, []byte(original), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			project := &ProjectInfo{
+				Name:         "shop",
+				Architecture: tt.architecture,
+				RootPath:     ".",
+				ModuleName:   "example.com/shop",
+				Router:       RouterChi,
+				ORM:          "gorm",
+			}
+			var relations []Relation
+			if tt.architecture == "layered" {
+				relations = []Relation{{Kind: RelationManyToMany, Entity: "tags"}}
+			}
+
+			gen := NewModuleGenerator(project)
+			files, err := gen.GenerateModule("order_items", nil, relations, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := gen.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := os.ReadFile(ModelsRegistryPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range append(tt.entries, tt.imports...) {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("models_all.go misses %s:\n%s", want, content)
+				}
+			}
+			if !slices.Contains(files, ModelsRegistryPath) {
+				t.Errorf("GenerateModule did not report %s among %v", ModelsRegistryPath, files)
+			}
+
+			// Generating again does not register the model twice
+			updated, err := NewModuleGenerator(project).registerModels("order_items", relations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated != "" {
+				t.Errorf("registering the model again updated %s", updated)
+			}
+
+			gen = NewModuleGenerator(project)
+			if _, err := gen.Destroy("module", "order_items", false); err != nil {
+				t.Fatal(err)
+			}
+			if err := gen.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			restored, err := os.ReadFile(ModelsRegistryPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(restored) != original {
+				t.Errorf("models_all.go not restored:\n%s", restored)
+			}
+		})
+	}
+}
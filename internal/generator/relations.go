@@ -0,0 +1,242 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported relation types
+const (
+	RelationBelongsTo  = "belongs_to"
+	RelationHasMany    = "has_many"
+	RelationManyToMany = "many_to_many"
+)
+
+// Relation links a generated entity to another entity of the project.
+// Entity is the name of the related module, e.g. "user" or "order_items".
+type Relation struct {
+	Kind   string
+	Entity string
+}
+
+// ParseRelations builds the relations declared with --belongs-to,
+// --has-many and --many-to-many
+func ParseRelations(belongsTo, hasMany, manyToMany []string) ([]Relation, error) {
+	var relations []Relation
+	seen := make(map[string]bool)
+
+	add := func(kind string, entities []string) error {
+		for _, entity := range entities {
+			entity = strings.TrimSpace(entity)
+			if err := ValidateComponentName(entity); err != nil {
+				return fmt.Errorf("invalid %s relation %q: %w", kind, entity, err)
+			}
+			relation := Relation{Kind: kind, Entity: entity}
+			if seen[relation.AssociationName()] {
+				return fmt.Errorf("relation %q declared more than once", entity)
+			}
+			seen[relation.AssociationName()] = true
+			relations = append(relations, relation)
+		}
+		return nil
+	}
+
+	if err := add(RelationBelongsTo, belongsTo); err != nil {
+		return nil, err
+	}
+	if err := add(RelationHasMany, hasMany); err != nil {
+		return nil, err
+	}
+	if err := add(RelationManyToMany, manyToMany); err != nil {
+		return nil, err
+	}
+
+	return relations, nil
+}
+
+// AssociationName returns the struct field holding the related entity
+// ("User" for belongs_to, "OrderItems" for has_many and many_to_many)
+func (r Relation) AssociationName() string {
+	if r.Kind == RelationBelongsTo {
+		return toPascalCase(toSingular(r.Entity))
+	}
+	return toPascalCase(toPlural(r.Entity))
+}
+
+// ForeignKey returns the column referencing the parent of a belongs_to
+func (r Relation) ForeignKey() string {
+	return foreignKeyFor(r.Entity)
+}
+
+// Path returns the URL segment of the related entity ("order_items")
+func (r Relation) Path() string {
+	return resourcePath(r.Entity)
+}
+
+// resourcePath returns the route segment of a module or entity, its
+// snake_case plural ("OrderItem" -> "order_items"), so a module is served
+// under the same segment nested routes use for it as a parent
+func resourcePath(name string) string {
+	return toSnakeCase(toPlural(name))
+}
+
+// TypeName returns the model type of the related module
+func (r Relation) TypeName() string {
+	return moduleTypeName(r.Entity)
+}
+
+// IDsField returns the DTO field carrying many-to-many IDs ("TagIDs")
+func (r Relation) IDsField() string {
+	return toPascalCase(toSingular(r.Entity)) + "IDs"
+}
+
+// IDsJSON returns the JSON name of the many-to-many IDs ("tag_ids")
+func (r Relation) IDsJSON() string {
+	return toSnakeCase(toSingular(r.Entity)) + "_ids"
+}
+
+// foreignKeyFor returns the foreign key column pointing to an entity
+func foreignKeyFor(entity string) string {
+	return toSnakeCase(toSingular(entity)) + "_id"
+}
+
+// joinTableName returns the many-to-many join table ("product_tags")
+func joinTableName(owner, entity string) string {
+	return toSnakeCase(toSingular(owner)) + "_" + toSnakeCase(toPlural(entity))
+}
+
+// joinModelName returns the struct mapped to a join table ("ProductTag")
+func joinModelName(owner, entity string) string {
+	return toPascalCase(toSingular(owner)) + toPascalCase(toSingular(entity))
+}
+
+// moduleTypeName returns the model type generated by "generate module <name>":
+// the singular entity in PascalCase ("order_items" and "OrderItem" give
+// "OrderItem")
+func moduleTypeName(name string) string {
+	return toPascalCase(toSingular(toSnakeCase(name)))
+}
+
+// filterRelations returns the relations of the given kind
+func filterRelations(relations []Relation, kind string) []Relation {
+	var filtered []Relation
+	for _, r := range relations {
+		if r.Kind == kind {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// RelationFields returns the foreign key fields added by belongs_to
+// relations. idType is the Go type of the primary keys (int or uint).
+func RelationFields(relations []Relation, idType string) []Field {
+	var fields []Field
+	for _, r := range filterRelations(relations, RelationBelongsTo) {
+		fields = append(fields, Field{
+			Name:   r.ForeignKey(),
+			Type:   idType,
+			GoType: idType,
+			Index:  true,
+		})
+	}
+	return fields
+}
+
+// mergeRelationFields appends the foreign keys that are not declared
+// explicitly among the fields
+func mergeRelationFields(fields []Field, relations []Relation) []Field {
+	declared := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		declared[f.Name] = true
+	}
+
+	merged := append([]Field(nil), fields...)
+	for _, fk := range RelationFields(relations, "int") {
+		if !declared[fk.Name] {
+			merged = append(merged, fk)
+		}
+	}
+	return merged
+}
+
+// associationLines returns the GORM association fields of a model
+func associationLines(owner string, relations []Relation) string {
+	var b strings.Builder
+	for _, r := range relations {
+		switch r.Kind {
+		case RelationBelongsTo:
+			fmt.Fprintf(&b, "\t%s *%s `json:\"%s,omitempty\" gorm:\"foreignKey:%s\"`\n",
+				r.AssociationName(), r.TypeName(), toSnakeCase(r.AssociationName()), toPascalCase(r.ForeignKey()))
+		case RelationHasMany:
+			fmt.Fprintf(&b, "\t%s []%s `json:\"%s,omitempty\" gorm:\"foreignKey:%s\"`\n",
+				r.AssociationName(), r.TypeName(), toSnakeCase(r.AssociationName()), toPascalCase(foreignKeyFor(owner)))
+		case RelationManyToMany:
+			fmt.Fprintf(&b, "\t%s []%s `json:\"%s,omitempty\" gorm:\"many2many:%s\"`\n",
+				r.AssociationName(), r.TypeName(), toSnakeCase(r.AssociationName()), joinTableName(owner, r.Entity))
+		}
+	}
+	return b.String()
+}
+
+// joinModels returns the structs mapped to the many-to-many join tables
+func joinModels(owner string, relations []Relation, idType string) string {
+	var b strings.Builder
+	for _, r := range filterRelations(relations, RelationManyToMany) {
+		name := joinModelName(owner, r.Entity)
+		ownerKey := toPascalCase(foreignKeyFor(owner))
+		otherKey := toPascalCase(foreignKeyFor(r.Entity))
+		fmt.Fprintf(&b, `
+// %s is the join table between %s and %s
+type %s struct {
+	%s %s `+"`gorm:\"primaryKey\"`"+`
+	%s %s `+"`gorm:\"primaryKey\"`"+`
+}
+
+// TableName returns the join table used by the many2many association
+func (%s) TableName() string {
+	return %q
+}
+`, name, toPlural(toSnakeCase(owner)), r.Path(), name, ownerKey, idType, otherKey, idType, name, joinTableName(owner, r.Entity))
+	}
+	return b.String()
+}
+
+// JoinModelNames returns the join model types declared for an entity
+func JoinModelNames(owner string, relations []Relation) []string {
+	var names []string
+	for _, r := range filterRelations(relations, RelationManyToMany) {
+		names = append(names, joinModelName(owner, r.Entity))
+	}
+	return names
+}
+
+// NestedRoute is a route listing the items of a module by parent
+type NestedRoute struct {
+	Path    string // e.g. /users/{id}/orders
+	Handler string // handler method, e.g. ListByUser
+}
+
+// NestedRoutes returns the nested routes generated for a module
+func NestedRoutes(name string, relations []Relation) []NestedRoute {
+	var routes []NestedRoute
+	for _, r := range filterRelations(relations, RelationBelongsTo) {
+		routes = append(routes, NestedRoute{
+			Path:    fmt.Sprintf("/%s/{id}/%s", r.Path(), resourcePath(name)),
+			Handler: "ListBy" + r.AssociationName(),
+		})
+	}
+	return routes
+}
+
+// GORMRelationLines returns the foreign keys and association fields of a
+// model created with "make model". Those models embed gorm.Model (uint
+// keys).
+func GORMRelationLines(owner string, relations []Relation) string {
+	return modelFieldLines(RelationFields(relations, "uint")) + associationLines(owner, relations)
+}
+
+// GORMJoinModels returns the join table structs of a "make model" model
+func GORMJoinModels(owner string, relations []Relation) string {
+	return joinModels(owner, relations, "uint")
+}
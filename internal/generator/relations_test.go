@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/geomark27/loom-go/internal/golden"
+)
+
+func TestModuleTypeName(t *testing.T) {
+	tests := map[string]string{
+		"products":    "Product",
+		"order_items": "OrderItem",
+		"OrderItem":   "OrderItem",
+		"categories":  "Category",
+		"address":     "Address",
+		"user":        "User",
+	}
+	for name, want := range tests {
+		if got := moduleTypeName(name); got != want {
+			t.Errorf("moduleTypeName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// TestComponentDataNames checks that only the model type of a module is
+// singular: middleware and docs keep the name as given
+func TestComponentDataNames(t *testing.T) {
+	gen := NewModuleGenerator(&ProjectInfo{Name: "shop", Architecture: "layered", ModuleName: "example.com/shop", Router: RouterGin})
+	tests := []struct {
+		name, pascal, typeName string
+	}{
+		{"cors", "Cors", "Cor"},
+		{"order_items", "OrderItems", "OrderItem"},
+		{"RateLimit", "RateLimit", "RateLimit"},
+	}
+	for _, tt := range tests {
+		data := gen.componentData(tt.name, nil, nil)
+		if data.Pascal != tt.pascal || data.Type != tt.typeName {
+			t.Errorf("componentData(%q) = Pascal %q, Type %q; want %q, %q", tt.name, data.Pascal, data.Type, tt.pascal, tt.typeName)
+		}
+	}
+
+	content, err := renderComponent("middleware.go.tmpl", gen.componentData("cors", nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "func Cors() gin.HandlerFunc") {
+		t.Errorf("middleware not named Cors:\n%s", content)
+	}
+}
+
+// TestAssociationTypes checks that associations reference the types
+// "generate module" declares for the related modules
+func TestAssociationTypes(t *testing.T) {
+	relations, err := ParseRelations([]string{"user"}, []string{"order_items"}, []string{"tags"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := associationLines("orders", relations)
+	for _, want := range []string{
+		"User *User ",
+		"OrderItems []OrderItem ",
+		"Tags []Tag ",
+	} {
+		if !strings.Contains(lines, want) {
+			t.Errorf("association %q missing from:\n%s", want, lines)
+		}
+	}
+}
+
+// TestNestedRoutes checks that a module is routed under the plural segment
+// nested routes use for it as a parent, whatever the name form
+func TestNestedRoutes(t *testing.T) {
+	relations, err := ParseRelations([]string{"Category"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Product", "product", "products"} {
+		routes := NestedRoutes(name, relations)
+		if len(routes) != 1 || routes[0].Path != "/categories/{id}/products" {
+			t.Errorf("NestedRoutes(%q) = %+v, want /categories/{id}/products", name, routes)
+		}
+		if got := resourcePath(name); got != "products" {
+			t.Errorf("resourcePath(%q) = %q, want products", name, got)
+		}
+	}
+	if got := resourcePath("OrderItem"); got != (Relation{Entity: "OrderItem"}).Path() {
+		t.Errorf("resourcePath(OrderItem) = %q, want the parent segment %q", got, (Relation{Entity: "OrderItem"}).Path())
+	}
+}
+
+// TestGenerateModuleTypeCollision checks that a layered module is refused
+// when another file of the models package declares its type
+func TestGenerateModuleTypeCollision(t *testing.T) {
+	root := newGoldenProject(t, "layered", RouterGin, true)
+	golden.Chdir(t, root)
+
+	gen := NewModuleGenerator(&ProjectInfo{
+		Name:         "shop",
+		Architecture: "layered",
+		RootPath:     ".",
+		ModuleName:   "example.com/shop",
+		Router:       RouterGin,
+	})
+
+	// The skeleton declares User in internal/app/models/user.go
+	_, err := gen.GenerateModule("users", nil, nil, false)
+	if err == nil || !strings.Contains(err.Error(), "internal/app/models/user.go") {
+		t.Fatalf("GenerateModule(users) error = %v, want a collision with user.go", err)
+	}
+
+	if _, err := gen.GenerateModule("order_items", nil, nil, false); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,340 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Routers the generated handlers, routes and middleware can target. The
+// names match addon.ProjectDetector.DetectRouter; projects without a
+// third-party router get net/http handlers (Go 1.22+ method patterns).
+const (
+	RouterGin        = "gin"
+	RouterChi        = "chi"
+	RouterEcho       = "echo"
+	RouterGorillaMux = "gorilla-mux"
+	RouterNetHTTP    = "net/http"
+)
+
+// HTTPRouter describes the router-specific parts of the generated code:
+// handler signatures, path parameters, responses and route registration.
+// Component templates reach it as .Router.
+type HTTPRouter struct {
+	Name       string // one of the Router* constants
+	Import     string // package providing the router types ("" for net/http)
+	RouterType string // type of the router passed to RegisterRoutes
+	Engine     string // type of the root router created by the server
+	Params     string // handler parameters
+	Results    string // handler results
+	IDParam    string // expression reading the "id" path parameter
+}
+
+// routerFor returns the HTTPRouter of a router name
+func routerFor(router string) HTTPRouter {
+	switch router {
+	case RouterGin:
+		return HTTPRouter{
+			Name:       RouterGin,
+			Engine:     "*gin.Engine",
+			Import:     "github.com/gin-gonic/gin",
+			RouterType: "*gin.RouterGroup",
+			Params:     "c *gin.Context",
+			IDParam:    `c.Param("id")`,
+		}
+	case RouterEcho:
+		return HTTPRouter{
+			Name:       RouterEcho,
+			Engine:     "*echo.Echo",
+			Import:     "github.com/labstack/echo/v4",
+			RouterType: "*echo.Group",
+			Params:     "c echo.Context",
+			Results:    " error",
+			IDParam:    `c.Param("id")`,
+		}
+	case RouterChi:
+		return HTTPRouter{
+			Name:       RouterChi,
+			Engine:     "*chi.Mux",
+			Import:     "github.com/go-chi/chi/v5",
+			RouterType: "chi.Router",
+			Params:     "w http.ResponseWriter, r *http.Request",
+			IDParam:    `chi.URLParam(r, "id")`,
+		}
+	case RouterGorillaMux:
+		return HTTPRouter{
+			Name:       RouterGorillaMux,
+			Engine:     "*mux.Router",
+			Import:     "github.com/gorilla/mux",
+			RouterType: "*mux.Router",
+			Params:     "w http.ResponseWriter, r *http.Request",
+			IDParam:    `mux.Vars(r)["id"]`,
+		}
+	}
+
+	return HTTPRouter{
+		Name:       RouterNetHTTP,
+		Engine:     "*http.ServeMux",
+		RouterType: "*http.ServeMux",
+		Params:     "w http.ResponseWriter, r *http.Request",
+		IDParam:    `r.PathValue("id")`,
+	}
+}
+
+// TargetRouter returns the router generated code targets for a detected
+// router name ("none" and "unknown" map to net/http)
+func TargetRouter(router string) string {
+	return routerFor(router).Name
+}
+
+// Module returns the go.mod requirement of the router ("" for net/http)
+func (d HTTPRouter) Module() string {
+	switch d.Name {
+	case RouterGin:
+		return "github.com/gin-gonic/gin v1.10.0"
+	case RouterEcho:
+		return "github.com/labstack/echo/v4 v4.11.3"
+	case RouterChi:
+		return "github.com/go-chi/chi/v5 v5.0.10"
+	case RouterGorillaMux:
+		return "github.com/gorilla/mux v1.8.1"
+	}
+	return ""
+}
+
+// routerTemplates are the templates of the skeleton files that depend on
+// the router
+var routerTemplates = map[string]bool{
+	"layered/server.go.tmpl":         true,
+	"layered/routes.go.tmpl":         true,
+	"layered/health_handler.go.tmpl": true,
+	"layered/user_handler.go.tmpl":   true,
+	"modular/server.go.tmpl":         true,
+	"modular/router.go.tmpl":         true,
+	"modular/handler.go.tmpl":        true,
+	"modular/module.go.tmpl":         true,
+}
+
+// RouterFiles renders the files of the project skeleton that depend on
+// the router for config.Router, by path, for a project switching to
+// another router. The modules generated since are not included.
+func (g *Generator) RouterFiles(config *ProjectConfig) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for filePath, templateName := range g.getFileMapping(config) {
+		if !routerTemplates[templateName] {
+			continue
+		}
+		content, err := g.render(filePath, templateName, config)
+		if err != nil {
+			return nil, err
+		}
+		files[filePath] = content
+	}
+	return files, nil
+}
+
+// router returns the HTTPRouter of the project
+func (g *ModuleGenerator) router() HTTPRouter {
+	return routerFor(g.project.Router)
+}
+
+// Stdlib reports whether handlers use http.ResponseWriter and *http.Request
+func (d HTTPRouter) Stdlib() bool {
+	return d.Name != RouterGin && d.Name != RouterEcho
+}
+
+// Signature returns the parameters and results of a handler method
+func (d HTTPRouter) Signature() string {
+	return "(" + d.Params + ")" + d.Results
+}
+
+// Fail returns the statements answering an error message and leaving the handler
+func (d HTTPRouter) Fail(status, message string) string {
+	switch d.Name {
+	case RouterGin:
+		return fmt.Sprintf("c.JSON(%s, gin.H{\"error\": %s})\n\t\treturn", status, message)
+	case RouterEcho:
+		return fmt.Sprintf("return c.JSON(%s, map[string]string{\"error\": %s})", status, message)
+	}
+	return fmt.Sprintf("http.Error(w, %s, %s)\n\t\treturn", message, status)
+}
+
+// Respond returns the statements answering value as JSON at the end of a handler
+func (d HTTPRouter) Respond(status, value string) string {
+	switch d.Name {
+	case RouterGin:
+		return fmt.Sprintf("c.JSON(%s, %s)", status, value)
+	case RouterEcho:
+		return fmt.Sprintf("return c.JSON(%s, %s)", status, value)
+	}
+
+	out := "w.Header().Set(\"Content-Type\", \"application/json\")\n\t"
+	if status != "http.StatusOK" {
+		out += fmt.Sprintf("w.WriteHeader(%s)\n\t", status)
+	}
+	return out + fmt.Sprintf("json.NewEncoder(w).Encode(%s)", value)
+}
+
+// Abort returns the statements answering value as JSON and leaving the handler
+func (d HTTPRouter) Abort(status, value string) string {
+	out := d.Respond(status, value)
+	if d.Name != RouterEcho {
+		out += "\n\treturn"
+	}
+	return strings.ReplaceAll(out, "\n\t", "\n\t\t")
+}
+
+// Reply returns the beginning of a call answering JSON, completed by the
+// value and a closing parenthesis. Stdlib routers use the writeJSON helper
+// generated with the project skeleton.
+func (d HTTPRouter) Reply(status string) string {
+	switch d.Name {
+	case RouterGin:
+		return fmt.Sprintf("c.JSON(%s, ", status)
+	case RouterEcho:
+		return fmt.Sprintf("return c.JSON(%s, ", status)
+	}
+	return fmt.Sprintf("writeJSON(w, %s, ", status)
+}
+
+// Return returns the statement leaving a handler after a Reply that is not
+// the last statement ("" for echo, where Reply already returns)
+func (d HTTPRouter) Return() string {
+	if d.Name == RouterEcho {
+		return ""
+	}
+	return "\n\t\treturn"
+}
+
+// NoContent returns the statement answering 204 No Content
+func (d HTTPRouter) NoContent() string {
+	switch d.Name {
+	case RouterGin:
+		return "c.Status(http.StatusNoContent)"
+	case RouterEcho:
+		return "return c.NoContent(http.StatusNoContent)"
+	}
+	return "w.WriteHeader(http.StatusNoContent)"
+}
+
+// Bind returns the expression decoding the request body into dst
+func (d HTTPRouter) Bind(dst string) string {
+	switch d.Name {
+	case RouterGin:
+		return fmt.Sprintf("c.ShouldBindJSON(%s)", dst)
+	case RouterEcho:
+		return fmt.Sprintf("c.Bind(%s)", dst)
+	}
+	return fmt.Sprintf("json.NewDecoder(r.Body).Decode(%s)", dst)
+}
+
+// Map returns the map literal type used for ad-hoc JSON objects
+func (d HTTPRouter) Map() string {
+	if d.Name == RouterGin {
+		return "gin.H"
+	}
+	return "map[string]interface{}"
+}
+
+// pathPattern converts a {param} route path to the router syntax
+func (d HTTPRouter) pathPattern(p string) string {
+	if d.Name == RouterGin || d.Name == RouterEcho {
+		return strings.ReplaceAll(p, "{id}", ":id")
+	}
+	return p
+}
+
+// Group returns the statements creating groupVar, a router serving the
+// routes below prefix on routerVar (an Engine)
+func (d HTTPRouter) Group(routerVar, groupVar, prefix string) string {
+	switch d.Name {
+	case RouterGin, RouterEcho:
+		return fmt.Sprintf("%s := %s.Group(%q)", groupVar, routerVar, prefix)
+	case RouterChi:
+		return fmt.Sprintf("%s := chi.NewRouter()\n\t%s.Mount(%q, %s)", groupVar, routerVar, prefix, groupVar)
+	case RouterGorillaMux:
+		return fmt.Sprintf("%s := %s.PathPrefix(%q).Subrouter()", groupVar, routerVar, prefix)
+	}
+	return fmt.Sprintf("%s := http.NewServeMux()\n\t%s.Handle(%q, http.StripPrefix(%q, %s))", groupVar, routerVar, prefix+"/", prefix, groupVar)
+}
+
+// Handle returns the registration of a single route
+func (d HTTPRouter) Handle(routerVar, method, p, handler string) string {
+	switch d.Name {
+	case RouterGin, RouterEcho:
+		return fmt.Sprintf("%s.%s(%q, %s)", routerVar, method, d.pathPattern(p), handler)
+	case RouterChi:
+		return fmt.Sprintf("%s.%s(%q, %s)", routerVar, strings.Title(strings.ToLower(method)), p, handler)
+	case RouterGorillaMux:
+		return fmt.Sprintf("%s.HandleFunc(%q, %s).Methods(%q)", routerVar, p, handler, method)
+	}
+	if p == "/" {
+		// "/" alone would match every path
+		p = "/{$}"
+	}
+	return fmt.Sprintf("%s.HandleFunc(%q, %s)", routerVar, method+" "+p, handler)
+}
+
+// Routes returns the registrations of the CRUD routes of a module under
+// /<base> on routerVar, followed by its nested routes
+func (d HTTPRouter) Routes(routerVar, groupVar, base, handlerVar string, nested []NestedRoute) string {
+	routes := d.ResourceRoutes(routerVar, groupVar, base,
+		handlerVar+".List", handlerVar+".Create", handlerVar+".GetByID", handlerVar+".Update", handlerVar+".Delete")
+
+	var b strings.Builder
+	b.WriteString(routes)
+	for _, route := range nested {
+		handler := handlerVar + "." + route.Handler
+		switch d.Name {
+		case RouterGin, RouterEcho:
+			fmt.Fprintf(&b, "%s.GET(%q, %s)\n", routerVar, d.pathPattern(route.Path), handler)
+		case RouterChi:
+			fmt.Fprintf(&b, "%s.Get(%q, %s)\n", routerVar, route.Path, handler)
+		case RouterGorillaMux:
+			fmt.Fprintf(&b, "%s.HandleFunc(%q, %s).Methods(\"GET\")\n", routerVar, route.Path, handler)
+		default:
+			fmt.Fprintf(&b, "%s.HandleFunc(\"GET %s\", %s)\n", routerVar, route.Path, handler)
+		}
+	}
+
+	return b.String()
+}
+
+// ResourceRoutes returns the registrations of the CRUD routes of a
+// resource under /<base> on routerVar, given its handler functions
+func (d HTTPRouter) ResourceRoutes(routerVar, groupVar, base, list, create, get, update, del string) string {
+	var b strings.Builder
+
+	switch d.Name {
+	case RouterGin, RouterEcho:
+		fmt.Fprintf(&b, "%s := %s.Group(\"/%s\")\n{\n", groupVar, routerVar, base)
+		fmt.Fprintf(&b, "\t%s.GET(\"\", %s)\n", groupVar, list)
+		fmt.Fprintf(&b, "\t%s.POST(\"\", %s)\n", groupVar, create)
+		fmt.Fprintf(&b, "\t%s.GET(\"/:id\", %s)\n", groupVar, get)
+		fmt.Fprintf(&b, "\t%s.PUT(\"/:id\", %s)\n", groupVar, update)
+		fmt.Fprintf(&b, "\t%s.DELETE(\"/:id\", %s)\n", groupVar, del)
+		b.WriteString("}\n")
+	case RouterChi:
+		fmt.Fprintf(&b, "%s.Route(\"/%s\", func(r chi.Router) {\n", routerVar, base)
+		fmt.Fprintf(&b, "\tr.Get(\"/\", %s)\n", list)
+		fmt.Fprintf(&b, "\tr.Post(\"/\", %s)\n", create)
+		fmt.Fprintf(&b, "\tr.Get(\"/{id}\", %s)\n", get)
+		fmt.Fprintf(&b, "\tr.Put(\"/{id}\", %s)\n", update)
+		fmt.Fprintf(&b, "\tr.Delete(\"/{id}\", %s)\n", del)
+		b.WriteString("})\n")
+	case RouterGorillaMux:
+		fmt.Fprintf(&b, "%s := %s.PathPrefix(\"/%s\").Subrouter()\n", groupVar, routerVar, base)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"\", %s).Methods(\"GET\")\n", groupVar, list)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"\", %s).Methods(\"POST\")\n", groupVar, create)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"/{id}\", %s).Methods(\"GET\")\n", groupVar, get)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"/{id}\", %s).Methods(\"PUT\")\n", groupVar, update)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"/{id}\", %s).Methods(\"DELETE\")\n", groupVar, del)
+	default:
+		fmt.Fprintf(&b, "%s.HandleFunc(\"GET /%s\", %s)\n", routerVar, base, list)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"POST /%s\", %s)\n", routerVar, base, create)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"GET /%s/{id}\", %s)\n", routerVar, base, get)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"PUT /%s/{id}\", %s)\n", routerVar, base, update)
+		fmt.Fprintf(&b, "%s.HandleFunc(\"DELETE /%s/{id}\", %s)\n", routerVar, base, del)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,223 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema describes the domain of a project: the entities Loom generates
+// modules for. It is loaded from a YAML or JSON file, e.g.
+//
+//	entities:
+//	  - name: product
+//	    fields:
+//	      - name:string
+//	      - price:float64:default=0
+//	      - { name: sku, type: string, unique: true, size: 64 }
+//	    relations:
+//	      - { type: belongs_to, entity: category }
+//	    indexes:
+//	      - { fields: [name, price] }
+type Schema struct {
+	Entities []EntitySchema `yaml:"entities" json:"entities"`
+}
+
+// EntitySchema describes one entity (one generated module)
+type EntitySchema struct {
+	Name      string           `yaml:"name" json:"name"`
+	Fields    []FieldSchema    `yaml:"fields" json:"fields"`
+	Relations []RelationSchema `yaml:"relations" json:"relations"`
+	Indexes   []IndexSchema    `yaml:"indexes" json:"indexes"`
+}
+
+// FieldSchema is a field declaration. It accepts the same shorthand as the
+// command line ("price:float64:default=0") or an object with explicit keys.
+type FieldSchema struct {
+	Name     string `yaml:"name" json:"name"`
+	Type     string `yaml:"type" json:"type"`
+	Unique   bool   `yaml:"unique" json:"unique"`
+	Nullable bool   `yaml:"nullable" json:"nullable"`
+	Index    bool   `yaml:"index" json:"index"`
+	Default  string `yaml:"default" json:"default"`
+	Size     int    `yaml:"size" json:"size"`
+
+	spec string // shorthand form, when used
+}
+
+// RelationSchema declares a relation to another entity of the schema
+type RelationSchema struct {
+	Type   string `yaml:"type" json:"type"`
+	Entity string `yaml:"entity" json:"entity"`
+}
+
+// IndexSchema declares a (possibly composite) index
+type IndexSchema struct {
+	Name   string   `yaml:"name" json:"name"`
+	Fields []string `yaml:"fields" json:"fields"`
+	Unique bool     `yaml:"unique" json:"unique"`
+}
+
+// UnmarshalYAML accepts both the shorthand and the object form of a field
+func (f *FieldSchema) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		f.spec = node.Value
+		return nil
+	}
+
+	type plain FieldSchema
+	return node.Decode((*plain)(f))
+}
+
+// LoadSchema reads and validates a schema file. JSON is a subset of YAML,
+// so both formats go through the same decoder.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema: %w", err)
+	}
+
+	var schema Schema
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	if err := schema.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+
+	return &schema, nil
+}
+
+// Validate checks entity names, field declarations, relations and indexes
+func (s *Schema) Validate() error {
+	if len(s.Entities) == 0 {
+		return fmt.Errorf("no entities declared")
+	}
+
+	entities := make(map[string]bool)
+	for _, entity := range s.Entities {
+		if err := ValidateComponentName(entity.Name); err != nil {
+			return fmt.Errorf("entity %q: %w", entity.Name, err)
+		}
+		key := strings.ToLower(entity.Name)
+		if entities[key] {
+			return fmt.Errorf("entity %q declared more than once", entity.Name)
+		}
+		entities[key] = true
+	}
+
+	for _, entity := range s.Entities {
+		for _, relation := range entity.Relations {
+			switch relation.Type {
+			case RelationBelongsTo, RelationHasMany, RelationManyToMany:
+			default:
+				return fmt.Errorf("entity %q: unknown relation type %q (use %s, %s or %s)",
+					entity.Name, relation.Type, RelationBelongsTo, RelationHasMany, RelationManyToMany)
+			}
+			if !entities[strings.ToLower(relation.Entity)] {
+				return fmt.Errorf("entity %q: relation to unknown entity %q", entity.Name, relation.Entity)
+			}
+		}
+
+		if _, err := entity.ResolveFields(); err != nil {
+			return fmt.Errorf("entity %q: %w", entity.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ResolveFields converts the declared fields, belongs_to foreign keys and
+// composite indexes into the fields used by the module templates
+func (e EntitySchema) ResolveFields() ([]Field, error) {
+	specs := make([]string, 0, len(e.Fields))
+	for _, fs := range e.Fields {
+		spec, err := fs.Spec()
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+
+	fields, err := ParseFields(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each belongs_to relation needs an indexed foreign key column
+	fields = mergeRelationFields(fields, e.ResolveRelations())
+
+	byName := make(map[string]int, len(fields))
+	for i, f := range fields {
+		byName[f.Name] = i
+	}
+
+	for _, index := range e.Indexes {
+		if len(index.Fields) == 0 {
+			return nil, fmt.Errorf("index %q has no fields", index.Name)
+		}
+
+		name := index.Name
+		if name == "" {
+			name = "idx_" + toSnakeCase(toPlural(e.Name))
+			for _, field := range index.Fields {
+				name += "_" + toSnakeCase(field)
+			}
+		}
+
+		for _, field := range index.Fields {
+			i, ok := byName[toSnakeCase(field)]
+			if !ok {
+				return nil, fmt.Errorf("index %q references unknown field %q", name, field)
+			}
+			if index.Unique {
+				fields[i].UniqueIndexNames = append(fields[i].UniqueIndexNames, name)
+			} else {
+				fields[i].IndexNames = append(fields[i].IndexNames, name)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// ResolveRelations returns the relations of the entity
+func (e EntitySchema) ResolveRelations() []Relation {
+	relations := make([]Relation, 0, len(e.Relations))
+	for _, r := range e.Relations {
+		relations = append(relations, Relation{Kind: r.Type, Entity: r.Entity})
+	}
+	return relations
+}
+
+// Spec returns the field in the name:type[:modifier...] form
+func (f FieldSchema) Spec() (string, error) {
+	if f.spec != "" {
+		return f.spec, nil
+	}
+	if f.Name == "" || f.Type == "" {
+		return "", fmt.Errorf("field declarations need a name and a type")
+	}
+
+	parts := []string{f.Name, f.Type}
+	if f.Unique {
+		parts = append(parts, "unique")
+	}
+	if f.Nullable {
+		parts = append(parts, "nullable")
+	}
+	if f.Index {
+		parts = append(parts, "index")
+	}
+	if f.Default != "" {
+		parts = append(parts, "default="+f.Default)
+	}
+	if f.Size > 0 {
+		parts = append(parts, fmt.Sprintf("size=%d", f.Size))
+	}
+
+	return strings.Join(parts, ":"), nil
+}
@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ChangeKind describes what a sync does to a generated file
+type ChangeKind string
+
+const (
+	ChangeCreate    ChangeKind = "create"
+	ChangeUpdate    ChangeKind = "update"
+	ChangeMerged    ChangeKind = "merged"
+	ChangeConflict  ChangeKind = "conflict"
+	ChangeDelete    ChangeKind = "delete"
+	ChangeUnchanged ChangeKind = "unchanged"
+	ChangeSkipped   ChangeKind = "skipped"
+)
+
+// FileChange is the outcome of a sync for a single file
+type FileChange struct {
+	Path   string
+	Kind   ChangeKind
+	Reason string
+}
+
+// SyncModule brings the files of a module in line with its declaration.
+// Missing files are created and files that are still exactly what Loom
+// generated are rewritten. Files edited by hand are merged three ways with
+// the content they were generated from; when it is unknown they are left
+// alone unless force is set. The changes are staged like every other
+// generator write.
+func (g *ModuleGenerator) SyncModule(name string, fields []Field, relations []Relation, force bool) ([]FileChange, error) {
+	generatorName := "module:" + strings.ToLower(name)
+
+	plan, err := g.PlanModule(name, fields, relations)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, planned := range plan {
+		change, err := g.planChange(planned, force)
+		if err != nil {
+			return changes, fmt.Errorf("%s: %w", planned.Path, err)
+		}
+
+		switch change.Kind {
+		case ChangeCreate, ChangeUpdate, ChangeMerged:
+			conflicts, err := g.createFile(planned.Path, planned.Content, true)
+			if err != nil {
+				return changes, fmt.Errorf("%s: %w", planned.Path, err)
+			}
+			if conflicts {
+				change.Kind = ChangeConflict
+				change.Reason = "resolve the conflict markers"
+			}
+			g.track(planned, generatorName)
+		case ChangeUnchanged:
+			// Adopt identical files generated before state tracking existed
+			if g.state != nil && !g.state.Tracked(planned.Path) {
+				g.track(planned, generatorName)
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	registryPath, err := g.registerModels(name, relations)
+	if err != nil {
+		fmt.Printf("⚠️  models_all.go: %v\n", err)
+	} else if registryPath != "" {
+		changes = append(changes, FileChange{Path: registryPath, Kind: ChangeUpdate, Reason: "register models"})
+	}
+
+	docsPath, err := g.appendModuleDocs(name, moduleFields(fields, relations), relations)
+	if err != nil {
+		fmt.Printf("⚠️  docs/API.md: %v\n", err)
+	} else if docsPath != "" {
+		changes = append(changes, FileChange{Path: docsPath, Kind: ChangeUpdate, Reason: "document endpoints"})
+	}
+
+	return changes, nil
+}
+
+// planChange decides what a sync does with a rendered file
+func (g *ModuleGenerator) planChange(planned PlannedFile, force bool) (FileChange, error) {
+	change := FileChange{Path: planned.Path}
+
+	current, err := g.changes.ReadFile(planned.Path)
+	if os.IsNotExist(err) {
+		change.Kind = ChangeCreate
+		return change, nil
+	}
+	if err != nil {
+		return change, err
+	}
+
+	if string(current) == planned.Content {
+		change.Kind = ChangeUnchanged
+		return change, nil
+	}
+
+	tracked := g.state != nil && g.state.Tracked(planned.Path)
+	if tracked {
+		pristine, err := g.state.IsPristine(planned.Path)
+		if err != nil {
+			return change, err
+		}
+		if pristine {
+			change.Kind = ChangeUpdate
+			return change, nil
+		}
+		if base, ok := g.state.Base(planned.Path); ok {
+			// Only the user changed the file since it was generated
+			if string(base) == planned.Content {
+				change.Kind = ChangeUnchanged
+				return change, nil
+			}
+			change.Kind = ChangeMerged
+			change.Reason = "keeps your edits"
+			return change, nil
+		}
+	}
+
+	if force {
+		change.Kind = ChangeUpdate
+		change.Reason = "forced"
+		return change, nil
+	}
+
+	change.Kind = ChangeSkipped
+	if tracked {
+		change.Reason = "modified since generation, use --force to overwrite"
+	} else {
+		change.Reason = "not generated by Loom, use --force to overwrite"
+	}
+	return change, nil
+}
@@ -0,0 +1,303 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/geomark27/loom-go/internal/source"
+)
+
+// serverDir holds the HTTP server scaffolded by "loom new"
+const serverDir = "internal/platform/server"
+
+// databaseDir holds the GORM connection created by "loom add orm gorm"
+const databaseDir = "internal/database"
+
+// WireModule registers a generated module in the project server so its
+// routes are served without manual edits:
+//
+//   - Layered: the repository, service and handler are constructed in
+//     server.go and a route group is added to registerRoutes in routes.go
+//   - Modular: NewModule(eventBus) is constructed in server.go and its
+//     routes are registered on the /api/v1 group
+//
+// In GORM projects the repository is given the connection of
+// internal/database.
+//
+// Wiring is idempotent. Returns the files that were changed (staged).
+func (g *ModuleGenerator) WireModule(name string, relations []Relation) ([]string, error) {
+	var files []*source.File
+	var err error
+
+	if g.project.Architecture == "layered" {
+		files, err = g.wireLayered(name, relations)
+	} else {
+		files, err = g.wireModular(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, file := range files {
+		if !file.Changed() {
+			continue
+		}
+		if err := file.Save(); err != nil {
+			return changed, err
+		}
+		changed = append(changed, file.Path())
+	}
+
+	return changed, nil
+}
+
+// usesGORM reports whether generated repositories are backed by GORM
+func (g *ModuleGenerator) usesGORM() bool {
+	return g.project.ORM == "gorm"
+}
+
+// repositoryArgs returns the arguments of the repository constructor
+func (g *ModuleGenerator) repositoryArgs() string {
+	if g.usesGORM() {
+		return "database.GetDB()"
+	}
+	return ""
+}
+
+// wireLayered patches server.go and routes.go of a layered project
+func (g *ModuleGenerator) wireLayered(name string, relations []Relation) ([]*source.File, error) {
+	nameLower := strings.ToLower(name)
+	nameTitle := moduleTypeName(name)
+	varName := toCamelCase(nameLower)
+	handlerVar := varName + "Handler"
+
+	server, err := source.LoadFrom(g.changes, path.Join(serverDir, "server.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range []string{"handlers", "services", "repositories"} {
+		if _, err := server.AddImport(g.project.ModuleName + "/internal/app/" + pkg); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.usesGORM() {
+		if _, err := server.AddImport(g.project.ModuleName + "/" + databaseDir); err != nil {
+			return nil, err
+		}
+	}
+
+	construction := fmt.Sprintf(`// %s module
+%sRepo := repositories.New%sRepository(%s)
+%sService := services.New%sService(%sRepo)
+%s := handlers.New%sHandler(%sService)
+`, nameTitle, varName, nameTitle, g.repositoryArgs(), varName, nameTitle, varName, handlerVar, nameTitle, varName)
+
+	if _, err := server.InsertBeforeCall("New", "registerRoutes", construction); err != nil {
+		return nil, err
+	}
+	if _, err := server.AddCallArg("New", "registerRoutes", handlerVar); err != nil {
+		return nil, err
+	}
+
+	routes, err := source.LoadFrom(g.changes, path.Join(serverDir, "routes.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := routes.AddImport(g.project.ModuleName + "/internal/app/handlers"); err != nil {
+		return nil, err
+	}
+	// chi route groups take a func(r chi.Router)
+	if r := g.router(); r.Name == RouterChi {
+		if _, err := routes.AddImport(r.Import); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := routes.AddParam("registerRoutes", fmt.Sprintf("%s *handlers.%sHandler", handlerVar, nameTitle)); err != nil {
+		return nil, err
+	}
+	if _, err := routes.AppendToFunc("registerRoutes", g.routeGroup(name, handlerVar, relations)); err != nil {
+		return nil, err
+	}
+
+	return []*source.File{server, routes}, nil
+}
+
+// wireModular patches server.go of a modular project
+func (g *ModuleGenerator) wireModular(name string) ([]*source.File, error) {
+	nameLower := strings.ToLower(name)
+	moduleVar := toCamelCase(nameLower) + "Module"
+
+	server, err := source.LoadFrom(g.changes, path.Join(serverDir, "server.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := server.AddImport(g.project.ModuleName + "/internal/modules/" + nameLower); err != nil {
+		return nil, err
+	}
+
+	args := "eventBus"
+	if g.usesGORM() {
+		if _, err := server.AddImport(g.project.ModuleName + "/" + databaseDir); err != nil {
+			return nil, err
+		}
+		args += ", " + g.repositoryArgs()
+	}
+
+	construction := fmt.Sprintf("%s := %s.NewModule(%s)", moduleVar, nameLower, args)
+	if _, err := server.InsertAfterCall("New", "NewModule", construction); err != nil {
+		return nil, err
+	}
+
+	registration := fmt.Sprintf("%s.RegisterRoutes(api)", moduleVar)
+	if _, err := server.InsertAfterCall("New", "RegisterRoutes", registration); err != nil {
+		return nil, err
+	}
+
+	return []*source.File{server}, nil
+}
+
+// UnwireModule removes what WireModule added for a module. Code edited by
+// hand may not be recognized; it is reported in the second result (the
+// files still referring to the module). Returns the files that were
+// changed (staged).
+func (g *ModuleGenerator) UnwireModule(name string) ([]string, []string, error) {
+	var files []*source.File
+	var ident string
+	var err error
+
+	if g.project.Architecture == "layered" {
+		ident = toCamelCase(strings.ToLower(name)) + "Handler"
+		files, err = g.unwireLayered(name)
+	} else {
+		ident = toCamelCase(strings.ToLower(name)) + "Module"
+		files, err = g.unwireModular(name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changed, leftover []string
+	for _, file := range files {
+		if file.Uses(ident) {
+			leftover = append(leftover, file.Path())
+		}
+		if !file.Changed() {
+			continue
+		}
+		if err := file.Save(); err != nil {
+			return changed, leftover, err
+		}
+		changed = append(changed, file.Path())
+	}
+
+	return changed, leftover, nil
+}
+
+// unwireLayered undoes wireLayered
+func (g *ModuleGenerator) unwireLayered(name string) ([]*source.File, error) {
+	varName := toCamelCase(strings.ToLower(name))
+	handlerVar := varName + "Handler"
+
+	server, err := source.LoadFrom(g.changes, path.Join(serverDir, "server.go"))
+	if err != nil {
+		return nil, err
+	}
+	// The argument goes first: the registerRoutes call refers to the handler
+	if _, err := server.RemoveCallArg("New", "registerRoutes", handlerVar); err != nil {
+		return nil, err
+	}
+	if _, err := server.RemoveStatementsUsing("New", varName+"Repo", varName+"Service", handlerVar); err != nil {
+		return nil, err
+	}
+	for _, pkg := range []string{"handlers", "services", "repositories"} {
+		if _, err := server.RemoveUnusedImport(g.project.ModuleName + "/internal/app/" + pkg); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := server.RemoveUnusedImport(g.project.ModuleName + "/" + databaseDir); err != nil {
+		return nil, err
+	}
+
+	routes, err := source.LoadFrom(g.changes, path.Join(serverDir, "routes.go"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := routes.RemoveParam("registerRoutes", handlerVar); err != nil {
+		return nil, err
+	}
+	if _, err := routes.RemoveStatementsUsing("registerRoutes", handlerVar, varName+"Routes"); err != nil {
+		return nil, err
+	}
+	imports := []string{g.project.ModuleName + "/internal/app/handlers"}
+	if r := g.router(); r.Import != "" {
+		imports = append(imports, r.Import)
+	}
+	for _, imp := range imports {
+		if _, err := routes.RemoveUnusedImport(imp); err != nil {
+			return nil, err
+		}
+	}
+
+	return []*source.File{server, routes}, nil
+}
+
+// unwireModular undoes wireModular
+func (g *ModuleGenerator) unwireModular(name string) ([]*source.File, error) {
+	nameLower := strings.ToLower(name)
+
+	server, err := source.LoadFrom(g.changes, path.Join(serverDir, "server.go"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := server.RemoveStatementsUsing("New", toCamelCase(nameLower)+"Module"); err != nil {
+		return nil, err
+	}
+	for _, imp := range []string{"internal/modules/" + nameLower, databaseDir} {
+		if _, err := server.RemoveUnusedImport(g.project.ModuleName + "/" + imp); err != nil {
+			return nil, err
+		}
+	}
+
+	return []*source.File{server}, nil
+}
+
+// routeGroup returns the route registrations of a layered module
+func (g *ModuleGenerator) routeGroup(name, handlerVar string, relations []Relation) string {
+	nameLower := strings.ToLower(name)
+	routes := g.router().Routes("api", toCamelCase(nameLower)+"Routes", resourcePath(name), handlerVar, NestedRoutes(name, relations))
+	return fmt.Sprintf("// %s routes\n%s", moduleTypeName(nameLower), routes)
+}
+
+// ManualWiring returns the code WireModule would add, for projects where
+// wiring is disabled or server.go was restructured by hand
+func (g *ModuleGenerator) ManualWiring(name string, relations []Relation) string {
+	nameLower := strings.ToLower(name)
+	varName := toCamelCase(nameLower)
+
+	if g.project.Architecture != "layered" {
+		args := "eventBus"
+		if g.usesGORM() {
+			args += ", " + g.repositoryArgs()
+		}
+		return fmt.Sprintf("// %s/server.go\n%sModule := %s.NewModule(%s)\n%sModule.RegisterRoutes(api)\n",
+			serverDir, varName, nameLower, args, varName)
+	}
+
+	nameTitle := moduleTypeName(name)
+	handlerVar := varName + "Handler"
+
+	return fmt.Sprintf(`// %s/server.go
+%sRepo := repositories.New%sRepository(%s)
+%sService := services.New%sService(%sRepo)
+%s := handlers.New%sHandler(%sService)
+
+// %s/routes.go (pass %s to registerRoutes)
+%s`, serverDir, varName, nameTitle, g.repositoryArgs(), varName, nameTitle, varName, handlerVar, nameTitle, varName,
+		serverDir, handlerVar, g.routeGroup(name, handlerVar, relations))
+}
@@ -0,0 +1,189 @@
+// Package golden compares generated files against golden files kept in
+// the testdata directory of the package under test.
+//
+// A golden file bundles several generated files, each one introduced by a
+// "-- path --" header line. Run the tests with -update to rewrite the
+// golden files after an intended change to the templates:
+//
+//	go test ./internal/... -update
+package golden
+
+import (
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/geomark27/loom-go/internal/state"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// dir is the golden directory of the package under test. It is resolved
+// when the test binary starts, before tests change the working directory.
+var dir = func() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return filepath.Join("testdata", "golden")
+	}
+	return filepath.Join(wd, "testdata", "golden")
+}()
+
+// Files returns the files below root, relative to it and sorted. The
+// .loom state directory is left out (it holds timestamps).
+func Files(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == state.Dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Changed returns the files below root that are not exactly what "loom
+// new" generated: files added by generators and addons, and skeleton
+// files they edited (according to .loom/generated.json)
+func Changed(root string) ([]string, error) {
+	st, err := state.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	files, err := Files(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, file := range files {
+		if entry, ok := st.Files[file]; ok && entry.Generator == "project" {
+			pristine, err := st.IsPristine(file)
+			if err != nil {
+				return nil, err
+			}
+			if pristine {
+				continue
+			}
+		}
+		changed = append(changed, file)
+	}
+	return changed, nil
+}
+
+// Archive reads files (relative to root) into a single golden text
+func Archive(root string, files []string) (string, error) {
+	var b strings.Builder
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString("-- " + file + " --\n")
+		b.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// ParseGo fails the test for every Go file among files that does not parse
+func ParseGo(t testing.TB, root string, files []string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		if _, err := parser.ParseFile(fset, filepath.Join(root, filepath.FromSlash(file)), nil, parser.AllErrors); err != nil {
+			t.Errorf("generated file does not parse: %v", err)
+		}
+	}
+}
+
+// Assert compares got with the golden file name (below testdata/golden,
+// without the .golden extension), or rewrites it with -update
+func Assert(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name)+".golden")
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the golden file (run the tests with -update to accept the change):\n%s",
+			name, firstDifference(string(want), got))
+	}
+}
+
+// Chdir changes the working directory for the rest of the test; the
+// module generator works relative to the project root
+func Chdir(t testing.TB, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// firstDifference describes the first line where want and got differ,
+// with the section of the archive it belongs to
+func firstDifference(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	section := ""
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if strings.HasPrefix(w, "-- ") && w == g {
+			section = strings.Trim(w, "- ")
+		}
+		if w != g {
+			return fmt.Sprintf("  %s (line %d of the archive)\n  want: %s\n  got:  %s", section, i+1, w, g)
+		}
+	}
+	return ""
+}
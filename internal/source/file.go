@@ -0,0 +1,655 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// File is a Go source file edited through its syntax tree.
+//
+// Edits are located with go/ast and spliced into the source at the
+// positions of the nodes they extend, so comments, aliased imports and the
+// existing layout survive. The result is printed with go/printer using the
+// gofmt settings. Every edit is idempotent: applying it twice is a no-op.
+type File struct {
+	fsys    FS
+	path    string
+	src     []byte
+	fset    *token.FileSet
+	file    *ast.File
+	changed bool
+}
+
+// FS reads and writes the files edited by this package. A change set
+// satisfies it, so edits can be staged instead of written to disk.
+type FS interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, content []byte) error
+}
+
+// Disk is the FS of the real filesystem
+var Disk FS = disk{}
+
+type disk struct{}
+
+func (disk) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (disk) WriteFile(path string, content []byte) error {
+	return os.WriteFile(path, content, 0644)
+}
+
+// Load parses the Go file at path
+func Load(path string) (*File, error) {
+	return LoadFrom(Disk, path)
+}
+
+// LoadFrom parses the Go file at path in fsys; Save writes it back there
+func LoadFrom(fsys FS, path string) (*File, error) {
+	src, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(path, src)
+	if err != nil {
+		return nil, err
+	}
+	f.fsys = fsys
+	return f, nil
+}
+
+// Parse parses Go source; path is only used for error messages and Save
+func Parse(path string, src []byte) (*File, error) {
+	f := &File{fsys: Disk, path: path}
+	if err := f.reparse(src); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the path the file was loaded from
+func (f *File) Path() string {
+	return f.path
+}
+
+// Changed reports whether any edit modified the file
+func (f *File) Changed() bool {
+	return f.changed
+}
+
+// Bytes returns the gofmt'd source
+func (f *File) Bytes() ([]byte, error) {
+	ast.SortImports(f.fset, f.file)
+
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, f.fset, f.file); err != nil {
+		return nil, fmt.Errorf("error printing %s: %w", f.path, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the file back when it was changed
+func (f *File) Save() error {
+	if !f.changed {
+		return nil
+	}
+	content, err := f.Bytes()
+	if err != nil {
+		return err
+	}
+	return f.fsys.WriteFile(f.path, content)
+}
+
+// HasImport reports whether the file imports path (with or without alias)
+func (f *File) HasImport(path string) bool {
+	for _, spec := range f.file.Imports {
+		if value, err := strconv.Unquote(spec.Path.Value); err == nil && value == path {
+			return true
+		}
+	}
+	return false
+}
+
+// AddImport adds an import. Returns false when it is already imported.
+func (f *File) AddImport(path string) (bool, error) {
+	return f.AddNamedImport("", path)
+}
+
+// AddNamedImport adds an import with an optional alias
+func (f *File) AddNamedImport(name, path string) (bool, error) {
+	if f.HasImport(path) {
+		return false, nil
+	}
+
+	spec := strconv.Quote(path)
+	if name != "" {
+		spec = name + " " + spec
+	}
+
+	var decl *ast.GenDecl
+	for _, d := range f.file.Decls {
+		if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			decl = gd
+			// Prefer the grouped import block
+			if gd.Lparen.IsValid() {
+				break
+			}
+		}
+	}
+
+	switch {
+	case decl == nil:
+		// No imports yet: add a declaration after the package clause
+		return true, f.insert(f.offset(f.file.Name.End()), "\n\nimport "+spec+"\n")
+
+	case decl.Lparen.IsValid() && len(decl.Specs) == 0:
+		return true, f.insert(f.offset(decl.Rparen), "\n\t"+spec+"\n")
+
+	case decl.Lparen.IsValid():
+		// Insert after the last spec, past its trailing comment if any
+		at := f.offset(decl.Specs[len(decl.Specs)-1].End())
+		if nl := bytes.IndexByte(f.src[at:f.offset(decl.Rparen)], '\n'); nl >= 0 {
+			at += nl
+		}
+		return true, f.insert(at, "\n\t"+spec)
+
+	default:
+		// Single import declaration: turn it into a block
+		existing := string(f.src[f.offset(decl.Specs[0].Pos()):f.offset(decl.Specs[0].End())])
+		return true, f.replace(f.offset(decl.Pos()), f.offset(decl.End()), "import (\n\t"+existing+"\n\t"+spec+"\n)")
+	}
+}
+
+// AppendToSlice appends expr to the composite literal assigned to the
+// package-level variable varName, e.g. AllModels = []interface{}{...}.
+// Returns false when an identical element is already present.
+func (f *File) AppendToSlice(varName, expr string) (bool, error) {
+	if _, err := parser.ParseExpr(expr); err != nil {
+		return false, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+
+	lit := f.findSliceLiteral(varName)
+	if lit == nil {
+		return false, fmt.Errorf("%s: composite literal %s not found", f.path, varName)
+	}
+
+	for _, elt := range lit.Elts {
+		if f.sameCode(elt, expr) {
+			return false, nil
+		}
+	}
+
+	singleLine := f.fset.Position(lit.Lbrace).Line == f.fset.Position(lit.Rbrace).Line
+
+	switch {
+	case len(lit.Elts) == 0 && singleLine:
+		return true, f.insert(f.offset(lit.Rbrace), "\n\t"+expr+",\n")
+	case len(lit.Elts) == 0:
+		// Keep the placeholder comments of an empty literal first
+		return true, f.insert(f.offset(lit.Rbrace), "\t"+expr+",\n")
+	case singleLine:
+		// Spread a one-line literal over several lines, one element each
+		elts := make([]string, 0, len(lit.Elts)+1)
+		for _, elt := range lit.Elts {
+			elts = append(elts, f.code(elt))
+		}
+		elts = append(elts, expr)
+		return true, f.replace(f.offset(lit.Lbrace)+1, f.offset(lit.Rbrace), "\n\t"+strings.Join(elts, ",\n\t")+",\n")
+	}
+	return true, f.insert(f.offset(lit.Elts[len(lit.Elts)-1].End()), ",\n\t"+expr)
+}
+
+// AppendToFunc appends statements to the body of a function, before its
+// final return statement when there is one. funcName is either a function
+// name or Type.Method. Returns false when every statement is already there.
+func (f *File) AppendToFunc(funcName, code string) (bool, error) {
+	stmts, err := parseStatements(code)
+	if err != nil {
+		return false, err
+	}
+
+	fn := f.findFunc(funcName)
+	if fn == nil || fn.Body == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	if f.containsStatements(fn.Body, stmts) {
+		return false, nil
+	}
+
+	code = strings.TrimRight(code, "\n")
+	body := fn.Body.List
+	if len(body) > 0 {
+		if ret, ok := body[len(body)-1].(*ast.ReturnStmt); ok {
+			return true, f.insert(f.leadingPos(ret), code+"\n\n")
+		}
+	}
+	return true, f.insert(f.offset(fn.Body.Rbrace), "\n"+code+"\n")
+}
+
+// InsertBeforeCall inserts statements before the first statement of a
+// function that calls callee (a name such as "registerRoutes" or a
+// selector suffix such as "RegisterRoutes")
+func (f *File) InsertBeforeCall(funcName, callee, code string) (bool, error) {
+	return f.insertAtCall(funcName, callee, code, false)
+}
+
+// InsertAfterCall inserts statements after the last statement of a
+// function that calls callee
+func (f *File) InsertAfterCall(funcName, callee, code string) (bool, error) {
+	return f.insertAtCall(funcName, callee, code, true)
+}
+
+// insertAtCall implements InsertBeforeCall and InsertAfterCall
+func (f *File) insertAtCall(funcName, callee, code string, after bool) (bool, error) {
+	stmts, err := parseStatements(code)
+	if err != nil {
+		return false, err
+	}
+
+	fn := f.findFunc(funcName)
+	if fn == nil || fn.Body == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	if f.containsStatements(fn.Body, stmts) {
+		return false, nil
+	}
+
+	var anchor ast.Stmt
+	for _, stmt := range fn.Body.List {
+		if f.callsFunc(stmt, callee) {
+			anchor = stmt
+			if !after {
+				break
+			}
+		}
+	}
+	if anchor == nil {
+		return false, fmt.Errorf("%s: no call to %s in %s", f.path, callee, funcName)
+	}
+
+	code = strings.TrimRight(code, "\n")
+	if after {
+		return true, f.insert(f.offset(anchor.End()), "\n"+code)
+	}
+	return true, f.insert(f.leadingPos(anchor), code+"\n\n")
+}
+
+// leadingPos returns the offset of a statement including the comment
+// directly above it, so inserted code does not split a comment from the
+// statement it documents
+func (f *File) leadingPos(stmt ast.Stmt) int {
+	pos := stmt.Pos()
+	line := f.fset.Position(pos).Line
+	for _, group := range f.file.Comments {
+		if group.End() < pos && f.fset.Position(group.End()).Line == line-1 {
+			pos = group.Pos()
+			line = f.fset.Position(pos).Line
+		}
+	}
+	return f.offset(pos)
+}
+
+// AddParam appends a parameter ("name Type") to a function signature.
+// Returns false when a parameter with that name already exists.
+func (f *File) AddParam(funcName, param string) (bool, error) {
+	fn := f.findFunc(funcName)
+	if fn == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	name, _, _ := strings.Cut(strings.TrimSpace(param), " ")
+	params := fn.Type.Params
+	for _, field := range params.List {
+		for _, ident := range field.Names {
+			if ident.Name == name {
+				return false, nil
+			}
+		}
+	}
+
+	if len(params.List) == 0 {
+		return true, f.insert(f.offset(params.Opening)+1, param)
+	}
+	return true, f.insert(f.offset(params.List[len(params.List)-1].End()), ",\n\t"+param)
+}
+
+// AddCallArg appends an argument to every call to callee inside a
+// function. Returns false when the calls already pass it.
+func (f *File) AddCallArg(funcName, callee, arg string) (bool, error) {
+	fn := f.findFunc(funcName)
+	if fn == nil || fn.Body == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	// Offsets are collected first and applied from the end of the file
+	// so that earlier offsets stay valid
+	var offsets []int
+	found := false
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !f.calleeMatches(call.Fun, callee) {
+			return true
+		}
+		found = true
+		for _, a := range call.Args {
+			if f.sameCode(a, arg) {
+				return true
+			}
+		}
+		if len(call.Args) == 0 {
+			offsets = append(offsets, -f.offset(call.Lparen)-1)
+		} else {
+			offsets = append(offsets, f.offset(call.Args[len(call.Args)-1].End()))
+		}
+		return true
+	})
+
+	if !found {
+		return false, fmt.Errorf("%s: no call to %s in %s", f.path, callee, funcName)
+	}
+
+	for i := len(offsets) - 1; i >= 0; i-- {
+		var err error
+		if offsets[i] < 0 {
+			err = f.insert(-offsets[i], arg)
+		} else {
+			err = f.insert(offsets[i], ", "+arg)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return len(offsets) > 0, nil
+}
+
+// AddField appends a field ("Name Type `tag`") to the struct typeName.
+// Returns false when a field with that name already exists.
+func (f *File) AddField(typeName, field string) (bool, error) {
+	st := f.findStruct(typeName)
+	if st == nil {
+		return false, fmt.Errorf("%s: struct %s not found", f.path, typeName)
+	}
+
+	name, _, _ := strings.Cut(strings.TrimSpace(field), " ")
+	for _, existing := range st.Fields.List {
+		for _, ident := range existing.Names {
+			if ident.Name == name {
+				return false, nil
+			}
+		}
+	}
+
+	return true, f.insert(f.offset(st.Fields.Closing), "\t"+field+"\n")
+}
+
+// AddLiteralField appends "key: value" to the composite literal of type
+// typeName (e.g. "Config" matches &Config{...}) inside a function.
+// Returns false when the literal already sets key.
+func (f *File) AddLiteralField(funcName, typeName, key, value string) (bool, error) {
+	if _, err := parser.ParseExpr(value); err != nil {
+		return false, fmt.Errorf("invalid expression %q: %w", value, err)
+	}
+
+	fn := f.findFunc(funcName)
+	if fn == nil || fn.Body == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	var lit *ast.CompositeLit
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if c, ok := n.(*ast.CompositeLit); ok && lit == nil && c.Type != nil && f.sameCode(c.Type, typeName) {
+			lit = c
+		}
+		return lit == nil
+	})
+	if lit == nil {
+		return false, fmt.Errorf("%s: no %s literal in %s", f.path, typeName, funcName)
+	}
+
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok && f.sameCode(kv.Key, key) {
+			return false, nil
+		}
+	}
+
+	entry := key + ": " + value
+	if len(lit.Elts) == 0 {
+		return true, f.insert(f.offset(lit.Rbrace), "\n\t"+entry+",\n")
+	}
+	return true, f.insert(f.offset(lit.Elts[len(lit.Elts)-1].End()), ",\n\t"+entry)
+}
+
+// AddDecl appends declarations (functions, methods, types) to the end of
+// the file, skipping the functions and methods that already exist
+func (f *File) AddDecl(code string) (bool, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, "", "package p\n"+code, parser.ParseComments)
+	if err != nil {
+		return false, fmt.Errorf("invalid declarations: %w", err)
+	}
+
+	for _, d := range parsed.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) == 1 {
+			name = receiverName(fn.Recv.List[0].Type) + "." + name
+		}
+		if f.findFunc(name) != nil {
+			return false, nil
+		}
+	}
+
+	return true, f.insert(len(f.src), "\n"+strings.TrimSpace(code)+"\n")
+}
+
+// callsFunc reports whether a node contains a call to callee
+func (f *File) callsFunc(node ast.Node, callee string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && f.calleeMatches(call.Fun, callee) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// calleeMatches reports whether a call target is callee or ends with .callee
+func (f *File) calleeMatches(fun ast.Expr, callee string) bool {
+	code := compact(f.code(fun))
+	return code == callee || strings.HasSuffix(code, "."+callee)
+}
+
+// HasCall reports whether the file calls the given selector, e.g.
+// "handlers.NewProductHandler", anywhere in its declarations
+func (f *File) HasCall(fun string) bool {
+	found := false
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && f.sameCode(call.Fun, fun) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// findSliceLiteral returns the composite literal assigned to varName
+func (f *File) findSliceLiteral(varName string) *ast.CompositeLit {
+	for _, d := range f.file.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			continue
+		}
+		for _, s := range gd.Specs {
+			vs := s.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if name.Name != varName || i >= len(vs.Values) {
+					continue
+				}
+				if lit, ok := vs.Values[i].(*ast.CompositeLit); ok {
+					return lit
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findStruct returns the struct type declared as typeName
+func (f *File) findStruct(typeName string) *ast.StructType {
+	for _, d := range f.file.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range gd.Specs {
+			ts := s.(*ast.TypeSpec)
+			if st, ok := ts.Type.(*ast.StructType); ok && ts.Name.Name == typeName {
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+// findFunc returns the declaration of a function or Type.Method
+func (f *File) findFunc(funcName string) *ast.FuncDecl {
+	recv, name, isMethod := strings.Cut(funcName, ".")
+	if !isMethod {
+		name = recv
+	}
+
+	for _, d := range f.file.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != name {
+			continue
+		}
+		if !isMethod && fn.Recv == nil {
+			return fn
+		}
+		if isMethod && fn.Recv != nil && len(fn.Recv.List) == 1 && receiverName(fn.Recv.List[0].Type) == recv {
+			return fn
+		}
+	}
+	return nil
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	}
+	return ""
+}
+
+// containsStatements reports whether every statement is already in body
+func (f *File) containsStatements(body *ast.BlockStmt, stmts []string) bool {
+	existing := make(map[string]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		if stmt, ok := n.(ast.Stmt); ok {
+			existing[compact(f.code(stmt))] = true
+		}
+		return true
+	})
+
+	for _, stmt := range stmts {
+		if !existing[compact(stmt)] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseStatements validates code and returns the source of each statement
+func parseStatements(code string) ([]string, error) {
+	src := "package p\nfunc _() {\n" + code + "\n}\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid statements %q: %w", code, err)
+	}
+
+	body := file.Decls[0].(*ast.FuncDecl).Body
+	stmts := make([]string, 0, len(body.List))
+	for _, stmt := range body.List {
+		stmts = append(stmts, src[fset.Position(stmt.Pos()).Offset:fset.Position(stmt.End()).Offset])
+	}
+	return stmts, nil
+}
+
+// sameCode compares a node with a snippet ignoring whitespace
+func (f *File) sameCode(node ast.Node, code string) bool {
+	return compact(f.code(node)) == compact(code)
+}
+
+// code returns the source text of a node
+func (f *File) code(node ast.Node) string {
+	return string(f.src[f.offset(node.Pos()):f.offset(node.End())])
+}
+
+// offset converts a position to a byte offset in the source
+func (f *File) offset(pos token.Pos) int {
+	return f.fset.Position(pos).Offset
+}
+
+// insert splices text at offset
+func (f *File) insert(offset int, text string) error {
+	return f.replace(offset, offset, text)
+}
+
+// replace swaps src[start:end] with text and parses the result again so
+// the positions of later edits stay valid
+func (f *File) replace(start, end int, text string) error {
+	src := make([]byte, 0, len(f.src)+len(text))
+	src = append(src, f.src[:start]...)
+	src = append(src, text...)
+	src = append(src, f.src[end:]...)
+
+	if err := f.reparse(src); err != nil {
+		return fmt.Errorf("edit produced invalid Go: %w", err)
+	}
+	f.changed = true
+	return nil
+}
+
+// reparse replaces the source and its syntax tree
+func (f *File) reparse(src []byte) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, f.path, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	f.src, f.fset, f.file = src, fset, file
+	return nil
+}
+
+// compact removes whitespace so snippets compare independently of layout
+func compact(code string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,281 @@
+package source
+
+import (
+	"testing"
+)
+
+// memFS is an in-memory FS
+type memFS map[string][]byte
+
+func (m memFS) ReadFile(path string) ([]byte, error) {
+	content, ok := m[path]
+	if !ok {
+		return nil, &notFoundError{path}
+	}
+	return content, nil
+}
+
+func (m memFS) WriteFile(path string, content []byte) error {
+	m[path] = content
+	return nil
+}
+
+type notFoundError struct{ path string }
+
+func (e *notFoundError) Error() string { return e.path + ": file does not exist" }
+
+// editTest is an edit applied to src, expected to give want
+type editTest struct {
+	name string
+	src  string
+	edit func(f *File) (bool, error)
+	want string
+}
+
+// runEditTests applies every edit twice: the first time must change the
+// file into want, the second must report no change and leave it as is
+func runEditTests(t *testing.T, tests []editTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := memFS{"a.go": []byte(tt.src)}
+
+			for i, wantChanged := range []bool{true, false} {
+				f, err := LoadFrom(fsys, "a.go")
+				if err != nil {
+					t.Fatal(err)
+				}
+				changed, err := tt.edit(f)
+				if err != nil {
+					t.Fatalf("edit %d: %v", i+1, err)
+				}
+				if changed != wantChanged {
+					t.Fatalf("edit %d changed = %v, want %v", i+1, changed, wantChanged)
+				}
+				if err := f.Save(); err != nil {
+					t.Fatal(err)
+				}
+				if got := string(fsys["a.go"]); got != tt.want {
+					t.Fatalf("edit %d gave:\n%s\nwant:\n%s", i+1, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddImport(t *testing.T) {
+	runEditTests(t, []editTest{
+		{
+			name: "no imports",
+			src:  "package a\n\nvar x = 1\n",
+			edit: func(f *File) (bool, error) { return f.AddImport("fmt") },
+			want: "package a\n\nimport \"fmt\"\n\nvar x = 1\n",
+		},
+		{
+			name: "single import",
+			src:  "package a\n\nimport \"os\"\n",
+			edit: func(f *File) (bool, error) { return f.AddImport("fmt") },
+			want: "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n",
+		},
+		{
+			name: "grouped",
+			src:  "package a\n\nimport (\n\t\"os\" // files\n\n\t\"example.com/app/models\"\n)\n",
+			edit: func(f *File) (bool, error) { return f.AddImport("example.com/app/services") },
+			want: "package a\n\nimport (\n\t\"os\" // files\n\n\t\"example.com/app/models\"\n\t\"example.com/app/services\"\n)\n",
+		},
+		{
+			name: "empty group",
+			src:  "package a\n\nimport ()\n",
+			edit: func(f *File) (bool, error) { return f.AddImport("fmt") },
+			want: "package a\n\nimport (\n\t\"fmt\"\n)\n",
+		},
+		{
+			name: "aliased",
+			src:  "package a\n\nimport (\n\t\"os\"\n)\n",
+			edit: func(f *File) (bool, error) {
+				return f.AddNamedImport("models", "example.com/app/internal/modules/users")
+			},
+			want: "package a\n\nimport (\n\tmodels \"example.com/app/internal/modules/users\"\n\t\"os\"\n)\n",
+		},
+	})
+}
+
+func TestAddImportAlreadyPresent(t *testing.T) {
+	src := "package a\n\nimport (\n\tm \"example.com/app/models\"\n)\n"
+	f, err := Parse("a.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The aliased import counts as present, with or without the alias
+	for _, add := range []func() (bool, error){
+		func() (bool, error) { return f.AddImport("example.com/app/models") },
+		func() (bool, error) { return f.AddNamedImport("models", "example.com/app/models") },
+	} {
+		added, err := add()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added {
+			t.Error("import added twice")
+		}
+	}
+	if f.Changed() {
+		t.Error("file changed")
+	}
+}
+
+func TestAppendToSlice(t *testing.T) {
+	runEditTests(t, []editTest{
+		{
+			name: "multi-line",
+			src:  "package a\n\nvar AllModels = []interface{}{\n\t&models.User{},\n}\n",
+			edit: func(f *File) (bool, error) { return f.AppendToSlice("AllModels", "&models.Product{}") },
+			want: "package a\n\nvar AllModels = []interface{}{\n\t&models.User{},\n\t&models.Product{},\n}\n",
+		},
+		{
+			name: "keeps the trailing comments",
+			src:  "package a\n\nvar AllSeeders = []Seeder{\n\t&UserSeeder{},\n\t// Add your seeders here\n}\n",
+			edit: func(f *File) (bool, error) { return f.AppendToSlice("AllSeeders", "&ProductSeeder{}") },
+			want: "package a\n\nvar AllSeeders = []Seeder{\n\t&UserSeeder{},\n\t&ProductSeeder{},\n\t// Add your seeders here\n}\n",
+		},
+		{
+			name: "empty",
+			src:  "package a\n\nvar AllModels = []interface{}{}\n",
+			edit: func(f *File) (bool, error) { return f.AppendToSlice("AllModels", "&models.User{}") },
+			want: "package a\n\nvar AllModels = []interface{}{\n\t&models.User{},\n}\n",
+		},
+		{
+			name: "single line",
+			src:  "package a\n\nvar names = []string{\"a\", \"b\"}\n",
+			edit: func(f *File) (bool, error) { return f.AppendToSlice("names", `"c"`) },
+			want: "package a\n\nvar names = []string{\n\t\"a\",\n\t\"b\",\n\t\"c\",\n}\n",
+		},
+	})
+}
+
+func TestAppendToFunc(t *testing.T) {
+	runEditTests(t, []editTest{
+		{
+			name: "before the return",
+			src:  "package a\n\nfunc setup() error {\n\tinit1()\n\n\treturn nil\n}\n",
+			edit: func(f *File) (bool, error) { return f.AppendToFunc("setup", "init2()") },
+			want: "package a\n\nfunc setup() error {\n\tinit1()\n\n\tinit2()\n\n\treturn nil\n}\n",
+		},
+		{
+			name: "without return",
+			src:  "package a\n\nfunc setup() {\n\tinit1()\n}\n",
+			edit: func(f *File) (bool, error) { return f.AppendToFunc("setup", "init2()") },
+			want: "package a\n\nfunc setup() {\n\tinit1()\n\n\tinit2()\n}\n",
+		},
+		{
+			name: "method",
+			src:  "package a\n\nfunc (s *Server) routes() {\n\ts.get()\n}\n",
+			edit: func(f *File) (bool, error) { return f.AppendToFunc("Server.routes", "s.post()") },
+			want: "package a\n\nfunc (s *Server) routes() {\n\ts.get()\n\n\ts.post()\n}\n",
+		},
+	})
+}
+
+func TestInsertBeforeCall(t *testing.T) {
+	runEditTests(t, []editTest{
+		{
+			name: "keeps the comment with its statement",
+			src: `package a
+
+func New() *Server {
+	users := newUsers()
+
+	// Routes
+	registerRoutes(users)
+	return nil
+}
+`,
+			edit: func(f *File) (bool, error) {
+				return f.InsertBeforeCall("New", "registerRoutes", "products := newProducts()")
+			},
+			want: `package a
+
+func New() *Server {
+	users := newUsers()
+
+	products := newProducts()
+
+	// Routes
+	registerRoutes(users)
+	return nil
+}
+`,
+		},
+		{
+			name: "selector callee",
+			src:  "package a\n\nfunc main() {\n\tapp.Run()\n}\n",
+			edit: func(f *File) (bool, error) { return f.InsertBeforeCall("main", "Run", "app.Use(logger)") },
+			want: "package a\n\nfunc main() {\n\tapp.Use(logger)\n\n\tapp.Run()\n}\n",
+		},
+	})
+}
+
+func TestInsertBeforeCallErrors(t *testing.T) {
+	f, err := Parse("a.go", []byte("package a\n\nfunc main() {\n\trun()\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.InsertBeforeCall("missing", "run", "x()"); err == nil {
+		t.Error("no error for a missing function")
+	}
+	if _, err := f.InsertBeforeCall("main", "start", "x()"); err == nil {
+		t.Error("no error for a missing call")
+	}
+	if _, err := f.InsertBeforeCall("main", "run", "x("); err == nil {
+		t.Error("no error for invalid code")
+	}
+}
+
+func TestAddCallArg(t *testing.T) {
+	runEditTests(t, []editTest{
+		{
+			name: "no arguments",
+			src:  "package a\n\nfunc New() {\n\tregisterRoutes()\n}\n",
+			edit: func(f *File) (bool, error) { return f.AddCallArg("New", "registerRoutes", "users") },
+			want: "package a\n\nfunc New() {\n\tregisterRoutes(users)\n}\n",
+		},
+		{
+			name: "after the last argument",
+			src:  "package a\n\nfunc New() {\n\tregisterRoutes(api, users)\n}\n",
+			edit: func(f *File) (bool, error) { return f.AddCallArg("New", "registerRoutes", "products") },
+			want: "package a\n\nfunc New() {\n\tregisterRoutes(api, users, products)\n}\n",
+		},
+	})
+}
+
+func TestAddFieldAndParam(t *testing.T) {
+	runEditTests(t, []editTest{
+		{
+			name: "field",
+			src:  "package a\n\ntype Config struct {\n\tPort string\n}\n",
+			edit: func(f *File) (bool, error) { return f.AddField("Config", "JWTSecret string") },
+			want: "package a\n\ntype Config struct {\n\tPort      string\n\tJWTSecret string\n}\n",
+		},
+		{
+			name: "param",
+			src:  "package a\n\nfunc registerRoutes(api *Router) {\n}\n",
+			edit: func(f *File) (bool, error) { return f.AddParam("registerRoutes", "users *Handler") },
+			want: "package a\n\nfunc registerRoutes(api *Router,\n\tusers *Handler) {\n}\n",
+		},
+		{
+			name: "literal field",
+			src:  "package a\n\nfunc Load() *Config {\n\treturn &Config{\n\t\tPort: \"8080\",\n\t}\n}\n",
+			edit: func(f *File) (bool, error) {
+				return f.AddLiteralField("Load", "Config", "DBName", `getEnv("DB_NAME", "app")`)
+			},
+			want: "package a\n\nfunc Load() *Config {\n\treturn &Config{\n\t\tPort:   \"8080\",\n\t\tDBName: getEnv(\"DB_NAME\", \"app\"),\n\t}\n}\n",
+		},
+		{
+			name: "declaration",
+			src:  "package a\n\nfunc a() {}\n",
+			edit: func(f *File) (bool, error) { return f.AddDecl("func b() {}") },
+			want: "package a\n\nfunc a() {}\n\nfunc b() {}\n",
+		},
+	})
+}
@@ -0,0 +1,75 @@
+package source
+
+import (
+	"fmt"
+
+	"golang.org/x/mod/modfile"
+)
+
+// AddRequire adds a requirement to the go.mod at path in fsys. Returns
+// false when the module is already required (at any version).
+func AddRequire(fsys FS, path, module, version string) (bool, error) {
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	file, err := modfile.Parse(path, content, nil)
+	if err != nil {
+		return false, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	for _, req := range file.Require {
+		if req.Mod.Path == module {
+			return false, nil
+		}
+	}
+
+	if err := file.AddRequire(module, version); err != nil {
+		return false, fmt.Errorf("error adding %s to %s: %w", module, path, err)
+	}
+	file.Cleanup()
+
+	formatted, err := file.Format()
+	if err != nil {
+		return false, err
+	}
+
+	return true, fsys.WriteFile(path, formatted)
+}
+
+// RemoveRequire removes the requirement of module from the go.mod at path
+// in fsys. Returns false when the module is not required.
+func RemoveRequire(fsys FS, path, module string) (bool, error) {
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	file, err := modfile.Parse(path, content, nil)
+	if err != nil {
+		return false, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	required := false
+	for _, req := range file.Require {
+		if req.Mod.Path == module {
+			required = true
+		}
+	}
+	if !required {
+		return false, nil
+	}
+
+	if err := file.DropRequire(module); err != nil {
+		return false, fmt.Errorf("error removing %s from %s: %w", module, path, err)
+	}
+	file.Cleanup()
+
+	formatted, err := file.Format()
+	if err != nil {
+		return false, err
+	}
+
+	return true, fsys.WriteFile(path, formatted)
+}
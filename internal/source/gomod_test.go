@@ -0,0 +1,79 @@
+package source
+
+import (
+	"strings"
+	"testing"
+)
+
+const testGoMod = `module example.com/shop
+
+go 1.23
+
+require github.com/go-chi/chi/v5 v5.0.12
+`
+
+func TestAddRequire(t *testing.T) {
+	fsys := memFS{"go.mod": []byte(testGoMod)}
+
+	for i, want := range []bool{true, false} {
+		added, err := AddRequire(fsys, "go.mod", "gorm.io/gorm", "v1.25.5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != want {
+			t.Fatalf("AddRequire %d = %v, want %v", i+1, added, want)
+		}
+	}
+
+	content := string(fsys["go.mod"])
+	if strings.Count(content, "gorm.io/gorm v1.25.5") != 1 {
+		t.Errorf("gorm required %d times:\n%s", strings.Count(content, "gorm.io/gorm"), content)
+	}
+	if !strings.Contains(content, "github.com/go-chi/chi/v5 v5.0.12") {
+		t.Errorf("existing requirement lost:\n%s", content)
+	}
+
+	// Any version counts as required
+	added, err := AddRequire(fsys, "go.mod", "github.com/go-chi/chi/v5", "v5.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added || strings.Contains(string(fsys["go.mod"]), "v5.1.0") {
+		t.Errorf("AddRequire replaced the required version:\n%s", fsys["go.mod"])
+	}
+}
+
+func TestRemoveRequire(t *testing.T) {
+	fsys := memFS{"go.mod": []byte(testGoMod)}
+	if _, err := AddRequire(fsys, "go.mod", "gorm.io/gorm", "v1.25.5"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{true, false} {
+		removed, err := RemoveRequire(fsys, "go.mod", "gorm.io/gorm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if removed != want {
+			t.Fatalf("RemoveRequire %d = %v, want %v", i+1, removed, want)
+		}
+	}
+
+	if got := string(fsys["go.mod"]); got != testGoMod {
+		t.Errorf("go.mod not restored:\n%s\nwant:\n%s", got, testGoMod)
+	}
+}
+
+func TestRequireErrors(t *testing.T) {
+	fsys := memFS{"go.mod": []byte("module\n")}
+
+	if _, err := AddRequire(fsys, "missing/go.mod", "gorm.io/gorm", "v1.25.5"); err == nil {
+		t.Error("AddRequire: no error for a missing go.mod")
+	}
+	if _, err := AddRequire(fsys, "go.mod", "gorm.io/gorm", "v1.25.5"); err == nil {
+		t.Error("AddRequire: no error for an invalid go.mod")
+	}
+	if _, err := RemoveRequire(fsys, "go.mod", "gorm.io/gorm"); err == nil {
+		t.Error("RemoveRequire: no error for an invalid go.mod")
+	}
+}
@@ -0,0 +1,386 @@
+package source
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The edits below undo the ones of file.go. Like them they are idempotent:
+// removing something that is not there is a no-op that returns false.
+
+// span is a byte range of the source to delete
+type span struct {
+	start, end int
+}
+
+// RemoveFromSlice removes the elements equal to expr from the composite
+// literal assigned to the package-level variable varName
+func (f *File) RemoveFromSlice(varName, expr string) (bool, error) {
+	if _, err := parser.ParseExpr(expr); err != nil {
+		return false, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+
+	lit := f.findSliceLiteral(varName)
+	if lit == nil {
+		return false, fmt.Errorf("%s: composite literal %s not found", f.path, varName)
+	}
+
+	var spans []span
+	for i, elt := range lit.Elts {
+		if f.sameCode(elt, expr) {
+			spans = append(spans, f.listItemSpan(nodes(lit.Elts), i))
+		}
+	}
+	return f.removeSpans(spans)
+}
+
+// RemoveStatementsUsing removes the top-level statements of a function
+// that refer to any of the given identifiers. A comment heading a
+// paragraph of statements is removed with it when the whole paragraph
+// goes. funcName is either a function name or Type.Method.
+func (f *File) RemoveStatementsUsing(funcName string, idents ...string) (bool, error) {
+	fn := f.findFunc(funcName)
+	if fn == nil || fn.Body == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	body := fn.Body.List
+	removed := make([]bool, len(body))
+	for i, stmt := range body {
+		removed[i] = refersTo(stmt, idents)
+	}
+
+	// The statements removed at the end of the body
+	trailing := len(body)
+	for trailing > 0 && removed[trailing-1] {
+		trailing--
+	}
+
+	var spans []span
+	for i, stmt := range body {
+		if !removed[i] {
+			continue
+		}
+		start, end := f.offset(stmt.Pos()), f.offset(stmt.End())
+		if lead := f.leadingPos(stmt); lead != start && f.ownLine(body, i, lead) && f.paragraphRemoved(body, removed, i) {
+			start = lead
+		}
+		start, end = f.lineSpan(start, end)
+		// Nothing follows the last paragraph: the blank line before it goes
+		if i == trailing && i > 0 && start >= 2 && string(f.src[start-2:start]) == "\n\n" {
+			start--
+		}
+		spans = append(spans, span{start, end})
+	}
+	return f.removeSpans(spans)
+}
+
+// paragraphRemoved reports whether the statements from i up to the next
+// blank line are all removed
+func (f *File) paragraphRemoved(body []ast.Stmt, removed []bool, i int) bool {
+	for j := i; j < len(body); j++ {
+		if !removed[j] {
+			return false
+		}
+		if j+1 < len(body) && f.line(body[j+1].Pos()) > f.line(body[j].End())+1 {
+			break
+		}
+	}
+	return true
+}
+
+// ownLine reports whether the comment at offset lead, heading statement
+// i, starts on a line of its own rather than trailing the statement before
+func (f *File) ownLine(body []ast.Stmt, i, lead int) bool {
+	return i == 0 || strings.Contains(string(f.src[f.offset(body[i-1].End()):lead]), "\n")
+}
+
+// RemoveField removes the field called name from the struct typeName
+func (f *File) RemoveField(typeName, name string) (bool, error) {
+	st := f.findStruct(typeName)
+	if st == nil {
+		return false, fmt.Errorf("%s: struct %s not found", f.path, typeName)
+	}
+
+	for _, field := range st.Fields.List {
+		for j, ident := range field.Names {
+			if ident.Name != name {
+				continue
+			}
+			if len(field.Names) > 1 {
+				// "A, B string": only the name goes
+				return f.removeSpans([]span{f.listItemSpan(nodes(field.Names), j)})
+			}
+			start, end := f.lineSpan(f.offset(field.Pos()), f.offset(field.End()))
+			return f.removeSpans([]span{{start, end}})
+		}
+	}
+	return false, nil
+}
+
+// RemoveLiteralField removes "key: value" from the composite literal of
+// type typeName inside a function (the inverse of AddLiteralField)
+func (f *File) RemoveLiteralField(funcName, typeName, key string) (bool, error) {
+	fn := f.findFunc(funcName)
+	if fn == nil || fn.Body == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	var spans []span
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok || lit.Type == nil || !f.sameCode(lit.Type, typeName) {
+			return true
+		}
+		for i, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok && f.sameCode(kv.Key, key) {
+				spans = append(spans, f.listItemSpan(nodes(lit.Elts), i))
+			}
+		}
+		return true
+	})
+	return f.removeSpans(spans)
+}
+
+// RemoveDecl removes a function or Type.Method with its doc comment and
+// the blank line before it (the inverse of AddDecl)
+func (f *File) RemoveDecl(funcName string) (bool, error) {
+	fn := f.findFunc(funcName)
+	if fn == nil {
+		return false, nil
+	}
+
+	start := f.offset(fn.Pos())
+	if fn.Doc != nil {
+		start = f.offset(fn.Doc.Pos())
+	}
+	start, end := f.lineSpan(start, f.offset(fn.End()))
+	if start >= 2 && string(f.src[start-2:start]) == "\n\n" {
+		start--
+	}
+	return f.removeSpans([]span{{start, end}})
+}
+
+// RemoveParam removes the parameter called name from a function signature
+func (f *File) RemoveParam(funcName, name string) (bool, error) {
+	fn := f.findFunc(funcName)
+	if fn == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	params := fn.Type.Params.List
+	for i, field := range params {
+		for j, ident := range field.Names {
+			if ident.Name != name {
+				continue
+			}
+			if len(field.Names) == 1 {
+				return f.removeSpans([]span{f.listItemSpan(nodes(params), i)})
+			}
+			// "a, b Type": only the name goes
+			return f.removeSpans([]span{f.listItemSpan(nodes(field.Names), j)})
+		}
+	}
+	return false, nil
+}
+
+// RemoveCallArg removes arg from every call to callee inside a function
+func (f *File) RemoveCallArg(funcName, callee, arg string) (bool, error) {
+	fn := f.findFunc(funcName)
+	if fn == nil || fn.Body == nil {
+		return false, fmt.Errorf("%s: function %s not found", f.path, funcName)
+	}
+
+	var spans []span
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !f.calleeMatches(call.Fun, callee) {
+			return true
+		}
+		for i, a := range call.Args {
+			if f.sameCode(a, arg) {
+				spans = append(spans, f.listItemSpan(nodes(call.Args), i))
+			}
+		}
+		return true
+	})
+	return f.removeSpans(spans)
+}
+
+// majorVersion matches the /vN suffix of a module path
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// RemoveUnusedImport removes an import the file no longer refers to.
+// Returns false when it is not imported or still used.
+func (f *File) RemoveUnusedImport(importPath string) (bool, error) {
+	for _, d := range f.file.Decls {
+		decl, ok := d.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		for i, s := range decl.Specs {
+			spec := s.(*ast.ImportSpec)
+			if value, err := strconv.Unquote(spec.Path.Value); err != nil || value != importPath {
+				continue
+			}
+
+			name := importName(spec)
+			if name == "_" || name == "." || f.usesPackage(name) {
+				return false, nil
+			}
+			if len(decl.Specs) == 1 {
+				start, end := f.lineSpan(f.offset(decl.Pos()), f.offset(decl.End()))
+				return f.removeSpans([]span{{start, end}})
+			}
+			return f.removeSpans([]span{f.listItemSpan(nodes(decl.Specs), i)})
+		}
+	}
+	return false, nil
+}
+
+// importName returns the name an import is referred to by
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	p, _ := strconv.Unquote(spec.Path.Value)
+	name := path.Base(p)
+	if majorVersion.MatchString(name) {
+		name = path.Base(path.Dir(p))
+	}
+	return name
+}
+
+// usesPackage reports whether the file refers to pkg.Something
+func (f *File) usesPackage(pkg string) bool {
+	found := false
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == pkg {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// Uses reports whether any function of the file refers to the identifier
+func (f *File) Uses(ident string) bool {
+	for _, d := range f.file.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && fn.Body != nil && refersTo(fn.Body, []string{ident}) {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeNames returns the names of the types declared in the file, sorted
+func (f *File) TypeNames() []string {
+	var names []string
+	for _, d := range f.file.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range gd.Specs {
+			names = append(names, s.(*ast.TypeSpec).Name.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// refersTo reports whether a node contains one of the identifiers
+func refersTo(node ast.Node, idents []string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			for _, name := range idents {
+				if id.Name == name {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// listItemSpan returns what to delete to remove item i of a comma
+// separated list: its whole line when it sits on a line of its own,
+// otherwise the item and one separator
+func (f *File) listItemSpan(items []ast.Node, i int) span {
+	start, end := f.offset(items[i].Pos()), f.offset(items[i].End())
+	if s, e := f.lineSpan(start, end); s != start || e != end {
+		return span{s, e}
+	}
+	switch {
+	case i > 0:
+		return span{f.offset(items[i-1].End()), end}
+	case len(items) > 1:
+		return span{start, f.offset(items[i+1].Pos())}
+	}
+	return span{start, end}
+}
+
+// lineSpan widens [start, end) to whole lines when nothing but blanks
+// precede it on its first line and nothing but a comma, blanks or a
+// comment follow it on its last line
+func (f *File) lineSpan(start, end int) (int, int) {
+	lineStart := start
+	for lineStart > 0 && (f.src[lineStart-1] == ' ' || f.src[lineStart-1] == '\t') {
+		lineStart--
+	}
+	if lineStart > 0 && f.src[lineStart-1] != '\n' {
+		return start, end
+	}
+
+	lineEnd := end
+	for lineEnd < len(f.src) && f.src[lineEnd] != '\n' {
+		lineEnd++
+	}
+	rest := strings.TrimSpace(string(f.src[end:lineEnd]))
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	if rest != "" && !strings.HasPrefix(rest, "//") {
+		return start, end
+	}
+	if lineEnd < len(f.src) {
+		lineEnd++
+	}
+	return lineStart, lineEnd
+}
+
+// removeSpans deletes the spans, last first so earlier offsets stay valid
+func (f *File) removeSpans(spans []span) (bool, error) {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start > spans[j].start
+	})
+	for _, s := range spans {
+		if err := f.replace(s.start, s.end, ""); err != nil {
+			return false, err
+		}
+	}
+	return len(spans) > 0, nil
+}
+
+// line returns the line of a position
+func (f *File) line(pos token.Pos) int {
+	return f.fset.Position(pos).Line
+}
+
+// nodes converts a list of AST nodes of one type
+func nodes[T ast.Node](items []T) []ast.Node {
+	converted := make([]ast.Node, len(items))
+	for i, item := range items {
+		converted[i] = item
+	}
+	return converted
+}
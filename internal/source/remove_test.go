@@ -0,0 +1,233 @@
+package source
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRemoveEdits(t *testing.T) {
+	runEditTests(t, []editTest{
+		{
+			name: "slice element",
+			src:  "package a\n\nvar AllModels = []interface{}{\n\t&models.User{},\n\t&models.Product{},\n}\n",
+			edit: func(f *File) (bool, error) { return f.RemoveFromSlice("AllModels", "&models.User{}") },
+			want: "package a\n\nvar AllModels = []interface{}{\n\t&models.Product{},\n}\n",
+		},
+		{
+			name: "single line slice element",
+			src:  "package a\n\nvar names = []string{\"a\", \"b\", \"c\"}\n",
+			edit: func(f *File) (bool, error) { return f.RemoveFromSlice("names", `"a"`) },
+			want: "package a\n\nvar names = []string{\"b\", \"c\"}\n",
+		},
+		{
+			name: "field",
+			src:  "package a\n\ntype Config struct {\n\tPort      string\n\tJWTSecret string // signs tokens\n}\n",
+			edit: func(f *File) (bool, error) { return f.RemoveField("Config", "JWTSecret") },
+			want: "package a\n\ntype Config struct {\n\tPort string\n}\n",
+		},
+		{
+			name: "literal field",
+			src:  "package a\n\nfunc Load() *Config {\n\treturn &Config{\n\t\tPort:   \"8080\",\n\t\tDBName: getEnv(\"DB_NAME\", \"app\"),\n\t}\n}\n",
+			edit: func(f *File) (bool, error) { return f.RemoveLiteralField("Load", "Config", "DBName") },
+			want: "package a\n\nfunc Load() *Config {\n\treturn &Config{\n\t\tPort: \"8080\",\n\t}\n}\n",
+		},
+		{
+			name: "declaration with its doc comment",
+			src:  "package a\n\nfunc a() {}\n\n// b does nothing\nfunc b() {}\n",
+			edit: func(f *File) (bool, error) { return f.RemoveDecl("b") },
+			want: "package a\n\nfunc a() {}\n",
+		},
+		{
+			name: "param",
+			src:  "package a\n\nfunc registerRoutes(api *Router, users *Handler) {\n}\n",
+			edit: func(f *File) (bool, error) { return f.RemoveParam("registerRoutes", "users") },
+			want: "package a\n\nfunc registerRoutes(api *Router) {\n}\n",
+		},
+		{
+			name: "call argument",
+			src:  "package a\n\nfunc New() {\n\tregisterRoutes(api, users, products)\n}\n",
+			edit: func(f *File) (bool, error) { return f.RemoveCallArg("New", "registerRoutes", "users") },
+			want: "package a\n\nfunc New() {\n\tregisterRoutes(api, products)\n}\n",
+		},
+		{
+			name: "unused import",
+			src:  "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar out = os.Stdout\n",
+			edit: func(f *File) (bool, error) { return f.RemoveUnusedImport("fmt") },
+			want: "package a\n\nimport (\n\t\"os\"\n)\n\nvar out = os.Stdout\n",
+		},
+		{
+			name: "last import",
+			src:  "package a\n\nimport \"github.com/go-chi/chi/v5\"\n\nvar x = 1\n",
+			edit: func(f *File) (bool, error) { return f.RemoveUnusedImport("github.com/go-chi/chi/v5") },
+			want: "package a\n\nvar x = 1\n",
+		},
+	})
+}
+
+func TestRemoveStatementsUsing(t *testing.T) {
+	runEditTests(t, []editTest{
+		{
+			name: "paragraph with its comment",
+			src: `package a
+
+func New() *Server {
+	users := newUsers()
+
+	// Products
+	products := newProducts()
+	products.Register()
+
+	registerRoutes(users)
+	return nil
+}
+`,
+			edit: func(f *File) (bool, error) { return f.RemoveStatementsUsing("New", "products") },
+			want: `package a
+
+func New() *Server {
+	users := newUsers()
+
+	registerRoutes(users)
+	return nil
+}
+`,
+		},
+		{
+			name: "comment kept with the rest of its paragraph",
+			src: `package a
+
+func New() *Server {
+	// Services
+	users := newUsers()
+	products := newProducts()
+	return nil
+}
+`,
+			edit: func(f *File) (bool, error) { return f.RemoveStatementsUsing("New", "products") },
+			want: `package a
+
+func New() *Server {
+	// Services
+	users := newUsers()
+	return nil
+}
+`,
+		},
+		{
+			name: "last paragraph",
+			src:  "package a\n\nfunc setup() {\n\tinit1()\n\n\tinit2()\n}\n",
+			edit: func(f *File) (bool, error) { return f.RemoveStatementsUsing("setup", "init2") },
+			want: "package a\n\nfunc setup() {\n\tinit1()\n}\n",
+		},
+	})
+}
+
+func TestRemoveUnusedImportStillUsed(t *testing.T) {
+	src := "package a\n\nimport (\n\t\"fmt\"\n\tm \"example.com/app/models\"\n)\n\nvar s = fmt.Sprint(m.User{})\n"
+	f, err := Parse("a.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, importPath := range []string{"fmt", "example.com/app/models", "os"} {
+		removed, err := f.RemoveUnusedImport(importPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if removed {
+			t.Errorf("RemoveUnusedImport(%q) removed an import in use", importPath)
+		}
+	}
+}
+
+// TestAddThenRemove checks that removing what was added gives back the
+// original file, which "loom destroy" relies on
+func TestAddThenRemove(t *testing.T) {
+	src := `package a
+
+import (
+	"os"
+)
+
+var AllModels = []interface{}{
+	&models.User{},
+}
+
+type Config struct {
+	Port string
+}
+
+func New(api *Router) {
+	users := newUsers()
+
+	registerRoutes(api, users)
+}
+
+var out = os.Stdout
+`
+	f, err := Parse("a.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edits := []struct {
+		add, remove func() (bool, error)
+	}{
+		{
+			func() (bool, error) { return f.AddImport("example.com/app/products") },
+			func() (bool, error) { return f.RemoveUnusedImport("example.com/app/products") },
+		},
+		{
+			func() (bool, error) { return f.AppendToSlice("AllModels", "&models.Product{}") },
+			func() (bool, error) { return f.RemoveFromSlice("AllModels", "&models.Product{}") },
+		},
+		{
+			func() (bool, error) { return f.AddField("Config", "Secret string") },
+			func() (bool, error) { return f.RemoveField("Config", "Secret") },
+		},
+		{
+			func() (bool, error) {
+				return f.InsertBeforeCall("New", "registerRoutes", "products := newProducts()")
+			},
+			func() (bool, error) { return f.RemoveStatementsUsing("New", "products") },
+		},
+		{
+			func() (bool, error) { return f.AddCallArg("New", "registerRoutes", "products") },
+			func() (bool, error) { return f.RemoveCallArg("New", "registerRoutes", "products") },
+		},
+		{
+			func() (bool, error) { return f.AddDecl("func newProducts() {}") },
+			func() (bool, error) { return f.RemoveDecl("newProducts") },
+		},
+	}
+	for i, edit := range edits {
+		if changed, err := edit.add(); err != nil || !changed {
+			t.Fatalf("add %d = %v, %v", i, changed, err)
+		}
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		if changed, err := edits[i].remove(); err != nil || !changed {
+			t.Fatalf("remove %d = %v, %v", i, changed, err)
+		}
+	}
+
+	got, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != src {
+		t.Errorf("got:\n%s\nwant:\n%s", got, src)
+	}
+}
+
+func TestTypeNamesAndUses(t *testing.T) {
+	f, err := Parse("a.go", []byte("package a\n\ntype (\n\tUser struct{}\n\tTag struct{}\n)\n\ntype Address struct{}\n\nfunc run() { seed() }\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.TypeNames(); !slices.Equal(got, []string{"Address", "Tag", "User"}) {
+		t.Errorf("TypeNames = %v", got)
+	}
+	if !f.Uses("seed") || f.Uses("User") {
+		t.Errorf("Uses(seed) = %v, Uses(User) = %v", f.Uses("seed"), f.Uses("User"))
+	}
+}
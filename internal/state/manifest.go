@@ -0,0 +1,234 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestFile is the file (inside Dir) describing the project
+const manifestFile = "loom.yaml"
+
+// Manifest describes how a project was built: the Loom version, its
+// stack, the addons installed, the modules generated and the template
+// packs it is pinned to. Detectors read it before falling back to
+// heuristics; an empty field (or a nil list) means "not recorded".
+type Manifest struct {
+	Version      string            `yaml:"version"`
+	Architecture string            `yaml:"architecture"`
+	Router       string            `yaml:"router,omitempty"`
+	ORM          string            `yaml:"orm,omitempty"`
+	Databases    []string          `yaml:"databases"`
+	Auth         string            `yaml:"auth,omitempty"`
+	Addons       []InstalledAddon  `yaml:"addons,omitempty"`
+	Modules      []string          `yaml:"modules,omitempty"`
+	Packs        map[string]string `yaml:"packs,omitempty"` // name -> pinned version
+}
+
+// InstalledAddon records an addon and the Loom version that installed it
+type InstalledAddon struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version,omitempty"`
+}
+
+// ManifestPath returns the manifest location of the project at root
+func ManifestPath(root string) string {
+	return filepath.Join(root, Dir, manifestFile)
+}
+
+// LoadManifest reads the manifest of the project at root.
+// A project without a manifest yields nil.
+func LoadManifest(root string) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(root))
+	if os.IsNotExist(err) || isNotDir(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading project manifest: %w", err)
+	}
+	return ParseManifest(data)
+}
+
+// isNotDir reports whether err comes from a legacy .loom file standing
+// where the directory should be
+func isNotDir(err error) bool {
+	return errors.Is(err, syscall.ENOTDIR)
+}
+
+// ParseManifest decodes the content of a manifest file
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filepath.Join(Dir, manifestFile), err)
+	}
+	return &m, nil
+}
+
+// Marshal encodes the manifest file
+func (m *Manifest) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# Loom project manifest, maintained by the loom CLI\n")
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(m); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SaveTo writes the manifest of the project at root to w. Like
+// State.SaveTo, a legacy .loom file is migrated first so the directory
+// can be created when w is committed.
+func (m *Manifest) SaveTo(root string, w Writer) error {
+	if info, err := os.Stat(filepath.Join(root, Dir)); err == nil && !info.IsDir() {
+		if err := EnsureDir(root); err != nil {
+			return err
+		}
+	}
+
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	return w.WriteFile(ManifestPath(root), data)
+}
+
+// Save writes the manifest of the project at root to disk
+func (m *Manifest) Save(root string) error {
+	if err := EnsureDir(root); err != nil {
+		return err
+	}
+	return m.SaveTo(root, disk{})
+}
+
+// HasAddon reports whether the manifest records an installed addon
+func (m *Manifest) HasAddon(name string) bool {
+	for _, addon := range m.Addons {
+		if addon.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddAddon records an installed addon, replacing an older record
+func (m *Manifest) AddAddon(name, version string) {
+	m.RemoveAddon(name)
+	m.Addons = append(m.Addons, InstalledAddon{Name: name, Version: version})
+	sort.Slice(m.Addons, func(i, j int) bool { return m.Addons[i].Name < m.Addons[j].Name })
+}
+
+// RemoveAddon forgets an uninstalled addon
+func (m *Manifest) RemoveAddon(name string) {
+	addons := m.Addons[:0]
+	for _, addon := range m.Addons {
+		if addon.Name != name {
+			addons = append(addons, addon)
+		}
+	}
+	m.Addons = addons
+	if len(m.Addons) == 0 {
+		m.Addons = nil
+	}
+}
+
+// AddModule records a generated module
+func (m *Manifest) AddModule(name string) {
+	for _, module := range m.Modules {
+		if module == name {
+			return
+		}
+	}
+	m.Modules = append(m.Modules, name)
+	sort.Strings(m.Modules)
+}
+
+// RemoveModule forgets a destroyed module
+func (m *Manifest) RemoveModule(name string) {
+	modules := m.Modules[:0]
+	for _, module := range m.Modules {
+		if module != name {
+			modules = append(modules, module)
+		}
+	}
+	m.Modules = modules
+	if len(m.Modules) == 0 {
+		m.Modules = nil
+	}
+}
+
+// PinPack records the version of a project template pack ("" forgets it)
+func (m *Manifest) PinPack(name, version string) {
+	if version == "" {
+		delete(m.Packs, name)
+		return
+	}
+	if m.Packs == nil {
+		m.Packs = make(map[string]string)
+	}
+	m.Packs[name] = version
+}
+
+// GeneratedModules returns the modules whose files are tracked in the
+// generation state, sorted (used to build a manifest for older projects)
+func (s *State) GeneratedModules() []string {
+	seen := make(map[string]bool)
+	var modules []string
+	for _, entry := range s.Files {
+		name, ok := strings.CutPrefix(entry.Generator, "module:")
+		if ok && !seen[name] {
+			seen[name] = true
+			modules = append(modules, name)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// LegacyConfig reads the key=value configuration written by older
+// versions of Loom, from a plain .loom file or .loom/config. A project
+// without one yields nil.
+func LegacyConfig(root string) map[string]string {
+	path := filepath.Join(root, Dir)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, legacyConfigFile)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	config := make(map[string]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			config[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return config
+}
+
+// RemoveLegacyConfig deletes the key=value configuration once it has been
+// migrated into the manifest
+func RemoveLegacyConfig(root string) error {
+	path := filepath.Join(root, Dir, legacyConfigFile)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
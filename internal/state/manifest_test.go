@@ -0,0 +1,62 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	root := t.TempDir()
+
+	m := &Manifest{Version: "0.5.0", Architecture: "modular", Router: "chi", Databases: []string{"postgres"}}
+	m.AddAddon("orm", "0.5.0")
+	m.AddAddon("auth", "0.4.0")
+	m.AddAddon("orm", "0.5.1")
+	m.AddModule("users")
+	m.AddModule("products")
+	m.AddModule("users")
+	m.PinPack("saas", "v1.2.0")
+	if err := m.Save(root); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadManifest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Manifest{
+		Version:      "0.5.0",
+		Architecture: "modular",
+		Router:       "chi",
+		Databases:    []string{"postgres"},
+		Addons:       []InstalledAddon{{Name: "auth", Version: "0.4.0"}, {Name: "orm", Version: "0.5.1"}},
+		Modules:      []string{"products", "users"},
+		Packs:        map[string]string{"saas": "v1.2.0"},
+	}
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("manifest = %+v, want %+v", loaded, want)
+	}
+
+	loaded.RemoveAddon("orm")
+	loaded.RemoveAddon("auth")
+	loaded.RemoveModule("products")
+	loaded.PinPack("saas", "")
+	if loaded.HasAddon("orm") || loaded.Addons != nil || !reflect.DeepEqual(loaded.Modules, []string{"users"}) || len(loaded.Packs) != 0 {
+		t.Errorf("manifest after removals = %+v", loaded)
+	}
+}
+
+// TestLoadManifestMissing checks that projects without a manifest, or
+// with a legacy .loom file, have none
+func TestLoadManifestMissing(t *testing.T) {
+	root := t.TempDir()
+	if m, err := LoadManifest(root); m != nil || err != nil {
+		t.Errorf("LoadManifest of a new project = %v, %v", m, err)
+	}
+
+	writeFile(t, filepath.Join(root, Dir), "architecture=layered\n")
+	if m, err := LoadManifest(root); m != nil || err != nil {
+		t.Errorf("LoadManifest of a legacy project = %v, %v", m, err)
+	}
+}
@@ -0,0 +1,304 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Dir is the directory where Loom keeps per-project state
+const Dir = ".loom"
+
+// generatedFile is the file (inside Dir) that tracks generated files
+const generatedFile = "generated.json"
+
+// baseDir is the directory (inside Dir) holding the last generated
+// content of every tracked file, the base of three-way merges
+const baseDir = "base"
+
+// legacyConfigFile is where the old key=value .loom file is moved to
+const legacyConfigFile = "config"
+
+// GeneratedFile records a file written by a Loom generator
+type GeneratedFile struct {
+	Hash      string    `json:"hash"`
+	Generator string    `json:"generator"`
+	Template  string    `json:"template,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// State tracks the files generated in a project so that later runs can
+// tell untouched generated files apart from files edited by the user
+type State struct {
+	root  string
+	Files map[string]GeneratedFile `json:"files"`
+
+	loaded    map[string]string // hashes as loaded, keyed like Files
+	contents  map[string][]byte // content recorded since, saved to baseDir
+	forgotten map[string]bool   // files forgotten since, their base is removed
+}
+
+// Load reads the generation state of the project at root.
+// A missing state file yields an empty state.
+func Load(root string) (*State, error) {
+	s := &State{
+		root:      root,
+		Files:     make(map[string]GeneratedFile),
+		loaded:    make(map[string]string),
+		contents:  make(map[string][]byte),
+		forgotten: make(map[string]bool),
+	}
+
+	// A legacy .loom file holds no state; it is migrated when saving
+	data, err := os.ReadFile(filepath.Join(root, Dir, generatedFile))
+	if os.IsNotExist(err) || isNotDir(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading generation state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filepath.Join(Dir, generatedFile), err)
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]GeneratedFile)
+	}
+	for path, entry := range s.Files {
+		s.loaded[path] = entry.Hash
+	}
+
+	return s, nil
+}
+
+// Writer receives the state file when it is saved (a change set)
+type Writer interface {
+	WriteFile(path string, content []byte) error
+	Remove(path string) error
+}
+
+// Save writes the generation state to disk
+func (s *State) Save() error {
+	if err := EnsureDir(s.root); err != nil {
+		return err
+	}
+	return s.SaveTo(disk{})
+}
+
+// disk writes state files directly, creating their directories
+type disk struct{}
+
+func (disk) WriteFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+func (disk) Remove(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SaveTo writes the generation state to w. The .loom directory is only
+// created when w is committed, but a legacy .loom file is migrated now so
+// the directory can be created then.
+func (s *State) SaveTo(w Writer) error {
+	if info, err := os.Stat(filepath.Join(s.root, Dir)); err == nil && !info.IsDir() {
+		if err := EnsureDir(s.root); err != nil {
+			return err
+		}
+	}
+
+	data, err := s.marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteFile(filepath.Join(s.root, Dir, generatedFile), data); err != nil {
+		return err
+	}
+
+	// The content recorded by this run is the base of the next merges
+	for path, content := range s.contents {
+		if err := w.WriteFile(s.basePath(path), content); err != nil {
+			return err
+		}
+	}
+	for path := range s.forgotten {
+		if err := w.Remove(s.basePath(path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshal encodes the state file
+func (s *State) marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Record stores the hash of a generated file and the template it was
+// rendered from ("" when unknown). The content itself is saved too, as
+// the base of a three-way merge when the file is regenerated.
+func (s *State) Record(path string, content []byte, generator, template string) {
+	s.contents[key(path)] = content
+	delete(s.forgotten, key(path))
+	s.Files[key(path)] = GeneratedFile{
+		Hash:      Hash(content),
+		Generator: generator,
+		Template:  template,
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// Lookup returns the record of a generated file
+func (s *State) Lookup(path string) (GeneratedFile, bool) {
+	file, ok := s.Files[key(path)]
+	return file, ok
+}
+
+// Restore puts back a record returned by Lookup (or forgets the file when
+// it had none)
+func (s *State) Restore(path string, file GeneratedFile, ok bool) {
+	if !ok {
+		s.Forget(path)
+		return
+	}
+	s.Files[key(path)] = file
+	delete(s.contents, key(path))
+}
+
+// Forget removes a file from the state
+func (s *State) Forget(path string) {
+	delete(s.Files, key(path))
+	delete(s.contents, key(path))
+	s.forgotten[key(path)] = true
+}
+
+// Base returns the content Loom generated for a file the last time it
+// was saved, when it is known. Unlike the other methods, path is relative
+// to the working directory (like the paths of a change set), not to the
+// project root.
+func (s *State) Base(path string) ([]byte, bool) {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return nil, false
+	}
+	hash, ok := s.loaded[key(rel)]
+	if !ok {
+		return nil, false
+	}
+
+	// Bases saved by older versions of Loom (or out of sync) are ignored
+	content, err := os.ReadFile(s.basePath(key(rel)))
+	if err != nil || Hash(content) != hash {
+		return nil, false
+	}
+	return content, true
+}
+
+// basePath returns where the base of a tracked file is saved
+func (s *State) basePath(path string) string {
+	return filepath.Join(s.root, Dir, baseDir, filepath.FromSlash(path))
+}
+
+// Tracked reports whether the file was written by a generator
+func (s *State) Tracked(path string) bool {
+	_, ok := s.Files[key(path)]
+	return ok
+}
+
+// IsPristine reports whether the file on disk is exactly what Loom generated
+func (s *State) IsPristine(path string) (bool, error) {
+	entry, ok := s.Files[key(path)]
+	if !ok {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.root, path))
+	if err != nil {
+		return false, err
+	}
+
+	return Hash(content) == entry.Hash, nil
+}
+
+// Paths returns the tracked paths generated by the given generator, sorted
+func (s *State) Paths(generator string) []string {
+	var paths []string
+	for path, entry := range s.Files {
+		if entry.Generator == generator {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Templates returns the template of every tracked file rendered from one,
+// keyed by path
+func (s *State) Templates() map[string]string {
+	templates := make(map[string]string)
+	for path, entry := range s.Files {
+		if entry.Template != "" {
+			templates[path] = entry.Template
+		}
+	}
+	return templates
+}
+
+// Hash returns the content hash used to detect modifications
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// EnsureDir creates the .loom directory. Projects upgraded with older
+// versions of Loom have a plain .loom file (key=value); it is moved to
+// .loom/config so its content is preserved.
+func EnsureDir(root string) error {
+	dir := filepath.Join(root, Dir)
+
+	info, err := os.Stat(dir)
+	if err == nil && info.IsDir() {
+		return nil
+	}
+
+	var legacy []byte
+	if err == nil {
+		legacy, err = os.ReadFile(dir)
+		if err != nil {
+			return fmt.Errorf("error reading legacy .loom file: %w", err)
+		}
+		if err := os.Remove(dir); err != nil {
+			return fmt.Errorf("error migrating legacy .loom file: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %w", Dir, err)
+	}
+
+	if legacy != nil {
+		return os.WriteFile(filepath.Join(dir, legacyConfigFile), legacy, 0644)
+	}
+
+	return nil
+}
+
+// key normalizes a path for use as a state key
+func key(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
@@ -0,0 +1,187 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	root := t.TempDir()
+
+	s, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record("internal/app/handlers/product_handler.go", []byte("package handlers\n"), "module:products", "layered/handler.go.tmpl")
+	s.Record("internal/app/models/product.go", []byte("package models\n"), "module:products", "")
+	s.Record("internal/app/dtos/user_dto.go", []byte("package dtos\n"), "module:users", "")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"internal/app/handlers/product_handler.go", "internal/app/models/product.go"}
+	if got := loaded.Paths("module:products"); !slices.Equal(got, want) {
+		t.Errorf("Paths = %v, want %v", got, want)
+	}
+	if got := loaded.GeneratedModules(); !slices.Equal(got, []string{"products", "users"}) {
+		t.Errorf("GeneratedModules = %v", got)
+	}
+	file, ok := loaded.Lookup("./internal/app/models/../models/product.go")
+	if !ok || file.Hash != Hash([]byte("package models\n")) {
+		t.Errorf("Lookup = %+v, %v", file, ok)
+	}
+	if got := loaded.Templates(); len(got) != 1 || got["internal/app/handlers/product_handler.go"] != "layered/handler.go.tmpl" {
+		t.Errorf("Templates = %v", got)
+	}
+}
+
+func TestStateMissingFile(t *testing.T) {
+	s, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Files) != 0 || s.Tracked("main.go") {
+		t.Errorf("state of a new project = %v", s.Files)
+	}
+}
+
+func TestIsPristine(t *testing.T) {
+	root := t.TempDir()
+	path := "internal/app/models/product.go"
+	writeFile(t, filepath.Join(root, path), "package models\n")
+
+	s, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pristine, _ := s.IsPristine(path); pristine {
+		t.Error("untracked file reported pristine")
+	}
+
+	s.Record(path, []byte("package models\n"), "module:products", "")
+	if pristine, err := s.IsPristine(path); err != nil || !pristine {
+		t.Errorf("IsPristine = %v, %v; want true", pristine, err)
+	}
+
+	writeFile(t, filepath.Join(root, path), "package models\n\n// edited\n")
+	if pristine, err := s.IsPristine(path); err != nil || pristine {
+		t.Errorf("IsPristine of an edited file = %v, %v; want false", pristine, err)
+	}
+}
+
+// TestBase checks that the content recorded by a run is the merge base of
+// the next one, and only while it matches the recorded hash
+func TestBase(t *testing.T) {
+	root := t.TempDir()
+	path := "internal/app/models/product.go"
+
+	s, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record(path, []byte("package models\n"), "module:products", "")
+	if _, ok := s.Base(filepath.Join(root, path)); ok {
+		t.Error("base known before the state is saved")
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, ok := s.Base(filepath.Join(root, path))
+	if !ok || string(base) != "package models\n" {
+		t.Errorf("Base = %q, %v", base, ok)
+	}
+
+	// A base out of sync with the state is ignored
+	writeFile(t, s.basePath(path), "package other\n")
+	if _, ok := s.Base(filepath.Join(root, path)); ok {
+		t.Error("stale base returned")
+	}
+
+	s.Forget(path)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.basePath(path)); !os.IsNotExist(err) {
+		t.Errorf("base of a forgotten file kept: %v", err)
+	}
+	if s.Tracked(path) {
+		t.Error("forgotten file still tracked")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	s, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record("a.go", []byte("package a\n"), "make:model", "")
+	before, ok := s.Lookup("a.go")
+
+	s.Record("a.go", []byte("package b\n"), "make:model", "")
+	s.Restore("a.go", before, ok)
+	if got, _ := s.Lookup("a.go"); got.Hash != before.Hash {
+		t.Errorf("Restore kept hash %s, want %s", got.Hash, before.Hash)
+	}
+
+	_, ok = s.Lookup("b.go")
+	s.Record("b.go", []byte("package b\n"), "make:model", "")
+	s.Restore("b.go", GeneratedFile{}, ok)
+	if s.Tracked("b.go") {
+		t.Error("Restore of an untracked file kept it tracked")
+	}
+}
+
+// TestLegacyConfigFile checks that a plain .loom file left by older
+// versions of Loom is moved to .loom/config when the state is saved
+func TestLegacyConfigFile(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, Dir), "# Loom\narchitecture=modular\nrouter = chi\n")
+
+	if config := LegacyConfig(root); config["architecture"] != "modular" || config["router"] != "chi" {
+		t.Errorf("LegacyConfig = %v", config)
+	}
+
+	s, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record("main.go", []byte("package main\n"), "new", "")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if config := LegacyConfig(root); config["architecture"] != "modular" {
+		t.Errorf("legacy config lost: %v", config)
+	}
+	if _, err := os.Stat(filepath.Join(root, Dir, generatedFile)); err != nil {
+		t.Error(err)
+	}
+
+	if err := RemoveLegacyConfig(root); err != nil {
+		t.Fatal(err)
+	}
+	if config := LegacyConfig(root); config != nil {
+		t.Errorf("legacy config kept: %v", config)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/geomark27/loom-go/internal/addon"
+	"github.com/geomark27/loom-go/internal/generator"
+	"github.com/geomark27/loom-go/internal/state"
 	"github.com/geomark27/loom-go/internal/version"
 )
 
@@ -93,14 +96,9 @@ func (u *Upgrader) applyUpgrades() error {
 		current = nextVersion
 	}
 
-	// Update .loom file
-	architecture := "layered"
-	if _, err := os.Stat("internal/modules"); err == nil {
-		architecture = "modular"
-	}
-
-	if err := version.CreateLoomFile(u.targetVersion, architecture); err != nil {
-		return fmt.Errorf("error updating .loom: %w", err)
+	// Update the project manifest
+	if err := u.writeManifest(u.targetVersion); err != nil {
+		return fmt.Errorf("error updating %s: %w", state.ManifestPath("."), err)
 	}
 
 	fmt.Printf("✅ Project successfully updated to v%s!\n", u.targetVersion.String())
@@ -174,17 +172,12 @@ func (u *Upgrader) upgradeTo030() error {
 func (u *Upgrader) upgradeTo040() error {
 	fmt.Println("   ✨ Preparing support for 'loom generate'...")
 
-	// Create .loom file if it doesn't exist
+	// Create the project manifest if it doesn't exist
 	if _, err := os.Stat(".loom"); os.IsNotExist(err) {
-		architecture := "layered"
-		if _, err := os.Stat("internal/modules"); err == nil {
-			architecture = "modular"
-		}
-
-		if err := version.CreateLoomFile(version.Version{Major: 0, Minor: 4, Patch: 0}, architecture); err != nil {
+		if err := u.writeManifest(version.Version{Major: 0, Minor: 4, Patch: 0}); err != nil {
 			return err
 		}
-		fmt.Println("   ✅ .loom file created")
+		fmt.Println("   ✅ .loom/loom.yaml created")
 	}
 
 	return nil
@@ -194,19 +187,78 @@ func (u *Upgrader) upgradeTo040() error {
 func (u *Upgrader) upgradeTo050() error {
 	fmt.Println("   ⬆️  Preparing support for 'loom upgrade'...")
 
-	// Ensure .loom file exists
+	// Ensure the project manifest exists
 	if _, err := os.Stat(".loom"); os.IsNotExist(err) {
-		architecture := "layered"
-		if _, err := os.Stat("internal/modules"); err == nil {
-			architecture = "modular"
+		if err := u.writeManifest(version.Version{Major: 0, Minor: 5, Patch: 0}); err != nil {
+			return err
 		}
+	}
 
-		if err := version.CreateLoomFile(version.Version{Major: 0, Minor: 5, Patch: 0}, architecture); err != nil {
+	return nil
+}
+
+// MigrateManifest writes the manifest of a project that has none (its
+// version is not changed). It reports whether it did.
+func (u *Upgrader) MigrateManifest() (bool, error) {
+	m, err := state.LoadManifest(".")
+	if err != nil || m != nil {
+		return false, err
+	}
+	if err := u.writeManifest(u.currentVersion); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeManifest records the project version in .loom/loom.yaml. Older
+// projects have no manifest yet: their key=value .loom file is migrated
+// into one, completed with what is detected in the project.
+func (u *Upgrader) writeManifest(v version.Version) error {
+	m, err := state.LoadManifest(".")
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		if m, err = detectManifest(); err != nil {
 			return err
 		}
 	}
+	m.Version = v.String()
 
-	return nil
+	if err := m.Save("."); err != nil {
+		return err
+	}
+	return state.RemoveLegacyConfig(".")
+}
+
+// detectManifest builds the manifest of a project from its legacy .loom
+// file, its generation state, its pack lockfile and its dependencies
+func detectManifest() (*state.Manifest, error) {
+	architecture := state.LegacyConfig(".")["architecture"]
+	if architecture == "" {
+		architecture = "layered"
+		if _, err := os.Stat("internal/modules"); err == nil {
+			architecture = "modular"
+		}
+	}
+
+	m := addon.NewAddonManager(".", architecture).DetectManifest()
+
+	st, err := state.Load(".")
+	if err != nil {
+		return nil, err
+	}
+	m.Modules = st.GeneratedModules()
+
+	lock, err := generator.LoadPackLock(generator.ProjectPacksDir())
+	if err != nil {
+		return nil, err
+	}
+	for name, pack := range lock.Packs {
+		m.PinPack(name, pack.Version)
+	}
+
+	return m, nil
 }
 
 // addLoomCommentToGoMod adds a comment with the Loom version
@@ -0,0 +1,182 @@
+package upgrader
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/geomark27/loom-go/internal/golden"
+	"github.com/geomark27/loom-go/internal/state"
+	"github.com/geomark27/loom-go/internal/version"
+)
+
+const testGoMod = `module example.com/shop
+
+go 1.23
+
+require (
+	github.com/gin-gonic/gin v1.9.1
+	gorm.io/driver/postgres v1.5.4
+	gorm.io/gorm v1.25.5
+)
+`
+
+func mustParse(t *testing.T, s string) version.Version {
+	t.Helper()
+	v, err := version.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestCanUpgrade(t *testing.T) {
+	tests := []struct {
+		current, target string
+		want            bool
+	}{
+		{"0.3.0", "0.5.0", true},
+		{"0.5.0", "0.5.1", true},
+		{"0.5.0", "0.5.0", false},
+		{"0.5.0", "0.4.0", false},
+		{"0.5.0", "1.0.0", false},
+	}
+	for _, tt := range tests {
+		ok, reason := NewUpgrader(mustParse(t, tt.current), mustParse(t, tt.target)).CanUpgrade()
+		if ok != tt.want {
+			t.Errorf("CanUpgrade(%s -> %s) = %v (%s), want %v", tt.current, tt.target, ok, reason, tt.want)
+		}
+	}
+}
+
+// TestUpgradeLegacyProject checks that upgrading a project configured by
+// a plain .loom file writes a manifest detected from the project
+func TestUpgradeLegacyProject(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), testGoMod)
+	writeFile(t, filepath.Join(root, state.Dir), "version=0.2.0\narchitecture=modular\n")
+	golden.Chdir(t, root)
+
+	if err := NewUpgrader(mustParse(t, "0.2.0"), mustParse(t, "0.5.0")).Upgrade(false); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := state.LoadManifest(".")
+	if err != nil || m == nil {
+		t.Fatalf("LoadManifest = %v, %v", m, err)
+	}
+	if m.Version != "0.5.0" || m.Architecture != "modular" || m.Router != "gin" || m.ORM != "gorm" {
+		t.Errorf("manifest = %+v", m)
+	}
+	if !slices.Equal(m.Databases, []string{"postgres"}) {
+		t.Errorf("databases = %v, want [postgres]", m.Databases)
+	}
+	if config := state.LegacyConfig("."); config != nil {
+		t.Errorf("legacy config kept: %v", config)
+	}
+
+	goMod, err := os.ReadFile("go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(goMod), "// Generated with Loom v0.5.0") {
+		t.Errorf("go.mod misses the Loom comment:\n%s", goMod)
+	}
+}
+
+// TestUpgradeKeepsManifest checks that upgrading only bumps the version of
+// an existing manifest
+func TestUpgradeKeepsManifest(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), testGoMod)
+	golden.Chdir(t, root)
+
+	recorded := &state.Manifest{Version: "0.5.0", Architecture: "layered", Router: "chi", Modules: []string{"products"}}
+	if err := recorded.Save("."); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewUpgrader(mustParse(t, "0.5.0"), mustParse(t, "0.5.1")).Upgrade(false); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := state.LoadManifest(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != "0.5.1" || m.Router != "chi" || !slices.Equal(m.Modules, []string{"products"}) {
+		t.Errorf("manifest = %+v", m)
+	}
+}
+
+func TestMigrateManifest(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), testGoMod)
+	golden.Chdir(t, root)
+
+	st, err := state.Load(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Record("internal/modules/products/model.go", []byte("package products\n"), "module:products", "")
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	u := NewUpgrader(mustParse(t, "0.5.0"), mustParse(t, "0.5.0"))
+	for i, want := range []bool{true, false} {
+		migrated, err := u.MigrateManifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if migrated != want {
+			t.Fatalf("MigrateManifest %d = %v, want %v", i+1, migrated, want)
+		}
+	}
+
+	m, err := state.LoadManifest(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != "0.5.0" || m.Architecture != "layered" || !slices.Equal(m.Modules, []string{"products"}) {
+		t.Errorf("manifest = %+v", m)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), testGoMod)
+	writeFile(t, filepath.Join(root, "internal", "app", "app.go"), "package app\n")
+	golden.Chdir(t, root)
+
+	bm := NewBackupManager()
+	backup, err := bm.CreateBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, "internal/app/app.go", "package app\n\n// edited\n")
+	if err := bm.RestoreBackup(backup); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile("internal/app/app.go"); string(content) != "package app\n" {
+		t.Errorf("app.go not restored: %q", content)
+	}
+
+	backups, err := bm.ListBackups()
+	if err != nil || len(backups) != 1 || backups[0] != filepath.Base(backup) {
+		t.Errorf("ListBackups = %v, %v", backups, err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
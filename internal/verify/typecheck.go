@@ -0,0 +1,264 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// errNotLocal is returned for imports that are neither part of the
+// project nor of the standard library. Without the module cache their
+// packages are unknown: go/types treats them as fake packages and does
+// not report the selectors used on them.
+var errNotLocal = errors.New("dependency not available offline")
+
+// checker type-checks the packages of a module without the go tool
+type checker struct {
+	root     string
+	module   string
+	fset     *token.FileSet
+	std      types.Importer
+	packages map[string]*types.Package
+	problems []Problem
+}
+
+// typeCheck parses and type-checks every package of the module at root.
+// The standard library is loaded from GOROOT sources.
+func typeCheck(root string) ([]Problem, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading go.mod: %w", err)
+	}
+	module := modfile.ModulePath(data)
+	if module == "" {
+		return nil, fmt.Errorf("module path not found in go.mod")
+	}
+
+	fset := token.NewFileSet()
+	c := &checker{
+		root:     root,
+		module:   module,
+		fset:     fset,
+		std:      importer.ForCompiler(fset, "source", nil),
+		packages: make(map[string]*types.Package),
+	}
+
+	files, err := goFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]bool)
+	for _, rel := range files {
+		dir := path.Dir(rel)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if _, err := c.Import(path.Join(module, dir)); err != nil && !errors.Is(err, errNotLocal) {
+			return nil, err
+		}
+	}
+
+	return c.problems, nil
+}
+
+// Import implements types.Importer: project packages are type-checked
+// from source, the standard library through the source importer
+func (c *checker) Import(importPath string) (*types.Package, error) {
+	if pkg, ok := c.packages[importPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", importPath)
+		}
+		return pkg, nil
+	}
+
+	if c.local(importPath) {
+		return c.checkPackage(importPath)
+	}
+
+	if isStd(importPath) {
+		if pkg, err := c.std.Import(importPath); err == nil {
+			return pkg, nil
+		}
+	}
+	return nil, errNotLocal
+}
+
+// checkPackage type-checks the project package at importPath
+func (c *checker) checkPackage(importPath string) (*types.Package, error) {
+	c.packages[importPath] = nil
+
+	rel := strings.TrimPrefix(strings.TrimPrefix(importPath, c.module), "/")
+	dir := filepath.Join(c.root, filepath.FromSlash(rel))
+
+	info, err := build.Default.ImportDir(dir, 0)
+	if err != nil {
+		var noGo *build.NoGoError
+		if errors.As(err, &noGo) || os.IsNotExist(err) {
+			return nil, fmt.Errorf("no Go files for %s", importPath)
+		}
+		return nil, fmt.Errorf("error reading package %s: %w", importPath, err)
+	}
+
+	var files []*ast.File
+	unknown := make(map[string]bool) // names of the dependencies not loaded
+	for _, name := range info.GoFiles {
+		file, err := parser.ParseFile(c.fset, filepath.Join(dir, name), nil, parser.AllErrors)
+		if err != nil {
+			// Syntax errors are reported by the gofmt step
+			continue
+		}
+		files = append(files, file)
+
+		for _, spec := range file.Imports {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			if spec.Name == nil && !c.local(importPath) && !isStd(importPath) {
+				unknown[packageName(importPath)] = true
+			}
+		}
+	}
+
+	var errs []types.Error
+	config := types.Config{
+		Importer: c,
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok {
+				errs = append(errs, typeErr)
+			}
+		},
+	}
+	pkg, _ := config.Check(importPath, c.fset, files, nil)
+	c.packages[importPath] = pkg
+
+	for _, typeErr := range errs {
+		// A fake package is named after the last element of its path
+		// (v5 for github.com/go-chi/chi/v5), so its real name is undefined
+		if name, ok := strings.CutPrefix(typeErr.Msg, "undefined: "); ok && unknown[name] {
+			continue
+		}
+		c.report(pkg, typeErr)
+	}
+
+	return pkg, nil
+}
+
+// report records a type error, leaving out the ones caused by
+// dependencies that cannot be loaded offline
+func (c *checker) report(pkg *types.Package, typeErr types.Error) {
+	if strings.Contains(typeErr.Msg, errNotLocal.Error()) || c.incomplete(pkg, typeErr.Msg) {
+		return
+	}
+
+	pos := c.fset.Position(typeErr.Pos)
+	c.problems = append(c.problems, Problem{
+		File:    relPath(c.root, pos.Filename),
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Message: typeErr.Msg,
+	})
+}
+
+// missingMember matches the errors on selectors of a named type
+var missingMember = regexp.MustCompile(`\(type \*?(?:(\w+)\.)?(\w+) has no field or method `)
+
+// incomplete reports whether an error is about a member of a struct that
+// embeds a type of a dependency not available offline (gorm.Model, for
+// instance): the promoted fields and methods of such structs are unknown
+func (c *checker) incomplete(pkg *types.Package, msg string) bool {
+	match := missingMember.FindStringSubmatch(msg)
+	if match == nil || pkg == nil {
+		return false
+	}
+
+	// The type may come from a package the erroneous one does not import
+	// (a value returned by a service), so the project packages are searched
+	candidates := []*types.Package{pkg}
+	if match[1] != "" {
+		candidates = nil
+		for _, local := range c.packages {
+			if local != nil && local.Name() == match[1] {
+				candidates = append(candidates, local)
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		obj := candidate.Scope().Lookup(match[2])
+		if obj != nil && embedsUnknown(obj.Type(), make(map[types.Type]bool)) {
+			return true
+		}
+	}
+	return false
+}
+
+// embedsUnknown reports whether a struct type embeds, directly or through
+// other embedded structs, a type that could not be resolved
+func embedsUnknown(typ types.Type, seen map[types.Type]bool) bool {
+	if seen[typ] {
+		return false
+	}
+	seen[typ] = true
+
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Embedded() {
+			continue
+		}
+		embedded := field.Type()
+		if ptr, ok := embedded.(*types.Pointer); ok {
+			embedded = ptr.Elem()
+		}
+		if embedded == types.Typ[types.Invalid] || embedsUnknown(embedded, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// local reports whether an import path belongs to the project
+func (c *checker) local(importPath string) bool {
+	return importPath == c.module || strings.HasPrefix(importPath, c.module+"/")
+}
+
+// majorVersion matches the major version suffix of a module path
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// packageName guesses the name of a package from its import path, the way
+// goimports does: github.com/go-chi/chi/v5 is chi, gopkg.in/yaml.v3 is yaml
+func packageName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if majorVersion.MatchString(name) && len(elems) > 1 {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "-go"), ".go")
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// isStd reports whether an import path belongs to the standard library
+// (its first element has no dot)
+func isStd(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,311 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options configures a verification run
+type Options struct {
+	// Offline skips go mod tidy, go build and go vet (they may need to
+	// download modules); the project is only parsed and type-checked
+	Offline bool
+
+	// Generated lists the project-relative files written by Loom; only
+	// those are gofmt'd in place (nil formats every file). Other files are
+	// still checked for syntax errors.
+	Generated []string
+
+	// Templates maps project-relative paths to the template that produced
+	// them, so problems point back at the template to fix
+	Templates map[string]string
+}
+
+// Problem is an error found in a file of the project
+type Problem struct {
+	File     string // project-relative path
+	Line     int
+	Column   int
+	Message  string
+	Template string // template that produced the file, if known
+}
+
+// String formats the problem as file:line:column: message
+func (p Problem) String() string {
+	s := p.File
+	if p.Line > 0 {
+		s += ":" + strconv.Itoa(p.Line)
+		if p.Column > 0 {
+			s += ":" + strconv.Itoa(p.Column)
+		}
+	}
+	s += ": " + p.Message
+	if p.Template != "" {
+		s += " (template " + p.Template + ")"
+	}
+	return s
+}
+
+// Report is the outcome of a verification run
+type Report struct {
+	Formatted []string  // files rewritten by gofmt
+	Steps     []string  // checks that ran, e.g. "go build"
+	Notes     []string  // why checks were skipped or replaced
+	Problems  []Problem // errors found, empty when the project is sound
+}
+
+// OK reports whether no problem was found
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Run verifies the Go project at root: every Go file is gofmt'd, then
+// go mod tidy, go build and go vet run when the go toolchain is
+// available. Offline, or when the toolchain cannot resolve the
+// dependencies, the packages are parsed and type-checked instead.
+func Run(root string, opts Options) (*Report, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{}
+
+	files, err := goFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := make(map[string]bool, len(opts.Generated))
+	for _, rel := range opts.Generated {
+		generated[filepath.ToSlash(rel)] = true
+	}
+
+	for _, rel := range files {
+		formatted, problem, err := formatFile(root, rel, opts.Generated == nil || generated[rel])
+		if err != nil {
+			return nil, err
+		}
+		if problem != nil {
+			report.Problems = append(report.Problems, *problem)
+		} else if formatted {
+			report.Formatted = append(report.Formatted, rel)
+		}
+	}
+	report.Steps = append(report.Steps, "gofmt")
+
+	// Syntax errors would be reported again by every later step
+	if !report.OK() {
+		report.attribute(opts.Templates)
+		return report, nil
+	}
+
+	if !opts.Offline {
+		if _, err := exec.LookPath("go"); err != nil {
+			report.Notes = append(report.Notes, "go toolchain not found")
+			opts.Offline = true
+		}
+	}
+
+	if !opts.Offline {
+		online, err := report.runTool(root)
+		if err != nil {
+			return nil, err
+		}
+		opts.Offline = !online
+	}
+
+	if opts.Offline {
+		problems, err := typeCheck(root)
+		if err != nil {
+			return nil, err
+		}
+		report.Steps = append(report.Steps, "typecheck")
+		report.Problems = append(report.Problems, problems...)
+	}
+
+	report.attribute(opts.Templates)
+	return report, nil
+}
+
+// runTool runs go mod tidy, go build and go vet. Returns false when tidy
+// fails without pointing at a file (no network, no module cache), so the
+// caller falls back to the offline checks.
+func (r *Report) runTool(root string) (bool, error) {
+	output, err := goCommand(root, "mod", "tidy")
+	if err != nil {
+		problems := parseOutput(root, output)
+		if len(problems) == 0 {
+			r.Notes = append(r.Notes, "go mod tidy failed ("+firstLine(output)+"), falling back to offline checks")
+			return false, nil
+		}
+		r.Steps = append(r.Steps, "go mod tidy")
+		r.Problems = append(r.Problems, problems...)
+		return true, nil
+	}
+	r.Steps = append(r.Steps, "go mod tidy")
+
+	for _, step := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+		output, err := goCommand(root, step...)
+		r.Steps = append(r.Steps, "go "+step[0])
+		if err == nil {
+			continue
+		}
+		problems := parseOutput(root, output)
+		if len(problems) == 0 {
+			problems = []Problem{{File: "go.mod", Message: "go " + step[0] + ": " + firstLine(output)}}
+		}
+		r.Problems = append(r.Problems, problems...)
+		// vet reports the build errors again
+		break
+	}
+
+	return true, nil
+}
+
+// attribute fills in the template of each problem
+func (r *Report) attribute(templates map[string]string) {
+	for i := range r.Problems {
+		r.Problems[i].Template = templates[r.Problems[i].File]
+	}
+	sort.SliceStable(r.Problems, func(i, j int) bool {
+		if r.Problems[i].File != r.Problems[j].File {
+			return r.Problems[i].File < r.Problems[j].File
+		}
+		if r.Problems[i].Line != r.Problems[j].Line {
+			return r.Problems[i].Line < r.Problems[j].Line
+		}
+		return r.Problems[i].Column < r.Problems[j].Column
+	})
+}
+
+// goCommand runs the go tool in root and returns its combined output
+func goCommand(root string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = root
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return "", fmt.Errorf("error running go %s: %w", strings.Join(args, " "), err)
+	}
+	return out.String(), err
+}
+
+// positionPattern matches the file:line[:column]: message lines printed
+// by the go tool (vet prefixes them with "vet: ")
+var positionPattern = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// parseOutput extracts the problems from the output of the go tool
+func parseOutput(root, output string) []Problem {
+	var problems []Problem
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		match := positionPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil || seen[match[0]] {
+			continue
+		}
+		seen[match[0]] = true
+
+		lineNo, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		problems = append(problems, Problem{
+			File:    relPath(root, match[1]),
+			Line:    lineNo,
+			Column:  column,
+			Message: match[4],
+		})
+	}
+	return problems
+}
+
+// relPath makes a path printed by the go tool relative to root
+func relPath(root, path string) string {
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(root, path); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// firstLine returns the first non-empty line of output
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return "no output"
+}
+
+// goFiles returns the Go files of the project, relative to root. Hidden
+// directories, vendor and testdata are skipped like the go tool does.
+func goFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".go") {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, err
+}
+
+// skipDir reports whether the go tool ignores a directory
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+		name == "vendor" || name == "testdata"
+}
+
+// formatFile gofmt's a file, in place when write is set. Returns whether
+// it changed, or the syntax error that kept it from being formatted.
+func formatFile(root, rel string, write bool) (bool, *Problem, error) {
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil, err
+	}
+
+	formatted, err := format.Source(src)
+	if err != nil {
+		problem := Problem{File: rel, Message: err.Error()}
+		var list scanner.ErrorList
+		if errors.As(err, &list) && len(list) > 0 {
+			problem.Line = list[0].Pos.Line
+			problem.Column = list[0].Pos.Column
+			problem.Message = list[0].Msg
+		}
+		return false, &problem, nil
+	}
+
+	if !write || bytes.Equal(src, formatted) {
+		return false, nil, nil
+	}
+	return true, nil, os.WriteFile(path, formatted, 0644)
+}
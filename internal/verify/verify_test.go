@@ -0,0 +1,156 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeProject writes the files of a module named example.com/shop
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	files["go.mod"] = "module example.com/shop\n\ngo 1.23\n\nrequire gorm.io/gorm v1.25.5\n"
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRunFormatsGeneratedFiles(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"internal/app/models/product.go": "package models\n\ntype Product struct {\nID int\n}\n",
+		"internal/app/models/custom.go":  "package models\n\ntype Custom struct {\nID int\n}\n",
+	})
+
+	report, err := Run(root, Options{Offline: true, Generated: []string{"internal/app/models/product.go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Fatalf("problems: %v", report.Problems)
+	}
+	if !slices.Equal(report.Formatted, []string{"internal/app/models/product.go"}) {
+		t.Errorf("Formatted = %v", report.Formatted)
+	}
+	if !slices.Equal(report.Steps, []string{"gofmt", "typecheck"}) {
+		t.Errorf("Steps = %v", report.Steps)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(root, "internal/app/models/custom.go"))
+	if !strings.Contains(string(content), "\nID int\n") {
+		t.Errorf("user file formatted:\n%s", content)
+	}
+}
+
+// TestRunSyntaxError checks that syntax errors point at the template of
+// the file and stop the checks after gofmt
+func TestRunSyntaxError(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"internal/app/models/product.go": "package models\n\ntype Product struct {\n\tID int\n",
+	})
+
+	report, err := Run(root, Options{
+		Offline:   true,
+		Templates: map[string]string{"internal/app/models/product.go": "layered/model.go.tmpl"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 {
+		t.Fatalf("problems = %v, want one", report.Problems)
+	}
+	problem := report.Problems[0]
+	if problem.File != "internal/app/models/product.go" || problem.Line != 4 || problem.Template != "layered/model.go.tmpl" {
+		t.Errorf("problem = %+v", problem)
+	}
+	if !slices.Equal(report.Steps, []string{"gofmt"}) {
+		t.Errorf("Steps = %v", report.Steps)
+	}
+}
+
+// TestRunTypeCheck checks the offline type-check: errors across project
+// packages are reported, while members promoted from dependencies that
+// cannot be loaded are not
+func TestRunTypeCheck(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"internal/app/models/product.go": `package models
+
+import "gorm.io/gorm"
+
+type Product struct {
+	gorm.Model
+	Name string
+}
+
+type Tag struct {
+	Name string
+}
+`,
+		"internal/app/services/product_service.go": `package services
+
+import "example.com/shop/internal/app/models"
+
+func Name(p *models.Product) (string, uint) {
+	return p.Name, p.ID
+}
+
+func Slug(t *models.Tag) string {
+	return t.Slug
+}
+`,
+	})
+
+	report, err := Run(root, Options{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 {
+		t.Fatalf("problems = %v, want one", report.Problems)
+	}
+	problem := report.Problems[0]
+	if problem.File != "internal/app/services/product_service.go" || problem.Line != 10 || !strings.Contains(problem.Message, "Slug") {
+		t.Errorf("problem = %+v", problem)
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	root := filepath.FromSlash("/tmp/shop")
+	output := `# example.com/shop/internal/app/services
+internal/app/services/product_service.go:10:11: p.Price undefined
+vet: /tmp/shop/internal/app/handlers/product_handler.go:4:2: "fmt" imported and not used
+internal/app/services/product_service.go:10:11: p.Price undefined
+go: downloading gorm.io/gorm v1.25.5
+`
+	want := []Problem{
+		{File: "internal/app/services/product_service.go", Line: 10, Column: 11, Message: "p.Price undefined"},
+		{File: "internal/app/handlers/product_handler.go", Line: 4, Column: 2, Message: `"fmt" imported and not used`},
+	}
+	if got := parseOutput(root, output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseOutput = %+v, want %+v", got, want)
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"github.com/go-chi/chi/v5":    "chi",
+		"gopkg.in/yaml.v3":            "yaml",
+		"github.com/gin-gonic/gin":    "gin",
+		"gorm.io/driver/postgres":     "postgres",
+		"github.com/labstack/echo/v4": "echo",
+	}
+	for importPath, want := range tests {
+		if got := packageName(importPath); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", importPath, got, want)
+		}
+	}
+}
@@ -5,18 +5,25 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"strings"
+
+	"github.com/geomark27/loom-go/internal/state"
 )
 
 // DetectProjectVersion detects the Loom version used in the project
 func DetectProjectVersion() (Version, error) {
+	// The project manifest records the version the project is on
+	version, err := detectFromManifest()
+	if err == nil {
+		return version, nil
+	}
+
 	// Search for the Loom version comment in go.mod
-	version, err := detectFromGoMod()
+	version, err = detectFromGoMod()
 	if err == nil {
 		return version, nil
 	}
 
-	// Search in .loom if it exists
+	// Search in the key=value .loom file of older projects
 	version, err = detectFromLoomFile()
 	if err == nil {
 		return version, nil
@@ -26,6 +33,18 @@ func DetectProjectVersion() (Version, error) {
 	return Version{Major: 1, Minor: 0, Patch: 0}, nil
 }
 
+// detectFromManifest reads the version recorded in .loom/loom.yaml
+func detectFromManifest() (Version, error) {
+	manifest, err := state.LoadManifest(".")
+	if err != nil {
+		return Version{}, err
+	}
+	if manifest == nil || manifest.Version == "" {
+		return Version{}, fmt.Errorf("version not found in %s", state.ManifestPath("."))
+	}
+	return Parse(manifest.Version)
+}
+
 // detectFromGoMod searches for the version in go.mod comments
 func detectFromGoMod() (Version, error) {
 	file, err := os.Open("go.mod")
@@ -47,33 +66,13 @@ func detectFromGoMod() (Version, error) {
 	return Version{}, fmt.Errorf("version not found in go.mod")
 }
 
-// detectFromLoomFile reads the .loom file
+// detectFromLoomFile reads the key=value .loom file of older projects
 func detectFromLoomFile() (Version, error) {
-	content, err := os.ReadFile(".loom")
-	if err != nil {
-		return Version{}, err
-	}
-
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "version=") {
-			versionStr := strings.TrimPrefix(line, "version=")
-			return Parse(strings.TrimSpace(versionStr))
-		}
+	versionStr, ok := state.LegacyConfig(".")["version"]
+	if !ok {
+		return Version{}, fmt.Errorf("version not found in .loom")
 	}
-
-	return Version{}, fmt.Errorf("version not found in .loom")
-}
-
-// CreateLoomFile creates or updates the .loom file with the current version
-func CreateLoomFile(version Version, architecture string) error {
-	content := fmt.Sprintf(`# Loom Project Configuration
-version=%s
-architecture=%s
-created_with=loom-cli
-`, version.String(), architecture)
-
-	return os.WriteFile(".loom", []byte(content), 0644)
+	return Parse(versionStr)
 }
 
 // GetChangelogBetween returns the changelog between two versions